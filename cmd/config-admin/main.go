@@ -0,0 +1,268 @@
+// config-admin: 值班用的配置运维工具，查看配置历史版本并回滚，加密配置值
+//
+// 用法：
+//
+//	config-admin history  -data-id business.json [-group b] [-page 1] [-size 20]
+//	config-admin show     -data-id business.json -id 123 [-group b]
+//	config-admin rollback -data-id business.json -id 123 [-group b] [-dry-run]
+//	config-admin encrypt  [-key-id k2] [value]
+//	config-admin keygen   -key-id k2
+//
+// 配置源与连接信息与服务一致，通过 EVERFIR_CONFIG_SOURCE、EVERFIR_NACOS_ADDRS 等环境变量指定。
+// 内置配置（business.json、shutdown.json、gray.json、account_config.json）回滚时按对应类型执行 Validator 校验。
+//
+// encrypt 使用 EVERFIR_CONFIG_KEY_FILE 或 EVERFIR_CONFIG_KEYS 中的密钥加密，未指定 value 时从标准输入读取，
+// 输出的 enc: 加密值可直接写入配置；keygen 生成新的密钥，输出的一行追加到密钥文件后即可用于轮换。
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/everfir/go-helpers/consts"
+	"github.com/everfir/go-helpers/define"
+	"github.com/everfir/go-helpers/helper/nacos"
+	"github.com/everfir/go-helpers/internal/structs"
+	"github.com/everfir/go-helpers/internal/structs/config"
+	"github.com/everfir/go-helpers/internal/structs/gray"
+)
+
+// handler: 按配置类型执行对比与回滚
+type handler struct {
+	diff     func(source nacos.ConfigSource, dataId, id string, opts ...nacos.Option) ([]config.FieldDiff, error)
+	rollback func(source nacos.ConfigSource, dataId, id string, opts ...nacos.Option) (nacos.CASResult, error)
+}
+
+func typed[T any]() handler {
+	return handler{
+		diff: func(source nacos.ConfigSource, dataId, id string, opts ...nacos.Option) ([]config.FieldDiff, error) {
+			version, _, err := nacos.GetVersion[T](source, dataId, id, opts...)
+			if err != nil {
+				return nil, err
+			}
+
+			current, _, err := nacos.Read[T](source, dataId, opts...)
+			if err != nil {
+				return nil, err
+			}
+			return config.Diff(current, version)
+		},
+		rollback: nacos.Rollback[T],
+	}
+}
+
+// handlers: 内置配置的类型，其他配置按通用结构处理，不执行类型校验
+var handlers = map[string]handler{
+	"business.json":       typed[structs.BusinessConfig](),
+	"shutdown.json":       typed[structs.ShutdownConfig](),
+	"gray.json":           typed[gray.GrayConfig](),
+	"account_config.json": typed[define.AccountConfig](),
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	var err error
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "history":
+		err = history(args)
+	case "show":
+		err = show(args)
+	case "rollback":
+		err = rollback(args)
+	case "encrypt":
+		err = encrypt(args)
+	case "keygen":
+		err = keygen(args)
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: config-admin history|show|rollback -data-id <dataId> [-group <b|c|...>] [flags]\n       config-admin encrypt [-key-id <keyId>] [value]\n       config-admin keygen -key-id <keyId>")
+	os.Exit(2)
+}
+
+// common: 子命令的公共参数
+type common struct {
+	dataId string
+	group  string
+}
+
+func (c *common) register(fs *flag.FlagSet) {
+	fs.StringVar(&c.dataId, "data-id", "", "config data id, e.g. business.json")
+	fs.StringVar(&c.group, "group", "", "traffic group, e.g. b; empty for the env default group")
+}
+
+func (c *common) options() ([]nacos.Option, error) {
+	if c.dataId == "" {
+		return nil, fmt.Errorf("-data-id is required")
+	}
+	if c.group == "" {
+		return nil, nil
+	}
+	return []nacos.Option{nacos.WithTrafficGroup(consts.NewTrafficGroupFromString(c.group))}, nil
+}
+
+func history(args []string) error {
+	var c common
+	var page, size int
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	c.register(fs)
+	fs.IntVar(&page, "page", 1, "page number, starting from 1")
+	fs.IntVar(&size, "size", 20, "page size")
+	fs.Parse(args)
+
+	opts, err := c.options()
+	if err != nil {
+		return err
+	}
+	histories, err := nacos.ListHistory(nacos.GetConfigSource(), c.dataId, page, size, opts...)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tOP\tMD5\tUSER\tIP\tTIME")
+	for _, h := range histories {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", h.Id, h.OpType, h.Md5, h.SrcUser, h.SrcIp, h.LastModifiedTime)
+	}
+	return w.Flush()
+}
+
+func show(args []string) error {
+	var c common
+	var id string
+	fs := flag.NewFlagSet("show", flag.ExitOnError)
+	c.register(fs)
+	fs.StringVar(&id, "id", "", "history version id")
+	fs.Parse(args)
+
+	opts, err := c.options()
+	if err != nil {
+		return err
+	}
+	if id == "" {
+		return fmt.Errorf("-id is required")
+	}
+
+	// 原样输出历史版本内容，text 格式避免重新序列化
+	content, _, err := nacos.GetVersion[string](nacos.GetConfigSource(), c.dataId, id, append(opts, nacos.WithFormat(nacos.Format_Text))...)
+	if err != nil {
+		return err
+	}
+	fmt.Println(content)
+	return nil
+}
+
+func rollback(args []string) error {
+	var c common
+	var id string
+	var dryRun bool
+	fs := flag.NewFlagSet("rollback", flag.ExitOnError)
+	c.register(fs)
+	fs.StringVar(&id, "id", "", "history version id to roll back to")
+	fs.BoolVar(&dryRun, "dry-run", false, "only print the diff between the current config and the version")
+	fs.Parse(args)
+
+	opts, err := c.options()
+	if err != nil {
+		return err
+	}
+	if id == "" {
+		return fmt.Errorf("-id is required")
+	}
+
+	h, exist := handlers[c.dataId]
+	if !exist {
+		fmt.Fprintf(os.Stderr, "warning: %s has no registered type, rollback without type validation\n", c.dataId)
+		h = typed[any]()
+	}
+
+	source := nacos.GetConfigSource()
+	diffs, err := h.diff(source, c.dataId, id, opts...)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PATH\tOP\tCURRENT\tVERSION")
+	for _, d := range diffs {
+		fmt.Fprintf(w, "%s\t%s\t%v\t%v\n", d.Path, d.Op, d.Old, d.New)
+	}
+	w.Flush()
+
+	if dryRun {
+		return nil
+	}
+	result, err := h.rollback(source, c.dataId, id, opts...)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("rolled back %s/%s to version %s, md5 %s -> %s\n", result.Group, result.DataId, id, result.PrevMd5, result.Md5)
+	return nil
+}
+
+func encrypt(args []string) error {
+	var keyId string
+	fs := flag.NewFlagSet("encrypt", flag.ExitOnError)
+	fs.StringVar(&keyId, "key-id", "", "key id used to encrypt; empty for EVERFIR_CONFIG_KEY_ID or the first key")
+	fs.Parse(args)
+
+	keyring, err := nacos.KeyringFromEnv()
+	if err != nil {
+		return err
+	}
+	if keyring == nil {
+		return fmt.Errorf("no key configured, set EVERFIR_CONFIG_KEY_FILE or EVERFIR_CONFIG_KEYS")
+	}
+	if keyId != "" {
+		if keyring, err = keyring.WithPrimary(keyId); err != nil {
+			return err
+		}
+	}
+
+	// 未指定 value 时从标准输入读取，避免明文留在 shell 历史中
+	value := fs.Arg(0)
+	if fs.NArg() == 0 {
+		b, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		value = strings.TrimRight(string(b), "\r\n")
+	}
+
+	encrypted, err := keyring.Encrypt(value)
+	if err != nil {
+		return err
+	}
+	fmt.Println(encrypted)
+	return nil
+}
+
+func keygen(args []string) error {
+	var keyId string
+	fs := flag.NewFlagSet("keygen", flag.ExitOnError)
+	fs.StringVar(&keyId, "key-id", "", "id of the new key, e.g. k2")
+	fs.Parse(args)
+
+	if keyId == "" || strings.Contains(keyId, ":") {
+		return fmt.Errorf("-key-id is required and must not contain ':'")
+	}
+	key, err := nacos.GenerateKey()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s:%s\n", keyId, key)
+	return nil
+}
@@ -40,4 +40,6 @@ const (
 	AccountInfoKey ContextKey = "x-everfir-account-info"
 	// ExperimentGroupKey: 请求头中携带分组信息, 用于AB分组
 	ExperimentGroupKey ContextKey = "x-everfir-experiment-group"
+	// AccountExtraKey: 上下文中缓存解析后的用户 Extra 信息, 同一请求内只解析一次
+	AccountExtraKey ContextKey = "x-everfir-account-extra"
 )
@@ -1,5 +1,7 @@
 package define
 
+import "encoding/json"
+
 type AccountConfig struct {
 	UrlEnv map[string]string `json:"url_env"`
 }
@@ -27,3 +29,16 @@ type AccountInfo struct {
 func (info *AccountInfo) Validate() bool {
 	return info.AccountId != 0
 }
+
+// ParseExtra 将 Extra 解析为 JSON 对象, Extra 为空时返回空对象
+func (info *AccountInfo) ParseExtra() (map[string]any, error) {
+	ret := make(map[string]any)
+	if info.Extra == "" {
+		return ret, nil
+	}
+
+	if err := json.Unmarshal([]byte(info.Extra), &ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
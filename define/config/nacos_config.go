@@ -1,7 +1,9 @@
 package config
 
 import (
+	"context"
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/everfir/go-helpers/consts"
@@ -11,14 +13,38 @@ import (
 
 func NewNacosConfig[V any](data map[string]*internal_config.Config[V]) *NacosConfig[V] {
 	return &NacosConfig[V]{
-		lock: sync.RWMutex{},
-		data: data,
+		lock:      sync.RWMutex{},
+		data:      data,
+		listeners: map[string]map[string]registration[V]{},
+		selector:  env.ExperimentGroup,
 	}
 }
 
+// GroupSelector 根据请求上下文选择配置分组，用于 GetCtx
+type GroupSelector func(ctx context.Context) consts.TrafficGroup
+
 type NacosConfig[V any] struct {
-	lock sync.RWMutex
-	data map[string]*internal_config.Config[V]
+	lock      sync.RWMutex
+	data      map[string]*internal_config.Config[V]
+	listeners map[string]map[string]registration[V] // key: 分组 -> 监听器名称
+	selector  GroupSelector
+	closers   []func()
+	closeOnce sync.Once
+}
+
+// registration 注册的监听器及其投递选项，分组新增时挂载到新分组的配置上
+type registration[V any] struct {
+	listener internal_config.IApplyListener[V]
+	opts     internal_config.ListenerOptions
+}
+
+// groupKey 获取流量分组对应的分组键：未指定时为当前环境，否则为 "{env}_{group}"
+func groupKey(keys ...consts.TrafficGroup) string {
+	k := env.Env()
+	if len(keys) > 0 {
+		k = fmt.Sprintf("%s_%s", k, keys[0].Group())
+	}
+	return k
 }
 
 // Get 获取配置数据，支持按流量分组获取不同的配置。
@@ -52,13 +78,94 @@ func (config *NacosConfig[V]) Get(keys ...consts.TrafficGroup) (V, bool) {
 	}
 
 	var exist bool = true
-	// 如果找不到对应分组的配置，默认使用 A 组配置
+	// 如果找不到对应分组的配置，默认使用 A 组（当前环境的默认分组）配置
 	if _, exist = config.data[k]; !exist {
-		k = fmt.Sprintf("%s_%s", env.Env(), consts.TrafficGroup_A.Group())
+		k = env.Env()
 	}
 
 	// 返回对应的配置数据
-	return config.data[k].Get(), exist
+	conf, ok := config.data[k]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	return conf.Get(), exist
+}
+
+// SetGroupSelector 设置 GetCtx 选择分组的方式，默认为 env.ExperimentGroup（请求上下文中的实验分组）。
+//
+// 需要配置灰度与某个功能实验的分桶保持一致时，使用 gray.FeatureSelector：
+//
+//	cfg.SetGroupSelector(gray.FeatureSelector("new_token_url"))
+func (config *NacosConfig[V]) SetGroupSelector(selector GroupSelector) {
+	config.lock.Lock()
+	defer config.lock.Unlock()
+
+	if selector == nil {
+		selector = env.ExperimentGroup
+	}
+	config.selector = selector
+}
+
+// GetCtx 根据请求上下文获取配置。
+//
+// 分组由 SetGroupSelector 设置的方式选择（默认为 env.ExperimentGroup），
+// 上下文中带有业务（env.Business）且存在该业务的补丁（WithOverlay）时优先返回合并了业务补丁的配置，
+// 其余行为与 Get 一致：分组配置不存在时降级为当前环境的默认配置。
+//
+// 返回值中的 bool 表示是否命中了分组或业务对应的配置，A 组对应当前环境的默认配置。
+//
+// 示例：
+//
+//	cfg, _ := config.GetCtx(ctx)
+func (config *NacosConfig[V]) GetCtx(ctx context.Context) (V, bool) {
+	config.lock.RLock()
+	selector := config.selector
+	config.lock.RUnlock()
+
+	group := selector(ctx)
+	if business := env.Business(ctx); business != "" {
+		if v, exist := config.GetWithBusiness(business, group); exist {
+			return v, true
+		}
+	}
+	if group == consts.TrafficGroup_A {
+		// A 组即当前环境的默认配置
+		return config.Get()
+	}
+	return config.Get(group)
+}
+
+// BusinessGroup 业务补丁合并后的分组键："{group}.{business}"，例如 "production.momo"、"production_b.momo"
+func BusinessGroup(group, business string) string {
+	return group + "." + business
+}
+
+// GetWithBusiness 获取指定业务的配置，用于分层配置（WithOverlay）。
+//
+// 查找顺序：
+// 1. "{env}_{group}.{business}"：基础配置 + 流量分组补丁 + 业务补丁
+// 2. "{env}.{business}"：流量分组不存在时，基础配置 + 业务补丁
+// 3. 业务补丁不存在时与 Get 一致
+//
+// 返回值中的 bool 表示是否命中了该业务的补丁。
+//
+// 示例：
+//
+//	config.GetWithBusiness(env.Business(ctx), env.ExperimentGroup(ctx))
+func (config *NacosConfig[V]) GetWithBusiness(business string, keys ...consts.TrafficGroup) (V, bool) {
+	config.lock.RLock()
+	conf, exist := config.data[BusinessGroup(groupKey(keys...), business)]
+	if !exist {
+		conf, exist = config.data[BusinessGroup(env.Env(), business)]
+	}
+	config.lock.RUnlock()
+
+	if exist {
+		return conf.Get(), true
+	}
+	v, _ := config.Get(keys...)
+	return v, false
 }
 
 // RegisterListener 为指定分组的内部配置注册监听器。
@@ -71,20 +178,78 @@ func (config *NacosConfig[V]) Get(keys ...consts.TrafficGroup) (V, bool) {
 //   - listener: 实现了 `internal_config.IListener[V]` 的监听器实例，会在目标分组配置更新后接收最新的数据副本。
 //   - keys: 可选的流量分组参数。如果提供，则目标分组键为 "{env}_{group}"；
 //     如果不提供，则使用当前环境 `env.Env()` 对应的分组。
-//     注意：必须确保目标分组键已在 `NacosConfig.data` 中存在，否则可能导致空指针异常。
+//     目标分组尚不存在时，监听器会在该分组被发现（AddGroup）后生效。
 //
 // 并发：
-// - 本方法内部加写锁，线程安全；监听器回调由内部 `Config.Set` 触发。
+// - 本方法内部加写锁，线程安全；监听器由内部 `Config.Update` 异步触发，投递方式见 RegisterApplyListener。
+//
+// 内部通过 `internal_config.AdaptListener` 适配为 `internal_config.IChangeListener[V]`，
+// 需要变更前后的值或字段级差异时使用 RegisterChangeListener。
 func (config *NacosConfig[V]) RegisterListener(name string, listener internal_config.IListener[V], keys ...consts.TrafficGroup) {
+	config.RegisterChangeListener(name, internal_config.AdaptListener(listener), keys...)
+}
+
+// RegisterChangeListener 为指定分组的内部配置注册接收完整变更事件的监听器。
+//
+// 与 RegisterListener 相比，监听器收到的 `internal_config.ChangeEvent[V]` 额外包含：
+//   - Old/New: 变更前后的配置
+//   - Content: 变更后的原始配置内容
+//   - Diff: 按 JSON 序列化后的字段级差异，路径为 JSON Pointer，如 "/momo"
+//   - Namespace/Group/DataId/Md5: 配置的来源信息
+//
+// 参数 keys 与注意事项同 RegisterListener。
+//
+// 示例：
+//
+//	config.RegisterChangeListener("notify", internal_config.ChangeListenerFunc[map[string]bool](
+//	    func(event internal_config.ChangeEvent[map[string]bool]) {
+//	        for _, diff := range event.Diff {
+//	            // diff.Path: "/momo", diff.Op: "replace", diff.Old: false, diff.New: true
+//	        }
+//	    },
+//	))
+func (config *NacosConfig[V]) RegisterChangeListener(name string, listener internal_config.IChangeListener[V], keys ...consts.TrafficGroup) {
+	config.RegisterApplyListener(name, internal_config.ApplyListenerFunc[V](func(event internal_config.ChangeEvent[V]) error {
+		listener.OnConfigChange(event)
+		return nil
+	}), internal_config.ListenerOptions{}, keys...)
+}
+
+// RegisterApplyListener 为指定分组的内部配置注册可能应用失败的监听器。
+//
+// 监听器异步执行，每个监听器有独立的投递队列，只投递最新的配置（连续多次变更合并为一次，Old 为上次收到的配置）；
+// 监听器 panic 会被恢复并记录日志。opts 可指定：
+//   - Priority: 配置变更时按优先级从高到低将事件放入各监听器的队列，只决定入队顺序，不保证执行顺序
+//   - Retries/Backoff: 返回错误或 panic 后的重试次数与间隔，重试期间有新的变更时直接投递最新配置
+//   - OnError: 应用失败时回调，用于上报
+//
+// 参数 keys 与注意事项同 RegisterListener。
+//
+// 示例：
+//
+//	config.RegisterApplyListener("pool", internal_config.ApplyListenerFunc[AppConfig](
+//	    func(event internal_config.ChangeEvent[AppConfig]) error {
+//	        return pool.Resize(event.New.PoolSize)
+//	    },
+//	), internal_config.ListenerOptions{Priority: 10, Retries: 3})
+func (config *NacosConfig[V]) RegisterApplyListener(
+	name string,
+	listener internal_config.IApplyListener[V],
+	opts internal_config.ListenerOptions,
+	keys ...consts.TrafficGroup,
+) {
 	config.lock.Lock()
 	defer config.lock.Unlock()
 
-	k := env.Env()
-	if len(keys) > 0 {
-		k = fmt.Sprintf("%s_%s", k, keys[0].Group())
+	k := groupKey(keys...)
+	if _, exist := config.listeners[k]; !exist {
+		config.listeners[k] = map[string]registration[V]{}
 	}
+	config.listeners[k][name] = registration[V]{listener: listener, opts: opts}
 
-	config.data[k].RegisterListener(name, listener)
+	if conf, exist := config.data[k]; exist {
+		conf.RegisterApplyListener(name, listener, opts)
+	}
 }
 
 // UnregisterListener 从指定分组的内部配置中注销监听器。
@@ -93,7 +258,6 @@ func (config *NacosConfig[V]) RegisterListener(name string, listener internal_co
 //   - name: 监听器名称键（与注册时的 name 一致）。
 //   - keys: 可选的流量分组参数。如果提供，则从 "{env}_{group}" 分组注销；
 //     如果不提供，则从当前环境 `env.Env()` 对应的分组注销。
-//     注意：必须确保目标分组键已在 `NacosConfig.data` 中存在，否则可能导致空指针异常。
 //
 // 行为：
 // - 如果对应名称的监听器不存在，删除操作为幂等，不会报错。
@@ -104,10 +268,131 @@ func (config *NacosConfig[V]) UnregisterListener(name string, keys ...consts.Tra
 	config.lock.Lock()
 	defer config.lock.Unlock()
 
-	k := env.Env()
-	if len(keys) > 0 {
-		k = fmt.Sprintf("%s_%s", k, keys[0].Group())
+	k := groupKey(keys...)
+	delete(config.listeners[k], name)
+	if conf, exist := config.data[k]; exist {
+		conf.UnregisterListener(name)
+	}
+}
+
+// Groups 获取当前已加载的所有分组键
+func (config *NacosConfig[V]) Groups() []string {
+	config.lock.RLock()
+	defer config.lock.RUnlock()
+
+	groups := make([]string, 0, len(config.data))
+	for group := range config.data {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+// AddGroup 新增（或替换）分组配置，用于灰度分组的动态发现。
+//
+// 为该分组注册过的监听器会挂载到新配置上，并收到一次变更事件：
+// Old 为新增前该分组实际生效的配置（原分组配置或当前环境的默认配置），New 为新分组的配置。
+func (config *NacosConfig[V]) AddGroup(group string, conf *internal_config.Config[V]) {
+	config.lock.Lock()
+	prev, exist := config.data[group]
+	if !exist {
+		prev = config.data[env.Env()]
+	} else if prev != conf {
+		// 被替换的配置不再更新，投递完尚未投递的事件后停止
+		defer func() { go prev.Close() }()
+	}
+	config.data[group] = conf
+	listeners := config.listeners[group]
+	for name, reg := range listeners {
+		conf.RegisterApplyListener(name, reg.listener, reg.opts)
+	}
+	config.lock.Unlock()
+
+	if len(listeners) > 0 {
+		conf.Dispatch(event(prev, conf))
+	}
+}
+
+// RemoveGroup 删除分组配置，当前环境的默认分组不允许删除。
+//
+// 为该分组注册过的监听器会收到一次变更事件：Old 为删除前的分组配置，New 为降级后的当前环境默认配置；
+// 监听器会被保留，分组再次被发现时重新生效。
+func (config *NacosConfig[V]) RemoveGroup(group string) {
+	if group == env.Env() {
+		return
+	}
+
+	config.lock.Lock()
+	prev, exist := config.data[group]
+	if !exist {
+		config.lock.Unlock()
+		return
+	}
+	delete(config.data, group)
+	listeners := config.listeners[group]
+	next := config.data[env.Env()]
+	config.lock.Unlock()
+
+	// 通过被删除的配置投递事件，投递完成后停止其监听器队列
+	if len(listeners) > 0 {
+		prev.Dispatch(event(prev, next))
+	}
+	go prev.Close()
+}
+
+// event 分组新增或删除时的变更事件，prev/next 为空时使用零值
+func event[V any](prev, next *internal_config.Config[V]) internal_config.ChangeEvent[V] {
+	var old, new V
+	var meta internal_config.Metadata
+	var content string
+	if prev != nil {
+		old = prev.Get()
 	}
+	if next != nil {
+		new, meta, content = next.Snapshot()
+	}
+
+	return internal_config.NewChangeEvent(meta, old, new, content)
+}
+
+// OnClose 注册关闭时执行的清理函数，例如停止灰度分组的发现
+func (config *NacosConfig[V]) OnClose(fn func()) {
+	config.lock.Lock()
+	defer config.lock.Unlock()
+
+	config.closers = append(config.closers, fn)
+}
+
+// Flush 等待所有分组已放入队列的监听器事件（包括重试）处理完成
+func (config *NacosConfig[V]) Flush() {
+	for _, conf := range config.configs() {
+		conf.Flush()
+	}
+}
+
+func (config *NacosConfig[V]) configs() []*internal_config.Config[V] {
+	config.lock.RLock()
+	defer config.lock.RUnlock()
+
+	configs := make([]*internal_config.Config[V], 0, len(config.data))
+	for _, conf := range config.data {
+		configs = append(configs, conf)
+	}
+	return configs
+}
+
+// Close 停止后台任务（如灰度分组发现），投递完各监听器尚未投递的事件后返回，多次调用只生效一次
+func (config *NacosConfig[V]) Close() {
+	config.closeOnce.Do(func() {
+		config.lock.RLock()
+		closers := append([]func(){}, config.closers...)
+		config.lock.RUnlock()
 
-	config.data[k].UnregisterListener(name)
+		for _, fn := range closers {
+			fn()
+		}
+		for _, conf := range config.configs() {
+			conf.Close()
+		}
+	})
 }
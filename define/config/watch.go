@@ -0,0 +1,65 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/everfir/go-helpers/consts"
+	internal_config "github.com/everfir/go-helpers/internal/structs/config"
+)
+
+// watchSeq 生成 Watch 监听器的名称
+var watchSeq atomic.Uint64
+
+// Watch 以 channel 的形式监听配置：先发送当前配置，之后每次变更发送最新配置，ctx 结束后注销监听器并关闭 channel。
+//
+// 参数 keys 与 Get 一致：指定流量分组时监听 "{env}_{group}" 分组，分组不存在时先发送当前环境的默认配置，
+// 分组被发现后发送分组配置。
+//
+// 读取不及时时中间的变更会被合并，只收到最新的配置；注册与读取当前配置之间发生变更时可能收到重复的值。
+//
+// 示例：
+//
+//	for cfg := range config.Watch(ctx) {
+//	    pool.Rebuild(cfg.PoolSize)
+//	}
+func (config *NacosConfig[V]) Watch(ctx context.Context, keys ...consts.TrafficGroup) <-chan V {
+	ch := make(chan V)
+	signal := make(chan struct{}, 1)
+
+	name := fmt.Sprintf("go-helper-watch-%d", watchSeq.Add(1))
+	config.RegisterChangeListener(name, internal_config.ChangeListenerFunc[V](func(internal_config.ChangeEvent[V]) {
+		select {
+		case signal <- struct{}{}:
+		default:
+		}
+	}), keys...)
+
+	go func() {
+		defer close(ch)
+		defer config.UnregisterListener(name, keys...)
+
+		// 当前配置已包含注册后、读取前的变更
+		current, _ := config.Get(keys...)
+		select {
+		case <-signal:
+		default:
+		}
+		for {
+			select {
+			case ch <- current:
+			case <-ctx.Done():
+				return
+			}
+
+			select {
+			case <-signal:
+			case <-ctx.Done():
+				return
+			}
+			current, _ = config.Get(keys...)
+		}
+	}()
+	return ch
+}
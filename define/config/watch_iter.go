@@ -0,0 +1,30 @@
+//go:build go1.23
+
+package config
+
+import (
+	"context"
+	"iter"
+
+	"github.com/everfir/go-helpers/consts"
+)
+
+// Values 以 range-over-func 迭代器的形式监听配置，行为与 Watch 一致，跳出循环或 ctx 结束后注销监听器。
+//
+// 示例：
+//
+//	for cfg := range config.Values(ctx) {
+//	    pool.Rebuild(cfg.PoolSize)
+//	}
+func (config *NacosConfig[V]) Values(ctx context.Context, keys ...consts.TrafficGroup) iter.Seq[V] {
+	return func(yield func(V) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		for v := range config.Watch(ctx, keys...) {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
@@ -167,6 +167,50 @@ func AccountInfo(ctx context.Context) (info define.AccountInfo) {
 	return
 }
 
+type accountExtra struct {
+	once sync.Once
+	data map[string]any
+	err  error
+}
+
+// WithAccountExtra 在上下文中挂载用户 Extra 的解析缓存。
+// 挂载后，同一请求内多次调用 AccountExtra 只会解析一次 Extra。
+//
+// 前置依赖： 上下文中已存在用户信息（AccountInfoKey）
+func WithAccountExtra(ctx context.Context) context.Context {
+	if ctx == nil {
+		return ctx
+	}
+
+	if _, ok := ctx.Value(consts.AccountExtraKey).(*accountExtra); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, consts.AccountExtraKey, &accountExtra{})
+}
+
+// AccountExtra 获取解析后的用户 Extra（JSON 对象）。
+// 如果上下文通过 WithAccountExtra 挂载了缓存，则复用缓存结果，否则每次调用都会重新解析。
+//
+// 返回值:
+//   - map[string]any: 解析后的 Extra，Extra 为空时返回空对象
+//   - error: Extra 不是合法的 JSON 对象时返回错误
+func AccountExtra(ctx context.Context) (map[string]any, error) {
+	info := AccountInfo(ctx)
+	if ctx == nil {
+		return info.ParseExtra()
+	}
+
+	cache, ok := ctx.Value(consts.AccountExtraKey).(*accountExtra)
+	if !ok {
+		return info.ParseExtra()
+	}
+
+	cache.once.Do(func() {
+		cache.data, cache.err = info.ParseExtra()
+	})
+	return cache.data, cache.err
+}
+
 func Platform(ctx context.Context) consts.TDevicePlatform {
 	if ctx == nil {
 		return consts.DP_Unknow
@@ -2,27 +2,142 @@ package gray
 
 import (
 	"context"
-	"sync"
+	"fmt"
+	"strconv"
 
 	"github.com/everfir/go-helpers/consts"
 	"github.com/everfir/go-helpers/define/config"
 	"github.com/everfir/go-helpers/env"
+	"github.com/everfir/go-helpers/internal/helper/encode"
 	"github.com/everfir/go-helpers/internal/helper/nacos"
 	"github.com/everfir/go-helpers/internal/structs/gray"
 )
 
-var getGrayConfig func() *config.NacosConfig[gray.GrayConfig] = sync.OnceValue(func() *config.NacosConfig[gray.GrayConfig] {
-	config, err := nacos.GetConfigAndListen[gray.GrayConfig](nacos.GetNacosClient(), "gray.json")
-	if err != nil {
-		panic(err.Error())
+// FeatureStatus: 实验生命周期状态
+type FeatureStatus = gray.FeatureStatus
+
+const (
+	FeatureStatus_Draft     = gray.FeatureStatus_Draft
+	FeatureStatus_Running   = gray.FeatureStatus_Running
+	FeatureStatus_Paused    = gray.FeatureStatus_Paused
+	FeatureStatus_Concluded = gray.FeatureStatus_Concluded
+	FeatureStatus_Cleaned   = gray.FeatureStatus_Cleaned
+	FeatureStatus_Disabled  = gray.FeatureStatus_Disabled
+)
+
+// StickyStore: 实验分组的粘性存储，配置后 paused 状态的实验会冻结新分配
+type StickyStore = gray.StickyStore
+
+// SetStickyStore 设置全局粘性存储，传入 nil 表示关闭
+func SetStickyStore(store StickyStore) {
+	gray.SetStickyStore(store)
+}
+
+// NewMemoryStickyStore 创建进程内的粘性存储，适用于单实例服务与测试
+func NewMemoryStickyStore() StickyStore {
+	return gray.NewMemoryStickyStore()
+}
+
+// ExprLimits: 规则表达式的沙箱限制（节点数、运算符/函数白名单、内存预算、自动禁用阈值）
+type ExprLimits = gray.ExprLimits
+
+// DefaultExprLimits 返回默认的沙箱限制，可在此基础上调整后传给 SetExprLimits
+func DefaultExprLimits() ExprLimits {
+	return gray.DefaultExprLimits()
+}
+
+// SetExprLimits 设置规则表达式的沙箱限制，应在服务启动时、首次调用 ExperimentGroup 前调用。
+// 注意: 内存预算(MemoryBudget)会写入 expr 的全局变量 vm.MemoryBudget，改变进程内所有 expr 使用方（包括其他库）的预算，
+// expr 不支持按次运行传入预算；为 0 时使用默认值 1e6，预算变化时记录 Warn 日志
+func SetExprLimits(limits ExprLimits) {
+	gray.SetExprLimits(limits)
+}
+
+// BucketScheme: 流量分桶方案
+type BucketScheme = encode.BucketScheme
+
+const (
+	HashScheme_Xxh3V1       = encode.SchemeXxh3V1
+	HashScheme_Xxh3SaltedV2 = encode.SchemeXxh3SaltedV2
+)
+
+// RegisterHashScheme 注册自定义分桶方案，名称重复时返回错误。
+// 需要在加载灰度配置前注册，否则引用该方案的配置会校验失败。
+func RegisterHashScheme(scheme BucketScheme) error {
+	return encode.RegisterScheme(scheme)
+}
+
+// Bucket 计算用户在指定分桶方案下所在的桶，供其他服务复现完全一致的分组。
+//
+// 参数：
+//   - scheme: 分桶方案名称，为空时使用默认方案 xxh3-v1
+//   - salt: 盐值，与灰度配置中的 hash_salt 一致（未配置时为实验名称），非加盐方案忽略该参数
+//   - accountId: 用户ID
+//
+// 返回值：
+//   - bucket: 用户所在的桶，范围 [0, buckets)
+//   - buckets: 该方案的桶数量
+//   - err: 方案不存在时返回错误
+func Bucket(scheme, salt string, accountId uint64) (bucket, buckets uint64, err error) {
+	s, exist := encode.GetScheme(scheme)
+	if !exist {
+		return 0, 0, fmt.Errorf("[go-helper] unknown hash scheme[%s]", scheme)
+	}
+	return s.Bucket(salt, strconv.FormatUint(accountId, 10)), s.Buckets(), nil
+}
+
+// InRate 判断用户在指定分桶方案下是否落在 rate 比例内，与规则中 rate/traffic_rate 的判断一致
+func InRate(scheme, salt string, accountId uint64, rate float64) (bool, error) {
+	s, exist := encode.GetScheme(scheme)
+	if !exist {
+		return false, fmt.Errorf("[go-helper] unknown hash scheme[%s]", scheme)
+	}
+	return encode.InRate(s, salt, strconv.FormatUint(accountId, 10), rate), nil
+}
+
+// HashMigrationStats: 分桶方案迁移统计（当前方案、目标方案、参与对比的用户数、切换后会移动分组的用户数）
+type HashMigrationStats = gray.HashMigrationStats
+
+// GetHashMigrationStats 获取当前业务下处于分桶方案迁移模式（migrate_hash）的实验统计，
+// 用于在切换方案前评估会有多少用户移动分组
+func GetHashMigrationStats(ctx context.Context) map[string]HashMigrationStats {
+	business := env.Business(ctx)
+	if business == "" {
+		return nil
+	}
+
+	config := getGrayConfig()
+	if _, exist := config[business]; !exist {
+		return nil
 	}
 
-	return config
-})
+	bizConfig := config[business]
+	return bizConfig.MigrationStats()
+}
+
+var grayConfig = nacos.NewLazyConfig[gray.GrayConfig](
+	"gray.json",
+	// Nacos 与快照均不可用时默认没有实验, 所有流量走 A 组
+	nacos.WithDefault(gray.GrayConfig{}),
+)
+
+// getGrayConfig: 获取灰度配置, 配置不可用(配置源创建失败或加载失败)时返回空配置, 所有流量走 A 组
+//
+// 灰度没有 SetGrayFailMode: ExperimentGroup 只返回分组、没有错误, 无法像中间件一样拒绝请求,
+// 而配置不可用时唯一安全的分组是对照组 A(不进入任何实验), 因此只有这一种降级方式
+func getGrayConfig() gray.GrayConfig {
+	config, err := grayConfig.Get()
+	if err != nil {
+		return nil
+	}
+	conf, _ := config.Get()
+	return conf
+}
 
 // ExperimentGroup 判断某个功能(feature)在当前业务环境下是否处于实验阶段。
 // 如果业务标识为空，则默认返回 false，表示不可用。
 // 如果业务没有对应的灰度配置，则认为该业务是稳定业务，默认返回 true。
+// gray.json 不可用时按没有实验处理，返回 A 组（没有可选的降级方式，原因见 getGrayConfig）。
 // 否则，调用具体业务的灰度实验配置进行判断。
 func ExperimentGroup(ctx context.Context, feature string, config ...*gray.GrayConfig) consts.TrafficGroup {
 	business := env.Business(ctx)
@@ -30,7 +145,7 @@ func ExperimentGroup(ctx context.Context, feature string, config ...*gray.GrayCo
 		return consts.TrafficGroup_A
 	}
 
-	conf, _ := getGrayConfig().Get()
+	conf := getGrayConfig()
 	if len(config) > 0 && config[0] != nil {
 		conf = *config[0]
 	}
@@ -43,6 +158,19 @@ func ExperimentGroup(ctx context.Context, feature string, config ...*gray.GrayCo
 	return conf[business].Experimental(ctx, feature)
 }
 
+// FeatureSelector 按功能实验的分组选择配置分组，用于 NacosConfig.SetGroupSelector，
+// 使配置灰度与功能实验使用相同的分桶，处于实验组的用户获取对应分组的配置。
+//
+// 示例：
+//
+//	cfg.SetGroupSelector(gray.FeatureSelector("new_token_url"))
+//	v, _ := cfg.GetCtx(ctx)
+func FeatureSelector(feature string) config.GroupSelector {
+	return func(ctx context.Context) consts.TrafficGroup {
+		return ExperimentGroup(ctx, feature)
+	}
+}
+
 // GetAllEnableFeature 获取所有启动状态的feat名称
 func GetAllEnableFeature(ctx context.Context) []string {
 	business := env.Business(ctx)
@@ -50,7 +178,7 @@ func GetAllEnableFeature(ctx context.Context) []string {
 		return nil
 	}
 
-	config, _ := getGrayConfig().Get()
+	config := getGrayConfig()
 	// 业务没有对应的配置，认为此业务是稳定的业务，直接返回 false
 	if _, exist := config[business]; !exist {
 		return nil
@@ -58,10 +186,37 @@ func GetAllEnableFeature(ctx context.Context) []string {
 
 	ret := make([]string, 0, len(config[business].Feature))
 	for feat, rule := range config[business].Feature {
-		if rule.Enable {
+		if rule.Enabled() {
 			ret = append(ret, feat)
 		}
 	}
 
 	return ret
 }
+
+// GetFeaturesByStatus 按生命周期状态列出当前业务下的所有实验名称，用于发现长期未清理的实验
+func GetFeaturesByStatus(ctx context.Context) map[FeatureStatus][]string {
+	business := env.Business(ctx)
+	if business == "" {
+		return nil
+	}
+
+	config := getGrayConfig()
+	if _, exist := config[business]; !exist {
+		return nil
+	}
+
+	bizConfig := config[business]
+	return bizConfig.FeaturesByStatus()
+}
+
+// GetAllFeaturesByStatus 按业务与生命周期状态列出所有实验名称
+func GetAllFeaturesByStatus() map[string]map[FeatureStatus][]string {
+	config := getGrayConfig()
+
+	ret := make(map[string]map[FeatureStatus][]string, len(config))
+	for business, bizConfig := range config {
+		ret[business] = bizConfig.FeaturesByStatus()
+	}
+	return ret
+}
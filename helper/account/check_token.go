@@ -5,9 +5,31 @@ import (
 	"fmt"
 
 	"github.com/everfir/go-helpers/consts"
+	"github.com/everfir/go-helpers/define/config"
+	"github.com/everfir/go-helpers/env"
+	"github.com/everfir/go-helpers/internal/helper/nacos"
 	"github.com/everfir/go-helpers/internal/service/account"
 )
 
+// FailMode: account_config.json 不可用时的降级方式
+type FailMode = nacos.FailMode
+
+// SetFailMode 设置 account_config.json 不可用时 CheckToken 的降级方式:
+//   - nacos.FailMode_Closed(默认): 返回错误, 按 token 校验失败处理
+//   - nacos.FailMode_Open: 使用环境变量 EVERFIR_ACCOUNT_FALLBACK_URL 指定的地址, 未指定时同样返回错误
+func SetFailMode(mode FailMode) {
+	account.SetFailMode(mode)
+}
+
+// SetGroupSelector 设置按请求选择 account_config.json 分组的方式, 使账号服务的灰度与功能实验使用相同的分桶;
+// 未设置(或传入 nil)时生产环境使用 B 组的配置, 其他环境使用默认配置
+//
+//	account.SetGroupSelector(gray.FeatureSelector("new_token_url"))
+func SetGroupSelector(selector config.GroupSelector) {
+	account.SetGroupSelector(selector)
+}
+
+// CheckToken 访问账号服务校验 token, 并将用户信息存储在 Context 中
 func CheckToken(ctx context.Context, token string) (nctx context.Context, err error) {
 	if token == "" {
 		err = fmt.Errorf("[go-helper] Invalid Token. should not be empty")
@@ -26,7 +48,8 @@ func CheckToken(ctx context.Context, token string) (nctx context.Context, err er
 		return ctx, err
 	}
 
-	// 将用户信息存储到 Context 中
+	// 将用户信息存储到 Context 中, 并挂载 Extra 解析缓存
 	nctx = context.WithValue(ctx, consts.AccountInfoKey, accountInfo.AccountInfo)
+	nctx = env.WithAccountExtra(nctx)
 	return nctx, nil
 }
@@ -0,0 +1,163 @@
+// Package discovery 基于 Nacos 服务发现解析下游服务地址，替代手工维护的服务地址映射
+//
+// 示例：
+//
+//	client := &http.Client{Transport: discovery.NewTransport(nil, nil)}
+//	resp, err := client.Get("http://user-account/account/check_token")
+//
+// 注册当前服务并在退出时先摘流再停止 HTTP 服务：
+//
+//	lifecycle := discovery.NewLifecycle(discovery.Registration{Service: "user-account"}, discovery.LifecycleOptions{})
+//	err := lifecycle.Serve(context.Background(), &http.Server{Addr: ":8080", Handler: engine})
+package discovery
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/everfir/go-helpers/internal/helper/discovery"
+	"github.com/nacos-group/nacos-sdk-go/clients/naming_client"
+)
+
+// Instance 服务实例
+type Instance = discovery.Instance
+
+// Registry 服务注册中心
+type Registry = discovery.Registry
+
+// Resolver 将服务名解析为实例，缓存实例列表并订阅变化
+type Resolver = discovery.Resolver
+
+// Balancer 负载均衡
+type Balancer = discovery.Balancer
+
+// BalancerFunc 函数形式的负载均衡
+type BalancerFunc = discovery.BalancerFunc
+
+// Transport 通过服务发现解析请求地址的 http.RoundTripper
+type Transport = discovery.Transport
+
+// ResolveError 服务解析失败
+type ResolveError = discovery.ResolveError
+
+// NacosRegistry 基于 Nacos 服务发现的注册中心
+type NacosRegistry = discovery.NacosRegistry
+
+// MemoryRegistry 内存注册中心，用于测试与本地开发
+type MemoryRegistry = discovery.MemoryRegistry
+
+// Registration 注册的实例信息
+type Registration = discovery.Registration
+
+// Registrar 实例注册，NacosRegistry 与 MemoryRegistry 均实现该接口
+type Registrar = discovery.Registrar
+
+// Lifecycle 实例注册与优雅退出
+type Lifecycle = discovery.Lifecycle
+
+// LifecycleOptions 实例注册与优雅退出的配置
+type LifecycleOptions = discovery.LifecycleOptions
+
+// ErrNoInstance 服务没有可用实例
+var ErrNoInstance = discovery.ErrNoInstance
+
+// 实例元数据
+const (
+	MetadataIdc     = discovery.MetadataIdc
+	MetadataEnv     = discovery.MetadataEnv
+	MetadataVersion = discovery.MetadataVersion
+	MetadataBuild   = discovery.MetadataBuild
+)
+
+// 实例注册相关的环境变量
+const (
+	ServiceNameKey    = discovery.ServiceNameKey
+	ServiceVersionKey = discovery.ServiceVersionKey
+	ServiceBuildKey   = discovery.ServiceBuildKey
+	PodIpKey          = discovery.PodIpKey
+)
+
+// NewResolver 创建解析器，balancer 为空时使用 SameIDC(Weighted())
+func NewResolver(registry Registry, balancer Balancer) *Resolver {
+	return discovery.NewResolver(registry, balancer)
+}
+
+// NewNacosRegistry 创建 Nacos 注册中心，group 为空时使用 DEFAULT_GROUP
+func NewNacosRegistry(client naming_client.INamingClient, group string) *NacosRegistry {
+	return discovery.NewNacosRegistry(client, group)
+}
+
+// NewMemoryRegistry 创建内存注册中心
+func NewMemoryRegistry() *MemoryRegistry {
+	return discovery.NewMemoryRegistry()
+}
+
+// RoundRobin 按服务轮询
+func RoundRobin() Balancer {
+	return discovery.RoundRobin()
+}
+
+// Weighted 按实例权重随机选择
+func Weighted() Balancer {
+	return discovery.Weighted()
+}
+
+// SameIDC 优先选择与当前服务同机房（env.Idc()）的实例，同机房没有实例时从全部实例中选择
+func SameIDC(next Balancer) Balancer {
+	return discovery.SameIDC(next)
+}
+
+// DefaultRegistry 基于 Nacos 服务发现的默认注册中心，创建失败时 panic
+func DefaultRegistry() *NacosRegistry {
+	return discovery.DefaultRegistry()
+}
+
+// Default 基于 Nacos 服务发现的默认解析器，服务分组通过 EVERFIR_DISCOVERY_GROUP 指定，默认 DEFAULT_GROUP，创建失败时 panic
+func Default() *Resolver {
+	return discovery.Default()
+}
+
+// LoadDefaultRegistry 创建默认注册中心，Nacos 服务发现客户端创建失败时返回错误
+func LoadDefaultRegistry() (*NacosRegistry, error) {
+	return discovery.LoadDefaultRegistry()
+}
+
+// LoadDefault 创建默认解析器，Nacos 服务发现客户端创建失败时返回错误
+func LoadDefault() (*Resolver, error) {
+	return discovery.LoadDefault()
+}
+
+// Resolve 使用默认解析器选择服务的一个实例
+func Resolve(service string) (Instance, error) {
+	resolver, err := discovery.LoadDefault()
+	if err != nil {
+		return Instance{}, err
+	}
+	return resolver.Resolve(service)
+}
+
+// NewTransport 创建通过服务发现解析地址的 http.RoundTripper
+//
+// resolver 为空时使用 Default()，next 为空时使用 http.DefaultTransport；
+// 服务没有实例或注册中心不可用时按原地址请求（Fallback），便于逐步迁移
+func NewTransport(resolver *Resolver, next http.RoundTripper) *Transport {
+	return discovery.NewTransport(resolver, next)
+}
+
+// IsServiceName 默认的服务名判断：不含端口与 "." 且不是 localhost
+func IsServiceName(host string) bool {
+	return discovery.IsServiceName(host)
+}
+
+// NewLifecycle 创建实例注册与优雅退出的辅助
+//
+// Registration 未填写的字段使用默认值：服务名 EVERFIR_SERVICE_NAME，IP 为 POD_IP 或本机地址，权重 1，
+// 元数据自动补充 env、idc、version、build；注册为临时实例，由客户端定时发送心跳
+func NewLifecycle(reg Registration, opts LifecycleOptions) *Lifecycle {
+	return discovery.NewLifecycle(reg, opts)
+}
+
+// Serve 启动 HTTP 服务并注册实例，收到 SIGTERM/SIGINT 后注销（或权重置 0）、等待 Drain，再停止 HTTP 服务
+func Serve(ctx context.Context, server *http.Server, reg Registration, opts LifecycleOptions) error {
+	return discovery.NewLifecycle(reg, opts).Serve(ctx, server)
+}
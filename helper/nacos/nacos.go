@@ -1,17 +1,18 @@
 package nacos
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/everfir/go-helpers/consts"
 	"github.com/everfir/go-helpers/define/config"
-	"github.com/everfir/go-helpers/env"
+	"github.com/everfir/go-helpers/internal/helper/codec"
 	internal_nacos "github.com/everfir/go-helpers/internal/helper/nacos"
-	"github.com/nacos-group/nacos-sdk-go/clients"
+	"github.com/everfir/go-helpers/internal/helper/secret"
+	"github.com/everfir/go-helpers/internal/structs"
 	"github.com/nacos-group/nacos-sdk-go/clients/config_client"
-	"github.com/nacos-group/nacos-sdk-go/common/constant"
-	"github.com/nacos-group/nacos-sdk-go/vo"
 )
 
 // NewClient 创建并初始化 Nacos 配置客户端
@@ -22,7 +23,8 @@ import (
 // 3. 创建并返回配置客户端实例
 //
 // 参数：
-//   - ip: Nacos 服务器 IP 地址
+//   - ip: Nacos 服务器地址，支持 "127.0.0.1"、"127.0.0.1:8848"、"https://host:8848/nacos"，
+//     多个地址用逗号分隔，未指定端口时使用 8848
 //   - namespace: 命名空间 ID，用于隔离不同环境的配置
 //   - username: Nacos 认证用户名
 //   - password: Nacos 认证密码
@@ -40,35 +42,111 @@ import (
 //	if err != nil {
 //	    log.Fatal("Failed to create Nacos client:", err)
 //	}
+//
+// 需要配置 TLS、超时、缓存目录等时使用 NewClientWithOptions
 func NewClient(ip, namespace, username, password string) (nacosClient config_client.IConfigClient, err error) {
-	cc := constant.ClientConfig{
-		NamespaceId:         namespace,
-		TimeoutMs:           60000,
-		NotLoadCacheAtStart: true,
-		LogDir:              "",
-		CacheDir:            "",
-		LogLevel:            "error",
+	opts := ClientOptions{
+		Namespace:           namespace,
 		Username:            username,
 		Password:            password,
+		Timeout:             60 * time.Second,
+		NotLoadCacheAtStart: true,
+		LogLevel:            "error",
 	}
-
-	sc := []constant.ServerConfig{
-		{
-			IpAddr: ip,
-			Port:   8848,
-		},
+	for _, addr := range strings.Split(ip, ",") {
+		server, err := ParseServerAddr(addr, ServerAddr{Scheme: "http", Port: 8848, ContextPath: "/nacos"})
+		if err != nil {
+			return nil, fmt.Errorf("[go-helper] nacos.NewClient failed: %w", err)
+		}
+		opts.Servers = append(opts.Servers, server)
 	}
+	return NewClientWithOptions(opts)
+}
+
+// ClientOptions Nacos 客户端配置：服务地址、命名空间、账号、超时、缓存与日志目录、TLS
+type ClientOptions = internal_nacos.ClientOptions
+
+// ServerAddr Nacos 服务地址
+type ServerAddr = internal_nacos.ServerAddr
+
+// TLSOptions Nacos TLS 配置
+type TLSOptions = internal_nacos.TLSOptions
+
+// DefaultClientOptions 按 IDC 与环境内置的默认配置
+func DefaultClientOptions() ClientOptions {
+	return internal_nacos.DefaultClientOptions()
+}
+
+// ClientOptionsFromEnv 在默认配置基础上读取 EVERFIR_NACOS_* 环境变量，GetEverfirNacosClient 使用该配置
+//
+// 示例：
+//
+//	EVERFIR_NACOS_ADDRS=https://10.0.0.1:8848,https://10.0.0.2:8848
+//	EVERFIR_NACOS_TIMEOUT=10s
+//	EVERFIR_NACOS_TLS_CA=/etc/nacos/ca.pem
+func ClientOptionsFromEnv() (ClientOptions, error) {
+	return internal_nacos.ClientOptionsFromEnv()
+}
+
+// ParseServerAddr 解析服务地址，未指定的协议、端口、路径使用 defaults 中的值
+func ParseServerAddr(s string, defaults ServerAddr) (ServerAddr, error) {
+	return internal_nacos.ParseServerAddr(s, defaults)
+}
+
+// Credential Nacos 账号，String 与 JSON 序列化时密码脱敏
+type Credential = internal_nacos.Credential
+
+// CredentialProvider Nacos 账号提供者，ClientOptions.Credentials 与 OpenAPI.Credentials 使用；
+// OpenAPI 每次登录时读取账号，Nacos SDK 客户端只在创建时读取，轮换密码后需要重启服务
+type CredentialProvider = internal_nacos.CredentialProvider
+
+// CredentialProviderFunc 函数形式的账号提供者
+type CredentialProviderFunc = internal_nacos.CredentialProviderFunc
+
+// EnvCredentialProvider 从环境变量读取账号，默认 EVERFIR_NACOS_USERNAME、EVERFIR_NACOS_PASSWORD
+type EnvCredentialProvider = internal_nacos.EnvCredentialProvider
+
+// FileCredentialProvider 从挂载的 secrets 文件或目录读取账号，文件变化后自动重新加载，已创建的 Nacos SDK 客户端不受影响
+type FileCredentialProvider = internal_nacos.FileCredentialProvider
+
+// ChainCredentialProvider 依次尝试多个账号提供者
+type ChainCredentialProvider = internal_nacos.ChainCredentialProvider
 
-	configClient, err := clients.CreateConfigClient(map[string]interface{}{
-		"clientConfig":  cc,
-		"serverConfigs": sc,
-	})
+// ErrNoCredential 账号提供者没有可用账号
+var ErrNoCredential = internal_nacos.ErrNoCredential
+
+// NewFileCredentialProvider 创建文件账号提供者，每隔 interval 检查文件是否变化，interval <= 0 时为 10s
+func NewFileCredentialProvider(path string, interval time.Duration) *FileCredentialProvider {
+	return internal_nacos.NewFileCredentialProvider(path, interval)
+}
+
+// CredentialProviderFromEnv 默认的账号提供者：环境变量、EVERFIR_NACOS_CREDENTIALS_FILE 指定的文件
+func CredentialProviderFromEnv() CredentialProvider {
+	return internal_nacos.CredentialProviderFromEnv()
+}
+
+// Redact 隐藏敏感信息，用于日志输出
+func Redact(secret string) string {
+	return internal_nacos.Redact(secret)
+}
+
+// NewClientWithOptions 按配置创建 Nacos 配置客户端，多个服务地址时请求失败会切换到其他地址
+//
+// 示例：
+//
+//	opts, err := ClientOptionsFromEnv()
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	opts.RegisterFlags(flag.CommandLine)
+//	flag.Parse()
+//	client, err := NewClientWithOptions(opts)
+func NewClientWithOptions(opts ClientOptions) (config_client.IConfigClient, error) {
+	client, err := internal_nacos.NewConfigClient(opts)
 	if err != nil {
-		err = fmt.Errorf("[go-helper] nacos.NewClient failed: %w", err)
-		return nil, err
+		return nil, fmt.Errorf("[go-helper] nacos.NewClient failed: %w", err)
 	}
-
-	return configClient, nil
+	return client, nil
 }
 
 // GetEverfirNacosClient 获取 Everfir 预配置的 Nacos 客户端实例，用于操作全局业务配置
@@ -97,11 +175,179 @@ func GetEverfirNacosClient() config_client.IConfigClient {
 	return internal_nacos.GetNacosClient()
 }
 
+// LoadEverfirNacosClient 获取预配置的 Nacos 客户端，与 GetEverfirNacosClient 相同，创建失败时返回错误而不是 panic
+func LoadEverfirNacosClient() (config_client.IConfigClient, error) {
+	return internal_nacos.LoadNacosClient()
+}
+
+// ConfigSource 配置源，屏蔽 Nacos、本地目录、环境变量、内存等不同存储的差异
+type ConfigSource = internal_nacos.ConfigSource
+
+// ConfigChange 配置变更通知
+type ConfigChange = internal_nacos.ConfigChange
+
+// PublishParam 发布配置参数
+type PublishParam = internal_nacos.PublishParam
+
+// NewNacosSource 基于 Nacos 配置客户端创建配置源
+func NewNacosSource(client config_client.IConfigClient) *internal_nacos.NacosSource {
+	return internal_nacos.NewNacosSource(client)
+}
+
+// NewDirSource 基于本地目录创建配置源，目录结构为 {dir}/{group}/{dataId}，通过 inotify 监听变更
+func NewDirSource(dir string) (*internal_nacos.DirSource, error) {
+	return internal_nacos.NewDirSource(dir)
+}
+
+// NewEnvSource 基于环境变量创建只读配置源，变量名为 EVERFIR_CONFIG_{GROUP}_{DATAID}
+func NewEnvSource() *internal_nacos.EnvSource {
+	return internal_nacos.NewEnvSource()
+}
+
+// NewMemorySource 创建进程内配置源，适用于测试
+func NewMemorySource() *internal_nacos.MemorySource {
+	return internal_nacos.NewMemorySource()
+}
+
+// FallbackPolicy 配置源不可用时的降级策略
+type FallbackPolicy = internal_nacos.FallbackPolicy
+
+const (
+	FallbackPolicy_None     = internal_nacos.FallbackPolicy_None
+	FallbackPolicy_Snapshot = internal_nacos.FallbackPolicy_Snapshot
+	FallbackPolicy_Default  = internal_nacos.FallbackPolicy_Default
+)
+
+// FallbackOptions 降级配置：策略、快照目录、重连间隔
+type FallbackOptions = internal_nacos.FallbackOptions
+
+// ActiveSource 配置当前的实际来源：live、snapshot、default
+type ActiveSource = internal_nacos.ActiveSource
+
+// DefaultFallbackOptions 默认降级配置：优先使用快照，快照目录为 {os.TempDir()}/everfir-config-snapshot，每 10s 重连
+func DefaultFallbackOptions() FallbackOptions {
+	return internal_nacos.DefaultFallbackOptions()
+}
+
+// NewFallbackSource 为配置源增加本地快照与降级能力
+//
+// 每个通过校验的配置版本会原子写入快照目录；配置源不可用时按降级策略使用快照或编译期默认值（WithDefault），
+// 并在后台定期重连，恢复后切换回实时配置并通知监听器。
+//
+// 示例：
+//
+//	source, err := nacos.NewFallbackSource(nacos.NewNacosSource(client), nacos.DefaultFallbackOptions())
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	cfg, err := nacos.GetConfigAndListenFromSource[AppConfig](source, "app.json", nacos.WithDefault(AppConfig{Port: 8080}))
+func NewFallbackSource(primary ConfigSource, opts FallbackOptions) (*internal_nacos.FallbackSource, error) {
+	return internal_nacos.NewFallbackSource(primary, opts)
+}
+
+// SetConfigSource 设置内置配置（停服、业务、灰度、账号）使用的默认配置源，需要在首次加载配置前调用
+//
+// 示例：
+//
+//	source, err := nacos.NewDirSource("./configs")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	nacos.SetConfigSource(source)
+func SetConfigSource(source ConfigSource) {
+	internal_nacos.SetConfigSource(source)
+}
+
+// GetConfigSource 获取默认配置源，未设置时根据环境变量 EVERFIR_CONFIG_SOURCE（nacos/dir/env）创建，
+// Nacos 配置源会按 EVERFIR_CONFIG_FALLBACK（snapshot/default/none）包装为 FallbackSource
+//
+// 创建失败时 panic，需要处理错误时使用 LoadConfigSource
+func GetConfigSource() ConfigSource {
+	return internal_nacos.GetConfigSource()
+}
+
+// LoadConfigSource 获取默认配置源，与 GetConfigSource 相同，创建失败时返回错误而不是 panic，下次调用重新创建
+func LoadConfigSource() (ConfigSource, error) {
+	return internal_nacos.LoadConfigSource()
+}
+
+// Format 配置格式
+type Format = codec.Format
+
+const (
+	Format_JSON       = codec.Format_JSON
+	Format_YAML       = codec.Format_YAML
+	Format_TOML       = codec.Format_TOML
+	Format_Properties = codec.Format_Properties
+	Format_Text       = codec.Format_Text
+)
+
+// FormatOf 根据 dataId 的扩展名推断配置格式：
+// .json、.yaml/.yml、.toml、.properties、.txt/.text，无法识别时使用 JSON
+func FormatOf(dataId string) Format {
+	return codec.FormatOf(dataId)
+}
+
+// Option 获取、发布配置时的可选参数
+type Option = internal_nacos.Option
+
+// WithFormat 指定配置格式，默认根据 dataId 的扩展名推断
+//
+// YAML、TOML、properties 格式的字段名与 JSON 一致，均使用结构体的 `json` 标签；
+// text 格式要求配置类型为 string、[]byte 或实现 encoding.TextUnmarshaler/TextMarshaler。
+func WithFormat(format Format) Option {
+	return internal_nacos.WithFormat(format)
+}
+
+// WithDefault 指定编译期默认配置，配置源与本地快照均不可用时使用，只对 FallbackSource 生效
+func WithDefault(value any) Option {
+	return internal_nacos.WithDefault(value)
+}
+
+// WithGray 获取配置时加载该 dataId 下所有灰度分组的配置
+func WithGray() Option {
+	return internal_nacos.WithGray()
+}
+
+// WithOverlay 以分层模式加载配置：基础配置（{env} 分组）-> 流量分组补丁（{env}_{group}）-> 业务补丁（{env}.{business}），
+// 按 RFC 7386 合并，补丁只需包含与基础配置不同的字段
+//
+// 示例：
+//
+//	cfg, err := nacos.GetConfigAndListenFromSource[AppConfig](nacos.GetConfigSource(), "app.json", nacos.WithOverlay())
+//	v, _ := cfg.GetWithBusiness(env.Business(ctx), env.ExperimentGroup(ctx))
+func WithOverlay() Option {
+	return internal_nacos.WithOverlay()
+}
+
+// WithDiscoveryInterval 指定灰度配置（WithGray、WithOverlay）定期发现新增、删除分组的间隔，默认 30s，小于等于 0 时关闭发现
+func WithDiscoveryInterval(interval time.Duration) Option {
+	return internal_nacos.WithDiscoveryInterval(interval)
+}
+
+// WithTrafficGroup 发布配置时发布到指定流量分组，默认发布到当前环境分组
+func WithTrafficGroup(group consts.TrafficGroup) Option {
+	return internal_nacos.WithTrafficGroup(group)
+}
+
+// GetConfigAndListenFromSource 从指定配置源获取配置并监听配置变更，行为与 GetConfigAndListen 一致
+//
+// 示例：
+//
+//	cfg, err := GetConfigAndListenFromSource[AppConfig](source, "app-config.yaml", WithGray())
+func GetConfigAndListenFromSource[T any](
+	source ConfigSource,
+	dataId string,
+	opts ...Option,
+) (config *config.NacosConfig[T], err error) {
+	return internal_nacos.GetConfigAndListenFromSource[T](source, dataId, opts...)
+}
+
 // GetConfigAndListen 从 Nacos 获取配置并监听配置变更
 //
 // 该函数执行以下操作：
 // 1. 从 Nacos 服务器获取指定 dataId 的配置
-// 2. 将配置内容反序列化为指定类型
+// 2. 按 dataId 扩展名对应的格式（JSON、YAML、TOML、properties、text）将配置内容反序列化为指定类型
 // 3. 注册配置变更监听器，当配置发生变化时自动更新
 //
 // 参数：
@@ -143,7 +389,7 @@ func GetConfigAndListen[T any](
 // 参数：
 //   - client: Nacos 配置客户端
 //   - dataId: 配置 ID
-//   - data: 要发布的配置数据
+//   - data: 要发布的配置数据，按 dataId 扩展名对应的格式序列化
 //   - group: 配置分组，可选参数，默认使用环境变量作为分组
 //
 // 返回值：
@@ -156,32 +402,215 @@ func GetConfigAndListen[T any](
 //	    log.Fatal(err)
 //	}
 func Publish[T any](client config_client.IConfigClient, dataId string, data T, group ...consts.TrafficGroup) (err error) {
-	var content string
-	b, err := json.Marshal(data)
-	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
-	}
-	content = string(b)
-
-	var g string
+	var opts []Option
 	if len(group) > 0 {
-		g = fmt.Sprintf("%s_%s", env.Env(), group[0].Group())
-	} else {
-		g = env.Env()
+		opts = append(opts, WithTrafficGroup(group[0]))
 	}
+	return PublishToSource[T](internal_nacos.NewNacosSource(client), dataId, data, opts...)
+}
 
-	// 发布配置到 Nacos
-	success, err := client.PublishConfig(vo.ConfigParam{
-		DataId:  dataId,
-		Group:   g,
-		Content: content, // 配置内容
-		Type:    vo.JSON, // 配置类型
-	})
-	if err != nil {
-		return fmt.Errorf("failed to publish config: %w", err)
-	}
-	if !success {
-		return fmt.Errorf("publish config failed without error")
-	}
-	return nil
+// PublishToSource 向指定配置源发布配置，行为与 Publish 一致
+//
+// 示例：
+//
+//	err := PublishToSource(source, "app-config.yaml", cfg, WithTrafficGroup(consts.TrafficGroup_B))
+func PublishToSource[T any](source ConfigSource, dataId string, data T, opts ...Option) (err error) {
+	return internal_nacos.Publish[T](source, dataId, data, opts...)
+}
+
+// CASResult PublishCAS 的结果：最终内容、版本（md5）、修改前版本与尝试次数
+type CASResult = internal_nacos.CASResult
+
+// OpenAPI Nacos Open API 客户端，为 NacosSource 提供带 casMd5 的发布
+type OpenAPI = internal_nacos.OpenAPI
+
+var (
+	// ErrCASConflict 重试次数用尽后配置仍被并发修改
+	ErrCASConflict = internal_nacos.ErrCASConflict
+	// ErrCASUnsupported 配置源不支持 CAS 发布
+	ErrCASUnsupported = internal_nacos.ErrCASUnsupported
+	// ErrCASCreateUnsupported 配置不存在且配置源无法原子地创建（Nacos），需要先通过 Publish 创建
+	ErrCASCreateUnsupported = internal_nacos.ErrCASCreateUnsupported
+)
+
+// WithCASRetries 指定 PublishCAS 冲突时的重试次数，默认 3 次
+func WithCASRetries(retries int) Option {
+	return internal_nacos.WithCASRetries(retries)
+}
+
+// PublishCAS 读取配置并调用 mutate 修改，按加载流程（默认值、解密、Validator）校验后以 casMd5 发布原始值，
+// 配置在读取后被其他人修改时重新读取并重试，避免多个管理工具同时修改时互相覆盖
+//
+// 参数：
+//   - source: 配置源，需要支持 CAS 发布；Nacos 配置源需要通过 NacosSource.WithOpenAPI 设置 Open API，
+//     GetConfigSource 返回的默认配置源已设置
+//   - dataId: 配置 ID
+//   - mutate: 修改函数，返回错误时放弃发布
+//   - opts: WithTrafficGroup 指定分组，WithFormat 指定格式，WithCASRetries 指定重试次数
+//
+// 返回值：
+//   - result: 最终发布的内容与版本，可用于审计
+//   - err: 重试次数用尽时返回 ErrCASConflict；配置不存在且配置源无法原子地创建时返回 ErrCASCreateUnsupported
+//
+// 示例：
+//
+//	result, err := nacos.PublishCAS(nacos.GetConfigSource(), "shutdown.json", func(cfg *map[string]bool) error {
+//	    (*cfg)["momo"] = true
+//	    return nil
+//	})
+func PublishCAS[T any](source ConfigSource, dataId string, mutate func(data *T) error, opts ...Option) (CASResult, error) {
+	return internal_nacos.PublishCAS[T](source, dataId, mutate, opts...)
+}
+
+// Read 读取配置的当前原始值（不补全默认值、不解密）与版本（md5），不注册监听，适用于管理工具
+func Read[T any](source ConfigSource, dataId string, opts ...Option) (T, string, error) {
+	return internal_nacos.Read[T](source, dataId, opts...)
+}
+
+// ConfigHistory 配置的历史版本
+type ConfigHistory = internal_nacos.ConfigHistory
+
+// ErrHistoryUnsupported 配置源不支持查询历史版本
+var ErrHistoryUnsupported = internal_nacos.ErrHistoryUnsupported
+
+// ListHistory 分页列出配置的历史版本（时间倒序，pageNo 从 1 开始），分组由 WithTrafficGroup 指定
+//
+// Nacos 配置源通过 Open API 查询，需要通过 NacosSource.WithOpenAPI 设置，GetConfigSource 返回的默认配置源已设置。
+func ListHistory(source ConfigSource, dataId string, pageNo, pageSize int, opts ...Option) ([]ConfigHistory, error) {
+	return internal_nacos.ListHistory(source, dataId, pageNo, pageSize, opts...)
+}
+
+// GetVersion 获取配置的指定历史版本并解析为 T
+func GetVersion[T any](source ConfigSource, dataId string, id string, opts ...Option) (T, ConfigHistory, error) {
+	return internal_nacos.GetVersion[T](source, dataId, id, opts...)
+}
+
+// Rollback 将配置回滚到指定历史版本：按 T 校验后带 casMd5 原样发布历史内容（保留 T 中没有的字段与注释），冲突检测同 PublishCAS
+//
+// 示例：
+//
+//	histories, err := nacos.ListHistory(source, "business.json", 1, 10)
+//	result, err := nacos.Rollback[BusinessConfig](source, "business.json", histories[1].Id)
+func Rollback[T any](source ConfigSource, dataId string, id string, opts ...Option) (CASResult, error) {
+	return internal_nacos.Rollback[T](source, dataId, id, opts...)
+}
+
+// ConfigStatus 配置的状态：dataId、加载方式、是否加载成功，以及各分组的 md5、生效时间、被拒绝的变更
+type ConfigStatus = internal_nacos.ConfigStatus
+
+// GroupStatus 配置分组的状态
+type GroupStatus = internal_nacos.GroupStatus
+
+// ConfigStatuses 获取进程内通过 GetConfigAndListen 系列函数创建的所有配置的状态，
+// 配置内容中的敏感字段（password、secret、token 等）已脱敏；配置关闭（NacosConfig.Close）后不再返回
+func ConfigStatuses() []ConfigStatus {
+	return internal_nacos.ConfigStatuses()
+}
+
+// RequireConfigs 声明服务依赖的配置，这些配置没有加载成功时 CheckConfigHealth 返回错误
+//
+// 示例：
+//
+//	nacos.RequireConfigs("shutdown.json", "business.json")
+func RequireConfigs(dataIds ...string) {
+	internal_nacos.RequireConfigs(dataIds...)
+}
+
+// CheckConfigHealth 检查配置状态：配置加载失败或 RequireConfigs 声明的配置未加载时返回错误
+func CheckConfigHealth() error {
+	return internal_nacos.CheckConfigHealth()
+}
+
+// ApplyDefaults 按 struct tag 补全配置：`default:"5s"` 在字段为零值时生效，`env:"ACCOUNT_TIMEOUT"` 在环境变量不为空时覆盖字段，
+// 支持 time.Duration、切片与嵌套结构体
+//
+// GetConfigAndListen 系列函数在解析配置后、Validator 之前自动补全，并按配置内容判断字段是否缺失：
+// 配置中显式的 false、0、"" 不会被默认值覆盖；直接调用 ApplyDefaults 时没有配置内容，零值字段视为缺失
+func ApplyDefaults(v any) error {
+	return structs.ApplyDefaults(v)
+}
+
+// FailMode 依赖的配置不可用（加载失败）时的降级方式
+type FailMode = internal_nacos.FailMode
+
+const (
+	FailMode_Default = internal_nacos.FailMode_Default // 使用各组件文档中说明的默认降级方式
+	FailMode_Open    = internal_nacos.FailMode_Open    // 放行，按没有限制处理
+	FailMode_Closed  = internal_nacos.FailMode_Closed  // 拒绝，返回错误
+)
+
+// NewLazyConfig 创建延迟加载的配置，首次 Get 或 InitConfigs 时从 GetConfigSource 返回的默认配置源加载，
+// 加载失败时返回错误而不是 panic，之后的 Get 间隔一段时间在后台重新尝试加载；
+// Get 不会等待加载，未加载成功时直接返回上一次的错误（首次加载未完成时为 ErrConfigLoading），
+// 需要在处理请求前加载的配置使用 InitConfigs
+//
+// 示例：
+//
+//	var appConfig = nacos.NewLazyConfig[AppConfig]("app.json")
+//
+//	cfg, err := appConfig.Get()
+//	if err != nil {
+//	    // 配置不可用，按业务需要降级
+//	}
+//	v, _ := cfg.Get()
+func NewLazyConfig[T any](dataId string, opts ...Option) *internal_nacos.LazyConfig[T] {
+	return internal_nacos.NewLazyConfig[T](dataId, opts...)
+}
+
+// ErrConfigLoading NewLazyConfig 创建的配置正在后台首次加载，Get 返回的错误
+var ErrConfigLoading = internal_nacos.ErrConfigLoading
+
+// InitOptions InitConfigs 的选项：需要加载的配置（为空时不加载）、重试次数与间隔
+type InitOptions = internal_nacos.InitOptions
+
+// InitConfigs 在服务启动时加载指定的通过 NewLazyConfig 创建的配置（包括中间件、灰度、账号使用的配置），
+// 失败时重试，直到全部加载成功、重试次数用完或 ctx 结束（没有 deadline 时最多 30s），返回每个加载失败的配置的错误；
+// 加载的配置通过 RequireConfigs 声明为依赖
+func InitConfigs(ctx context.Context, opts InitOptions) error {
+	return internal_nacos.InitConfigs(ctx, opts)
+}
+
+// Keyring 解密配置使用的 AES-GCM 密钥集合，使用 Primary 加密，按加密值中的 keyId 解密，支持密钥轮换
+type Keyring = secret.Keyring
+
+// NewKeyring 创建密钥集合，keys 为 keyId 到密钥（16、24 或 32 字节）的映射，primary 为加密使用的 keyId
+func NewKeyring(primary string, keys map[string][]byte) (*Keyring, error) {
+	return secret.NewKeyring(primary, keys)
+}
+
+// KeyringFromEnv 按环境变量创建密钥集合，没有配置密钥时返回 nil：
+//   - EVERFIR_CONFIG_KEY_FILE: 密钥文件，每行一个 "keyId:base64 密钥"
+//   - EVERFIR_CONFIG_KEYS: 未指定密钥文件时使用，格式为 "keyId:base64 密钥,keyId:base64 密钥"
+//   - EVERFIR_CONFIG_KEY_ID: 加密使用的 keyId，默认为第一个密钥
+func KeyringFromEnv() (*Keyring, error) {
+	return secret.KeyringFromEnv()
+}
+
+// SetKeyring 设置解密配置使用的默认密钥集合，需要在首次加载配置前调用，传入 nil 时按环境变量创建
+func SetKeyring(k *Keyring) {
+	secret.SetKeyring(k)
+}
+
+// Encrypt 使用默认密钥集合加密配置值，返回 "enc:<keyId>:<base64>" 格式的加密值，可直接写入配置发布
+//
+// GetConfigAndListen 系列函数在解析配置、补全默认值之后，Validator 之前自动解密带有 enc: 前缀的字符串，
+// 以及带有 `encrypted:"true"` tag 的字段（可以省略前缀）
+//
+// 示例：
+//
+//	password, err := nacos.Encrypt("p@ss")
+//	cfg.Db.Password = password
+//	err = nacos.PublishToSource(source, "app.json", cfg)
+func Encrypt(plaintext string) (string, error) {
+	return secret.Encrypt(plaintext)
+}
+
+// DecryptFields 使用默认密钥集合解密配置中的加密值，v 需要是指针，用于自行解析的配置
+func DecryptFields(v any) error {
+	return secret.DecryptFields(v)
+}
+
+// GenerateKey 生成 AES-256 密钥（base64 编码），与 keyId 组成 "keyId:base64 密钥" 写入密钥文件
+func GenerateKey() (string, error) {
+	return secret.GenerateKey()
 }
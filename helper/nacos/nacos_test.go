@@ -1,61 +1,43 @@
 package nacos_test
 
 import (
-	"context"
 	"testing"
 	"time"
 
 	"github.com/everfir/go-helpers/consts"
+	"github.com/everfir/go-helpers/env"
 	"github.com/everfir/go-helpers/helper/nacos"
-	"github.com/everfir/logger-go"
-	"github.com/everfir/logger-go/structs/field"
-	"github.com/everfir/logger-go/structs/log_level"
+	"github.com/everfir/go-helpers/helper/nacos/nacostest"
+	"github.com/zeebo/assert"
 )
 
 // TestGetConfigAndListen 测试从 Nacos 获取配置并监听配置变更
 func TestGetConfigAndListen(t *testing.T) {
-	logger.Init(logger.WithLevel(log_level.InfoLevel))
-	client := nacos.GetEverfirNacosClient()
+	client := nacostest.NewConfigClient()
+	defer client.Close()
 
-	cfg, err := nacos.GetConfigAndListen[map[string]bool](client, "shutdown.json", true)
-	if err != nil {
-		t.Fatalf("Failed to get config from Nacos: %v", err)
-	}
+	client.Seed(env.Env(), map[string]string{"shutdown.json": `{"a":false}`})
+	client.Seed(env.Env()+"_b", map[string]string{"shutdown.json": `{"b":false}`})
 
-	// 前提： 需要先配置好配置
+	cfg, err := nacos.GetConfigAndListen[map[string]bool](client, "shutdown.json", true)
+	assert.NoError(t, err)
 
 	cfgDetail, exist := cfg.Get()
-	logger.Info(context.Background(), "A组配置 ", field.Any("config", cfgDetail), field.Bool("exist", exist))
-	// 修改配置, 观察日志
-	nacos.Publish(client, "shutdown.json", map[string]bool{
-		"a": true,
-	})
-
-	cfgDetail, exist = cfg.Get()
-	logger.Info(context.Background(), "B组配置 ", field.Any("config", cfgDetail), field.Bool("exist", exist))
-	// 修改配置, 观察日志
-	nacos.Publish(client, "shutdown.json", map[string]bool{
-		"b": true,
-	}, consts.TrafficGroup_B)
-
-	// 修改配置, 观察日志
+	assert.True(t, exist)
+	assert.DeepEqual(t, cfgDetail, map[string]bool{"a": false})
+
+	// 不存在的分组降级为 A 组配置
 	cfgDetail, exist = cfg.Get(consts.TrafficGroup_Z)
-	logger.Info(context.Background(), "Z组配置 ", field.Any("config", cfgDetail), field.Bool("exist", exist))
-	nacos.Publish(client, "shutdown.json", map[string]bool{
-		"z": true,
-	}, consts.TrafficGroup_Z)
+	assert.False(t, exist)
 
-	logger.Info(context.Background(), "等待10s后结束")
-	for i := 0; i < 3; i++ {
-		time.Sleep(1 * time.Second)
-	}
+	// 修改配置, 等待监听器回调
+	assert.NoError(t, nacos.Publish(client, "shutdown.json", map[string]bool{"a": true}))
+	assert.NoError(t, nacos.Publish(client, "shutdown.json", map[string]bool{"b": true}, consts.TrafficGroup_B))
+	assert.True(t, client.WaitForDelivery(time.Second))
 
+	cfgDetail, _ = cfg.Get()
+	assert.DeepEqual(t, cfgDetail, map[string]bool{"a": true})
 	cfgDetail, exist = cfg.Get(consts.TrafficGroup_B)
-	logger.Info(context.Background(), "修改之后的A组配置 ", field.Any("config", cfgDetail), field.Bool("exist", exist))
-	cfgDetail, exist = cfg.Get(consts.TrafficGroup_B)
-	logger.Info(context.Background(), "修改之后的B组配置 ", field.Any("config", cfgDetail), field.Bool("exist", exist))
-	cfgDetail, exist = cfg.Get(consts.TrafficGroup_Z)
-	logger.Info(context.Background(), "修改之后的Z组配置 ", field.Any("config", cfgDetail), field.Bool("exist", exist))
-
-	logger.Info(context.Background(), "test done")
+	assert.True(t, exist)
+	assert.DeepEqual(t, cfgDetail, map[string]bool{"b": true})
 }
@@ -0,0 +1,365 @@
+// Package nacostest 提供进程内的 Nacos 配置客户端实现，用于在不依赖 Nacos 服务器的情况下测试配置相关逻辑。
+//
+// 示例：
+//
+//	client := nacostest.NewConfigClient()
+//	client.Seed("test", map[string]string{"shutdown.json": `{"momo":false}`})
+//
+//	cfg, err := nacos.GetConfigAndListen[map[string]bool](client, "shutdown.json")
+//	client.SetConfig("shutdown.json", "test", `{"momo":true}`)
+//	client.WaitForDelivery(time.Second)
+package nacostest
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"path"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nacos-group/nacos-sdk-go/clients/config_client"
+	"github.com/nacos-group/nacos-sdk-go/model"
+	"github.com/nacos-group/nacos-sdk-go/vo"
+)
+
+var _ config_client.IConfigClient = (*ConfigClient)(nil)
+
+// Option 配置 ConfigClient
+type Option func(client *ConfigClient)
+
+// WithNamespace 设置命名空间，回调监听器时作为 namespace 参数
+func WithNamespace(namespace string) Option {
+	return func(client *ConfigClient) {
+		client.namespace = namespace
+	}
+}
+
+// NewConfigClient 创建进程内的 Nacos 配置客户端
+func NewConfigClient(opts ...Option) *ConfigClient {
+	client := &ConfigClient{
+		configs:   map[configKey]*model.ConfigItem{},
+		listeners: map[configKey][]func(namespace, group, dataId, data string){},
+	}
+	client.cond = sync.NewCond(&client.queueLock)
+	for _, opt := range opts {
+		opt(client)
+	}
+
+	go client.deliver()
+	return client
+}
+
+type configKey struct {
+	dataId string
+	group  string
+}
+
+// ConfigClient 实现 config_client.IConfigClient 的内存版本
+//
+// 与真实客户端一致，监听器在独立的 goroutine 中按发布顺序回调，
+// 且只有配置内容（md5）发生变化时才会回调。
+type ConfigClient struct {
+	namespace string
+
+	lock      sync.RWMutex
+	configs   map[configKey]*model.ConfigItem
+	listeners map[configKey][]func(namespace, group, dataId, data string)
+	err       error
+	nextId    int
+
+	queueLock sync.Mutex
+	cond      *sync.Cond
+	queue     []func()
+	pending   int
+	closed    bool
+}
+
+// Seed 批量写入某个分组下的配置，key 为 dataId
+func (c *ConfigClient) Seed(group string, configs map[string]string) {
+	dataIds := make([]string, 0, len(configs))
+	for dataId := range configs {
+		dataIds = append(dataIds, dataId)
+	}
+	sort.Strings(dataIds)
+
+	for _, dataId := range dataIds {
+		c.SetConfig(dataId, group, configs[dataId])
+	}
+}
+
+// SetConfig 写入配置，内容变化时回调监听器
+func (c *ConfigClient) SetConfig(dataId, group, content string) {
+	c.set(vo.ConfigParam{DataId: dataId, Group: group, Content: content, Type: vo.JSON})
+}
+
+// Md5 获取配置内容的 md5，配置不存在时返回空字符串
+func (c *ConfigClient) Md5(dataId, group string) string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if item, exist := c.configs[configKey{dataId, group}]; exist {
+		return item.Md5
+	}
+	return ""
+}
+
+// FailWith 模拟服务端故障，之后所有请求都返回 err，传入 nil 恢复正常
+func (c *ConfigClient) FailWith(err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.err = err
+}
+
+// WaitForDelivery 等待所有已触发的监听器回调执行完成，超时返回 false
+func (c *ConfigClient) WaitForDelivery(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		c.queueLock.Lock()
+		for c.pending > 0 {
+			c.cond.Wait()
+		}
+		c.queueLock.Unlock()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// Close 停止投递监听器回调，未投递的回调会被丢弃
+func (c *ConfigClient) Close() {
+	c.queueLock.Lock()
+	defer c.queueLock.Unlock()
+
+	c.closed = true
+	c.pending -= len(c.queue)
+	c.queue = nil
+	c.cond.Broadcast()
+}
+
+func (c *ConfigClient) GetConfig(param vo.ConfigParam) (string, error) {
+	if param.DataId == "" || param.Group == "" {
+		return "", errors.New("[client.GetConfig] param.dataId and param.group can not be empty")
+	}
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if c.err != nil {
+		return "", c.err
+	}
+
+	// 与真实客户端一致，配置不存在时返回空内容
+	if item, exist := c.configs[configKey{param.DataId, param.Group}]; exist {
+		return item.Content, nil
+	}
+	return "", nil
+}
+
+func (c *ConfigClient) PublishConfig(param vo.ConfigParam) (bool, error) {
+	if param.DataId == "" || param.Group == "" || param.Content == "" {
+		return false, errors.New("[client.PublishConfig] param.dataId, param.group and param.content can not be empty")
+	}
+
+	c.lock.RLock()
+	err := c.err
+	c.lock.RUnlock()
+	if err != nil {
+		return false, err
+	}
+
+	c.set(param)
+	return true, nil
+}
+
+func (c *ConfigClient) DeleteConfig(param vo.ConfigParam) (bool, error) {
+	if param.DataId == "" || param.Group == "" {
+		return false, errors.New("[client.DeleteConfig] param.dataId and param.group can not be empty")
+	}
+
+	key := configKey{param.DataId, param.Group}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.err != nil {
+		return false, c.err
+	}
+	if _, exist := c.configs[key]; !exist {
+		return true, nil
+	}
+
+	// 与真实客户端一致，删除后以空内容回调监听器
+	delete(c.configs, key)
+	c.notify(key, "")
+	return true, nil
+}
+
+func (c *ConfigClient) ListenConfig(param vo.ConfigParam) error {
+	if param.DataId == "" || param.Group == "" || param.OnChange == nil {
+		return errors.New("[client.ListenConfig] param.dataId, param.group and param.onChange can not be empty")
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.err != nil {
+		return c.err
+	}
+
+	key := configKey{param.DataId, param.Group}
+	c.listeners[key] = append(c.listeners[key], param.OnChange)
+	return nil
+}
+
+func (c *ConfigClient) CancelListenConfig(param vo.ConfigParam) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	delete(c.listeners, configKey{param.DataId, param.Group})
+	return nil
+}
+
+// SearchConfig 搜索配置
+//
+// search=accurate 时 dataId、group 精确匹配，search=blur 时支持 * 通配符，为空的条件不参与过滤。
+func (c *ConfigClient) SearchConfig(param vo.SearchConfigParam) (*model.ConfigPage, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	items := make([]model.ConfigItem, 0)
+	for key, item := range c.configs {
+		if match(param.Search, param.DataId, key.dataId) && match(param.Search, param.Group, key.group) {
+			items = append(items, *item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Group != items[j].Group {
+			return items[i].Group < items[j].Group
+		}
+		return items[i].DataId < items[j].DataId
+	})
+
+	pageNo, pageSize := param.PageNo, param.PageSize
+	if pageNo <= 0 {
+		pageNo = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 10
+	}
+
+	page := &model.ConfigPage{
+		TotalCount:     len(items),
+		PageNumber:     pageNo,
+		PagesAvailable: (len(items) + pageSize - 1) / pageSize,
+	}
+	start := (pageNo - 1) * pageSize
+	if start < len(items) {
+		end := start + pageSize
+		if end > len(items) {
+			end = len(items)
+		}
+		page.PageItems = items[start:end]
+	}
+	return page, nil
+}
+
+func (c *ConfigClient) PublishAggr(param vo.ConfigParam) (bool, error) {
+	return false, errors.New("[nacostest] PublishAggr is not supported")
+}
+
+func (c *ConfigClient) set(param vo.ConfigParam) {
+	key := configKey{param.DataId, param.Group}
+	sum := md5.Sum([]byte(param.Content))
+	md5sum := hex.EncodeToString(sum[:])
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	item, exist := c.configs[key]
+	if exist && item.Md5 == md5sum {
+		return
+	}
+	if !exist {
+		c.nextId++
+		item = &model.ConfigItem{Id: jsonNumber(c.nextId), DataId: param.DataId, Group: param.Group, Tenant: c.namespace}
+		c.configs[key] = item
+	}
+	item.Content = param.Content
+	item.Md5 = md5sum
+	c.notify(key, param.Content)
+}
+
+// notify 将回调加入投递队列, 调用方需持有 c.lock
+func (c *ConfigClient) notify(key configKey, content string) {
+	listeners := c.listeners[key]
+	if len(listeners) == 0 {
+		return
+	}
+
+	c.queueLock.Lock()
+	defer c.queueLock.Unlock()
+
+	if c.closed {
+		return
+	}
+	for _, onChange := range listeners {
+		onChange := onChange
+		c.queue = append(c.queue, func() {
+			onChange(c.namespace, key.group, key.dataId, content)
+		})
+		c.pending++
+	}
+	c.cond.Broadcast()
+}
+
+func (c *ConfigClient) deliver() {
+	for {
+		c.queueLock.Lock()
+		for len(c.queue) == 0 && !c.closed {
+			c.cond.Wait()
+		}
+		if c.closed {
+			c.queueLock.Unlock()
+			return
+		}
+		fn := c.queue[0]
+		c.queue = c.queue[1:]
+		c.queueLock.Unlock()
+
+		fn()
+
+		c.queueLock.Lock()
+		c.pending--
+		c.cond.Broadcast()
+		c.queueLock.Unlock()
+	}
+}
+
+func match(search, pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	if search == "blur" {
+		ok, _ := path.Match(pattern, value)
+		return ok
+	}
+	return pattern == value
+}
+
+func jsonNumber(i int) json.Number {
+	return json.Number(strconv.Itoa(i))
+}
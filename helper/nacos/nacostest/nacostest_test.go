@@ -0,0 +1,64 @@
+package nacostest_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/everfir/go-helpers/helper/nacos/nacostest"
+	"github.com/nacos-group/nacos-sdk-go/vo"
+	"github.com/zeebo/assert"
+)
+
+func TestConfigClient(t *testing.T) {
+	client := nacostest.NewConfigClient(nacostest.WithNamespace("ns"))
+	defer client.Close()
+
+	client.Seed("test", map[string]string{"a.json": `{}`, "b.json": `{}`})
+	client.Seed("test_b", map[string]string{"a.json": `{}`})
+
+	// 不存在的配置返回空内容
+	content, err := client.GetConfig(vo.ConfigParam{DataId: "c.json", Group: "test"})
+	assert.NoError(t, err)
+	assert.Equal(t, content, "")
+
+	page, err := client.SearchConfig(vo.SearchConfigParam{Search: "accurate", DataId: "a.json"})
+	assert.NoError(t, err)
+	assert.Equal(t, page.TotalCount, 2)
+	page, err = client.SearchConfig(vo.SearchConfigParam{Search: "blur", Group: "test*", PageSize: 2, PageNo: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, page.TotalCount, 3)
+	assert.Equal(t, len(page.PageItems), 1)
+
+	var lock sync.Mutex
+	var received []string
+	assert.NoError(t, client.ListenConfig(vo.ConfigParam{
+		DataId: "a.json",
+		Group:  "test",
+		OnChange: func(namespace, group, dataId, data string) {
+			lock.Lock()
+			defer lock.Unlock()
+			assert.Equal(t, namespace, "ns")
+			received = append(received, data)
+		},
+	}))
+
+	// 内容不变时不回调
+	_, err = client.PublishConfig(vo.ConfigParam{DataId: "a.json", Group: "test", Content: `{}`})
+	assert.NoError(t, err)
+	_, err = client.PublishConfig(vo.ConfigParam{DataId: "a.json", Group: "test", Content: `{"v":1}`})
+	assert.NoError(t, err)
+	assert.Equal(t, client.Md5("a.json", "test"), "a191475ae2bf7db9c7e320f7da455bbb")
+	_, err = client.DeleteConfig(vo.ConfigParam{DataId: "a.json", Group: "test"})
+	assert.NoError(t, err)
+
+	assert.True(t, client.WaitForDelivery(time.Second))
+	lock.Lock()
+	assert.DeepEqual(t, received, []string{`{"v":1}`, ""})
+	lock.Unlock()
+
+	client.FailWith(errors.New("nacos down"))
+	_, err = client.GetConfig(vo.ConfigParam{DataId: "b.json", Group: "test"})
+	assert.Error(t, err)
+}
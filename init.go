@@ -0,0 +1,65 @@
+// Package helpers 提供服务启动时的统一初始化入口
+package helpers
+
+import (
+	"context"
+	"time"
+
+	"github.com/everfir/go-helpers/helper/account"
+	"github.com/everfir/go-helpers/helper/nacos"
+	"github.com/everfir/go-helpers/middleware"
+)
+
+// Options: Init 的选项
+type Options struct {
+	// Configs 需要加载的配置 dataId, 为空时只设置降级方式、不加载配置; 按服务用到的组件列出:
+	// shutdown.json(ShutdownMiddleware)、business.json(BusinessMiddleware)、gray.json(gray 包)、
+	// account_config.json(CheckToken) 以及通过 nacos.NewLazyConfig 创建的配置, 所在的包需要被引入
+	Configs []string
+	// Timeout 初始化的超时时间, ctx 已有 deadline 时忽略, 默认 30s
+	Timeout time.Duration
+	// Retries 加载失败后的重试次数, 默认 3, 小于 0 时不重试
+	Retries int
+	// Backoff 首次重试的间隔, 之后每次翻倍, 默认 500ms
+	Backoff time.Duration
+
+	// ShutdownFailMode shutdown.json 不可用时 ShutdownMiddleware 的降级方式, 默认放行, 见 middleware.SetShutdownFailMode
+	ShutdownFailMode nacos.FailMode
+	// BusinessFailMode business.json 不可用时 BusinessMiddleware 的降级方式, 默认返回 503, 见 middleware.SetBusinessFailMode
+	BusinessFailMode nacos.FailMode
+	// AccountFailMode account_config.json 不可用时 CheckToken 的降级方式, 默认返回错误, 见 account.SetFailMode
+	AccountFailMode nacos.FailMode
+	// gray.json 不可用时固定为没有实验(所有流量走 A 组), 没有可选的降级方式
+}
+
+// Init 在服务启动时设置降级方式并加载依赖的配置, 替代首次请求时才加载配置(加载失败时 panic)的方式
+//
+// 加载失败时按选项重试, 直到全部加载成功、重试次数用完或超时, 返回每个加载失败的配置的错误;
+// 服务可以据此退出, 也可以忽略错误启动: 未加载的配置在使用时间隔一段时间重新加载, 期间按降级方式处理请求
+//
+// 示例:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+//	defer cancel()
+//	if err := helpers.Init(ctx, helpers.Options{Configs: []string{"shutdown.json", "business.json"}}); err != nil {
+//	    log.Fatal(err)
+//	}
+func Init(ctx context.Context, opts Options) error {
+	middleware.SetShutdownFailMode(opts.ShutdownFailMode)
+	middleware.SetBusinessFailMode(opts.BusinessFailMode)
+	account.SetFailMode(opts.AccountFailMode)
+
+	if opts.Timeout > 0 {
+		if _, ok := ctx.Deadline(); !ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+			defer cancel()
+		}
+	}
+
+	return nacos.InitConfigs(ctx, nacos.InitOptions{
+		Configs: opts.Configs,
+		Retries: opts.Retries,
+		Backoff: opts.Backoff,
+	})
+}
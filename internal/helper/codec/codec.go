@@ -0,0 +1,207 @@
+package codec
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Format: 配置格式
+type Format string
+
+const (
+	Format_JSON       Format = "json"
+	Format_YAML       Format = "yaml"
+	Format_TOML       Format = "toml"
+	Format_Properties Format = "properties"
+	Format_Text       Format = "text"
+)
+
+// FormatOf: 根据 dataId 的扩展名推断配置格式, 无法识别时使用 JSON
+func FormatOf(dataId string) Format {
+	switch strings.ToLower(filepath.Ext(dataId)) {
+	case ".yaml", ".yml":
+		return Format_YAML
+	case ".toml":
+		return Format_TOML
+	case ".properties":
+		return Format_Properties
+	case ".txt", ".text":
+		return Format_Text
+	default:
+		return Format_JSON
+	}
+}
+
+// Valid: 是否为支持的格式
+func (f Format) Valid() bool {
+	switch f {
+	case Format_JSON, Format_YAML, Format_TOML, Format_Properties, Format_Text:
+		return true
+	default:
+		return false
+	}
+}
+
+// ConfigType: Nacos 中的配置类型, Nacos 不支持 TOML, 以 text 保存
+func (f Format) ConfigType() string {
+	switch f {
+	case Format_YAML:
+		return "yaml"
+	case Format_Properties:
+		return "properties"
+	case Format_TOML, Format_Text:
+		return "text"
+	default:
+		return "json"
+	}
+}
+
+// Decode: 按格式解析配置内容到 v
+//
+// YAML、TOML、properties 会先解析为通用结构再转换为 JSON 解析到 v, 因此字段名沿用 `json` 标签,
+// 与 JSON 格式的配置共用同一套结构体定义。
+// YAML 与 properties 中看起来像数字或布尔值的标量解析到字符串字段时保留原始文本(例如 1.10、13800000000),
+// TOML 的值有明确类型, 与 TOML 原生解析一致, 数字不能解析到字符串字段。
+// text 格式要求 v 为 *string、*[]byte 或实现 encoding.TextUnmarshaler。
+func Decode(format Format, content string, v any) error {
+	switch format {
+	case Format_JSON:
+		return json.Unmarshal([]byte(content), v)
+	case Format_Text:
+		return decodeText(content, v)
+	}
+
+	var data any
+	var err error
+	switch format {
+	case Format_YAML:
+		data, err = decodeYAML(content)
+	case Format_TOML:
+		err = toml.Unmarshal([]byte(content), &data)
+	case Format_Properties:
+		data, err = decodeProperties(content)
+	default:
+		return fmt.Errorf("unsupported config format: %s", format)
+	}
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(typed(data, reflect.TypeOf(v)))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// Encode: 按格式序列化配置
+//
+// 与 Decode 对应, YAML、TOML、properties 的字段名沿用 `json` 标签。
+func Encode(format Format, v any) (string, error) {
+	switch format {
+	case Format_JSON:
+		b, err := json.Marshal(v)
+		return string(b), err
+	case Format_Text:
+		return encodeText(v)
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	var data any
+	if err = decoder.Decode(&data); err != nil {
+		return "", err
+	}
+	data = normalize(data)
+
+	switch format {
+	case Format_YAML:
+		b, err = yaml.Marshal(data)
+	case Format_TOML:
+		b, err = toml.Marshal(data)
+	case Format_Properties:
+		return encodeProperties(data)
+	default:
+		return "", fmt.Errorf("unsupported config format: %s", format)
+	}
+	return string(b), err
+}
+
+// normalize: 将通用结构转换为 JSON/YAML/TOML 均可序列化的形式
+//   - map[any]any 的键转为字符串
+//   - json.Number 转为 int64 或 float64
+func normalize(data any) any {
+	switch v := data.(type) {
+	case map[string]any:
+		ret := make(map[string]any, len(v))
+		for key, val := range v {
+			ret[key] = normalize(val)
+		}
+		return ret
+	case map[any]any:
+		ret := make(map[string]any, len(v))
+		for key, val := range v {
+			ret[fmt.Sprint(key)] = normalize(val)
+		}
+		return ret
+	case []any:
+		ret := make([]any, len(v))
+		for i, val := range v {
+			ret[i] = normalize(val)
+		}
+		return ret
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	default:
+		return v
+	}
+}
+
+func decodeText(content string, v any) error {
+	switch t := v.(type) {
+	case *string:
+		*t = content
+	case *[]byte:
+		*t = []byte(content)
+	case encoding.TextUnmarshaler:
+		return t.UnmarshalText([]byte(content))
+	default:
+		return fmt.Errorf("text config requires *string, *[]byte or encoding.TextUnmarshaler, got %T", v)
+	}
+	return nil
+}
+
+func encodeText(v any) (string, error) {
+	switch t := v.(type) {
+	case string:
+		return t, nil
+	case *string:
+		return *t, nil
+	case []byte:
+		return string(t), nil
+	case encoding.TextMarshaler:
+		b, err := t.MarshalText()
+		return string(b), err
+	default:
+		return "", fmt.Errorf("text config requires string, []byte or encoding.TextMarshaler, got %T", v)
+	}
+}
@@ -0,0 +1,261 @@
+package codec
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/zeebo/assert"
+)
+
+type inner struct {
+	Name  string   `json:"name"`
+	Ports []int    `json:"ports"`
+	Tags  []string `json:"tags,omitempty"`
+}
+
+type sample struct {
+	Enable  bool           `json:"enable"`
+	Rate    float64        `json:"rate"`
+	Count   int64          `json:"count"`
+	Version string         `json:"version"`
+	Inner   inner          `json:"inner"`
+	Extra   map[string]int `json:"extra"`
+}
+
+var want = sample{
+	Enable:  true,
+	Rate:    0.5,
+	Count:   9007199254740993,
+	Version: "1.0",
+	Inner:   inner{Name: "svc", Ports: []int{80, 443}},
+	Extra:   map[string]int{"a": 1},
+}
+
+func TestFormatOf(t *testing.T) {
+	assert.Equal(t, FormatOf("gray.json"), Format_JSON)
+	assert.Equal(t, FormatOf("app.YAML"), Format_YAML)
+	assert.Equal(t, FormatOf("app.yml"), Format_YAML)
+	assert.Equal(t, FormatOf("app.toml"), Format_TOML)
+	assert.Equal(t, FormatOf("app.properties"), Format_Properties)
+	assert.Equal(t, FormatOf("notice.txt"), Format_Text)
+	assert.Equal(t, FormatOf("app-config"), Format_JSON)
+}
+
+func TestDecode(t *testing.T) {
+	contents := map[Format]string{
+		Format_JSON: `{"enable":true,"rate":0.5,"count":9007199254740993,"version":"1.0",
+			"inner":{"name":"svc","ports":[80,443]},"extra":{"a":1}}`,
+		Format_YAML: `
+enable: true
+rate: 0.5
+count: 9007199254740993
+version: "1.0"
+inner:
+  name: svc
+  ports: [80, 443]
+extra:
+  a: 1
+`,
+		Format_TOML: `
+enable = true
+rate = 0.5
+count = 9007199254740993
+version = "1.0"
+
+[inner]
+name = "svc"
+ports = [80, 443]
+
+[extra]
+a = 1
+`,
+		Format_Properties: `
+# comment
+enable=true
+rate: 0.5
+count = 9007199254740993
+version="1.0"
+inner.name=s\
+  vc
+inner.ports.0=80
+inner.ports.1=443
+extra.a=1
+`,
+	}
+
+	for format, content := range contents {
+		var got sample
+		assert.NoError(t, Decode(format, content, &got))
+		assert.DeepEqual(t, got, want)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, format := range []Format{Format_JSON, Format_YAML, Format_TOML, Format_Properties} {
+		content, err := Encode(format, want)
+		assert.NoError(t, err)
+
+		var got sample
+		assert.NoError(t, Decode(format, content, &got))
+		assert.DeepEqual(t, got, want)
+	}
+}
+
+func TestProperties(t *testing.T) {
+	var got map[string]any
+	assert.NoError(t, Decode(Format_Properties, "a.b=x y\nlist.0=1\nlist.2=3\n! comment\nempty\n", &got))
+	assert.DeepEqual(t, got, map[string]any{
+		"a":     map[string]any{"b": "x y"},
+		"list":  map[string]any{"0": float64(1), "2": float64(3)},
+		"empty": "",
+	})
+
+	content, err := Encode(Format_Properties, map[string]any{"k": "true", "s": " lead"})
+	assert.NoError(t, err)
+	assert.Equal(t, content, "k=\"true\"\ns=\\ lead\n")
+
+	assert.Error(t, Decode(Format_Properties, "a=1\na.b=2\n", &got))
+}
+
+func TestText(t *testing.T) {
+	var s string
+	assert.NoError(t, Decode(Format_Text, "hello", &s))
+	assert.Equal(t, s, "hello")
+
+	var ts time.Time
+	assert.NoError(t, Decode(Format_Text, "2024-01-02T03:04:05Z", &ts))
+	assert.Equal(t, ts.Year(), 2024)
+
+	content, err := Encode(Format_Text, ts)
+	assert.NoError(t, err)
+	assert.Equal(t, content, "2024-01-02T03:04:05Z")
+
+	var m map[string]string
+	assert.Error(t, Decode(Format_Text, "x", &m))
+}
+
+func TestMergePatch(t *testing.T) {
+	// RFC 7386 附录 A 的用例
+	cases := []struct{ target, patch, want string }{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, c := range cases {
+		target, err := DecodeTree(Format_JSON, c.target)
+		assert.NoError(t, err)
+		patch, err := DecodeTree(Format_JSON, c.patch)
+		assert.NoError(t, err)
+
+		got, err := Encode(Format_JSON, MergePatch(target, patch))
+		assert.NoError(t, err)
+		assert.Equal(t, got, c.want)
+	}
+
+	// 不同格式的层可以合并, JSON 大整数不丢失精度
+	base, err := DecodeTree(Format_JSON, `{"count":9007199254740993,"inner":{"name":"svc","ports":[80]}}`)
+	assert.NoError(t, err)
+	patch, err := DecodeTree(Format_YAML, "inner:\n  ports: [80, 443]\n")
+	assert.NoError(t, err)
+	var got sample
+	content, err := Encode(Format_JSON, MergePatch(base, patch))
+	assert.NoError(t, err)
+	assert.NoError(t, Decode(Format_JSON, content, &got))
+	assert.Equal(t, got.Count, int64(9007199254740993))
+	assert.DeepEqual(t, got.Inner, inner{Name: "svc", Ports: []int{80, 443}})
+
+	_, err = DecodeTree(Format_Text, "x")
+	assert.Error(t, err)
+}
+
+func TestDecodeNumericString(t *testing.T) {
+	type contact struct {
+		Phone   string  `json:"phone"`
+		Version string  `json:"version"`
+		Enable  string  `json:"enable"`
+		Rate    float64 `json:"rate"`
+	}
+	type config struct {
+		contact
+		Contacts []contact         `json:"contacts"`
+		Labels   map[string]string `json:"labels"`
+		Any      map[string]any    `json:"any"`
+	}
+
+	cases := []struct {
+		format  Format
+		content string
+	}{
+		{Format_YAML, `
+phone: 13800000000
+version: 1.10
+enable: yes
+rate: 0.50
+contacts:
+  - &c {phone: 13800000000, version: 1.10, enable: true, rate: 0.5}
+  - <<: *c
+labels: {version: 1.10}
+any: {version: 1.10}
+`},
+		{Format_Properties, `
+phone=13800000000
+version=1.10
+enable=yes
+rate=0.50
+contacts.0.phone=13800000000
+contacts.0.version=1.10
+contacts.0.enable=true
+contacts.0.rate=0.5
+contacts.1.phone=13800000000
+contacts.1.version=1.10
+contacts.1.enable=true
+contacts.1.rate=0.5
+labels.version=1.10
+any.version=1.10
+`},
+	}
+
+	for _, c := range cases {
+		var got config
+		assert.NoError(t, Decode(c.format, c.content, &got))
+		assert.Equal(t, got.Phone, "13800000000")
+		assert.Equal(t, got.Version, "1.10")
+		assert.Equal(t, got.Enable, "yes")
+		assert.Equal(t, got.Rate, 0.5)
+		assert.DeepEqual(t, got.Contacts, []contact{
+			{Phone: "13800000000", Version: "1.10", Enable: "true", Rate: 0.5},
+			{Phone: "13800000000", Version: "1.10", Enable: "true", Rate: 0.5},
+		})
+		assert.DeepEqual(t, got.Labels, map[string]string{"version": "1.10"})
+		// 无法确定目标类型时使用推断出的值
+		assert.DeepEqual(t, got.Any, map[string]any{"version": 1.1})
+	}
+
+	// 合并多层配置后按目标类型转换
+	base, err := DecodeTree(Format_Properties, "phone=13800000000\n")
+	assert.NoError(t, err)
+	patch, err := DecodeTree(Format_YAML, "version: 1.10\n")
+	assert.NoError(t, err)
+	b, err := json.Marshal(TypedTree(MergePatch(base, patch), new(config)))
+	assert.NoError(t, err)
+	var got config
+	assert.NoError(t, json.Unmarshal(b, &got))
+	assert.Equal(t, got.Phone, "13800000000")
+	assert.Equal(t, got.Version, "1.10")
+
+	// TOML 的值有明确类型, 数字不能解析到字符串字段
+	assert.Error(t, Decode(Format_TOML, "version = 1.10\n", &got))
+}
@@ -0,0 +1,69 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// DecodeTree: 按格式解析配置内容为通用结构(map[string]any、[]any、标量), 用于合并多层配置
+//
+// JSON 的数字保留为 json.Number, 避免大整数丢失精度; YAML 与 properties 的非字符串标量保留原始文本,
+// 解析到结构体前需要经过 TypedTree; text 格式没有结构, 不支持
+func DecodeTree(format Format, content string) (any, error) {
+	var data any
+	var err error
+	switch format {
+	case Format_JSON:
+		decoder := json.NewDecoder(bytes.NewReader([]byte(content)))
+		decoder.UseNumber()
+		err = decoder.Decode(&data)
+		return data, err
+	case Format_YAML:
+		data, err = decodeYAML(content)
+	case Format_TOML:
+		err = toml.Unmarshal([]byte(content), &data)
+	case Format_Properties:
+		data, err = decodeProperties(content)
+	default:
+		return nil, fmt.Errorf("unsupported tree format: %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return normalize(data), nil
+}
+
+// TypedTree: 按 v 的类型转换 DecodeTree 的结果, JSON 序列化后再解析到 v 时字符串字段保留标量的原始文本
+func TypedTree(tree any, v any) any {
+	return typed(tree, reflect.TypeOf(v))
+}
+
+// MergePatch: 按 RFC 7386 将 patch 合并到 target, 返回合并结果, 不修改入参
+//   - patch 为对象时逐个字段合并, 字段值为 null 表示删除该字段
+//   - patch 为其他类型(包括数组)时整体替换 target
+func MergePatch(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	t, ok := target.(map[string]any)
+	ret := make(map[string]any, len(t)+len(p))
+	if ok {
+		for key, val := range t {
+			ret[key] = val
+		}
+	}
+	for key, val := range p {
+		if val == nil {
+			delete(ret, key)
+			continue
+		}
+		ret[key] = MergePatch(ret[key], val)
+	}
+	return ret
+}
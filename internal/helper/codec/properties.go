@@ -0,0 +1,276 @@
+package codec
+
+import (
+	"bufio"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// decodeProperties: 解析 properties 格式
+//
+//   - 支持 "key=value"、"key: value" 与 "key value" 三种写法, "#" 与 "!" 开头的行为注释, 行尾 "\" 续行
+//   - 键按 "." 拆分为嵌套对象, 子键全部为从 0 开始的连续整数时视为数组
+//   - 值按 YAML 标量的规则推断类型(true/false、整数、浮点数), 用双引号包裹的值始终为字符串,
+//     推断为其他类型的值解析到字符串字段时使用原始文本
+func decodeProperties(content string) (any, error) {
+	root := map[string]any{}
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	var logical strings.Builder
+	for scanner.Scan() {
+		line := strings.TrimLeft(scanner.Text(), " \t\f")
+		if logical.Len() == 0 && (line == "" || line[0] == '#' || line[0] == '!') {
+			continue
+		}
+
+		// 行尾为奇数个反斜杠时续行
+		if n := len(line) - len(strings.TrimRight(line, `\`)); n%2 == 1 {
+			logical.WriteString(line[:len(line)-1])
+			continue
+		}
+		logical.WriteString(line)
+
+		key, value := splitProperty(logical.String())
+		logical.Reset()
+		if err := setProperty(root, key, value); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if logical.Len() > 0 {
+		key, value := splitProperty(logical.String())
+		if err := setProperty(root, key, value); err != nil {
+			return nil, err
+		}
+	}
+
+	return toArrays(root), nil
+}
+
+func splitProperty(line string) (key, value string) {
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '=', ':', ' ', '\t', '\f':
+			key = line[:i]
+			rest := strings.TrimLeft(line[i:], " \t\f")
+			if rest != "" && (rest[0] == '=' || rest[0] == ':') && (line[i] == ' ' || line[i] == '\t' || line[i] == '\f') {
+				rest = rest[1:]
+			} else if line[i] == '=' || line[i] == ':' {
+				rest = line[i+1:]
+			}
+			return unescapeProperty(key), unescapeProperty(strings.TrimLeft(rest, " \t\f"))
+		}
+	}
+	return unescapeProperty(line), ""
+}
+
+func unescapeProperty(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if i+4 < len(s) {
+				if r, err := strconv.ParseUint(s[i+1:i+5], 16, 32); err == nil {
+					b.WriteRune(rune(r))
+					i += 4
+					continue
+				}
+			}
+			b.WriteByte('u')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+func setProperty(root map[string]any, key, value string) error {
+	keys := strings.Split(key, ".")
+	cur := root
+	for i, k := range keys[:len(keys)-1] {
+		next, exist := cur[k]
+		if !exist {
+			m := map[string]any{}
+			cur[k] = m
+			cur = m
+			continue
+		}
+		m, ok := next.(map[string]any)
+		if !ok {
+			return fmt.Errorf("properties key %q conflicts with %q", key, strings.Join(keys[:i+1], "."))
+		}
+		cur = m
+	}
+
+	last := keys[len(keys)-1]
+	if _, ok := cur[last].(map[string]any); ok {
+		return fmt.Errorf("properties key %q conflicts with its children", key)
+	}
+	switch v := propertyScalar(value).(type) {
+	case string:
+		cur[last] = v
+	default:
+		cur[last] = scalar{text: value, value: v}
+	}
+	return nil
+}
+
+func propertyScalar(value string) any {
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		if s, err := strconv.Unquote(value); err == nil {
+			return s
+		}
+	}
+	if b, err := strconv.ParseBool(value); err == nil && (value == "true" || value == "false") {
+		return b
+	}
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return i
+	}
+	if u, err := strconv.ParseUint(value, 10, 64); err == nil {
+		return u
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return f
+	}
+	return value
+}
+
+// toArrays: 子键全部为从 0 开始的连续整数的对象转换为数组
+func toArrays(data any) any {
+	m, ok := data.(map[string]any)
+	if !ok {
+		return data
+	}
+
+	for k, v := range m {
+		m[k] = toArrays(v)
+	}
+	if len(m) == 0 {
+		return m
+	}
+
+	arr := make([]any, len(m))
+	for k, v := range m {
+		i, err := strconv.Atoi(k)
+		if err != nil || i < 0 || i >= len(m) || strconv.Itoa(i) != k {
+			return m
+		}
+		arr[i] = v
+	}
+	return arr
+}
+
+// encodeProperties: 将通用结构展开为 properties 格式, 键有序
+func encodeProperties(data any) (string, error) {
+	lines := map[string]string{}
+	if err := flattenProperties("", data, lines); err != nil {
+		return "", err
+	}
+
+	keys := make([]string, 0, len(lines))
+	for k := range lines {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(escapeProperty(k, true))
+		b.WriteByte('=')
+		b.WriteString(lines[k])
+		b.WriteByte('\n')
+	}
+	return b.String(), nil
+}
+
+func flattenProperties(prefix string, data any, lines map[string]string) error {
+	join := func(k string) string {
+		if prefix == "" {
+			return k
+		}
+		return prefix + "." + k
+	}
+
+	switch v := data.(type) {
+	case map[string]any:
+		for k, val := range v {
+			if err := flattenProperties(join(k), val, lines); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for i, val := range v {
+			if err := flattenProperties(join(strconv.Itoa(i)), val, lines); err != nil {
+				return err
+			}
+		}
+	case nil:
+	case string:
+		// 会被推断为其他类型的字符串需要加引号, 保证解析后类型不变
+		if _, ok := propertyScalar(v).(string); !ok || (len(v) >= 2 && v[0] == '"' && v[len(v)-1] == '"') {
+			lines[prefix] = escapeProperty(strconv.Quote(v), false)
+		} else {
+			lines[prefix] = escapeProperty(v, false)
+		}
+	case bool, int64, uint64, float64:
+		lines[prefix] = fmt.Sprint(v)
+	default:
+		return fmt.Errorf("unsupported properties value %T at %q", v, prefix)
+	}
+	return nil
+}
+
+func escapeProperty(s string, key bool) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '=', ':', '#', '!':
+			if key || i == 0 {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(r)
+		case ' ':
+			if key || i == 0 {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
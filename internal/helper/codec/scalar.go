@@ -0,0 +1,206 @@
+package codec
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// scalar: 保留原始文本的非字符串标量, 解析到字符串字段时使用原始文本, 否则使用推断出的值
+//
+// 例如 YAML 的 version: 1.10 与 properties 的 phone=13800000000 可以解析到 string 字段, 且不丢失末尾的 0
+type scalar struct {
+	text  string // 原始文本
+	value any    // 按格式规则推断的值
+}
+
+// MarshalJSON: 序列化为推断出的值
+func (s scalar) MarshalJSON() ([]byte, error) {
+	return json.Marshal(normalize(s.value))
+}
+
+// decodeYAML: 解析 YAML 为通用结构, 标量的类型与 yaml.v3 一致, 非字符串标量保留原始文本
+func decodeYAML(content string) (any, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		return nil, err
+	}
+	return yamlTree(&doc)
+}
+
+func yamlTree(n *yaml.Node) (any, error) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return nil, nil
+		}
+		return yamlTree(n.Content[0])
+	case yaml.AliasNode:
+		return yamlTree(n.Alias)
+	case yaml.SequenceNode:
+		arr := make([]any, 0, len(n.Content))
+		for _, item := range n.Content {
+			v, err := yamlTree(item)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		return arr, nil
+	case yaml.MappingNode:
+		m := map[string]any{}
+		var merges []*yaml.Node
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, val := n.Content[i], n.Content[i+1]
+			if key.Kind == yaml.ScalarNode && key.ShortTag() == "!!merge" {
+				merges = append(merges, val)
+				continue
+			}
+			v, err := yamlTree(val)
+			if err != nil {
+				return nil, err
+			}
+			m[key.Value] = v
+		}
+		// 合并键 "<<" 引入的字段优先级低于显式字段, 多个合并对象时靠前的优先
+		for _, merge := range merges {
+			sources := []*yaml.Node{merge}
+			if merge.Kind == yaml.SequenceNode {
+				sources = merge.Content
+			}
+			for _, source := range sources {
+				v, err := yamlTree(source)
+				if err != nil {
+					return nil, err
+				}
+				fields, ok := v.(map[string]any)
+				if !ok {
+					return nil, fmt.Errorf("yaml merge value at line %d is not a mapping", source.Line)
+				}
+				for key, val := range fields {
+					if _, exist := m[key]; !exist {
+						m[key] = val
+					}
+				}
+			}
+		}
+		return m, nil
+	default:
+		var value any
+		if err := n.Decode(&value); err != nil {
+			return nil, err
+		}
+		switch value.(type) {
+		case nil, string:
+			return value, nil
+		default:
+			return scalar{text: n.Value, value: value}, nil
+		}
+	}
+}
+
+// typed: 按目标类型 t 将通用结构转换为可 JSON 序列化的值
+//
+// 目标为字符串或 encoding.TextUnmarshaler 时标量使用原始文本, 其他情况(包括无法确定目标类型)使用推断出的值
+func typed(data any, t reflect.Type) any {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	// 自定义 UnmarshalJSON 的类型无法确定内部结构
+	if t != nil && (t.Implements(jsonUnmarshalerType) || reflect.PointerTo(t).Implements(jsonUnmarshalerType)) {
+		t = nil
+	}
+
+	switch v := data.(type) {
+	case scalar:
+		if t != nil && (t.Kind() == reflect.String || reflect.PointerTo(t).Implements(textUnmarshalerType)) {
+			return v.text
+		}
+		return normalize(v.value)
+	case map[string]any:
+		ret := make(map[string]any, len(v))
+		for key, val := range v {
+			ret[key] = typed(val, fieldType(t, key))
+		}
+		return ret
+	case []any:
+		var elem reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			elem = t.Elem()
+		}
+		ret := make([]any, len(v))
+		for i, val := range v {
+			ret[i] = typed(val, elem)
+		}
+		return ret
+	default:
+		return normalize(v)
+	}
+}
+
+// fieldType: 对象的 key 在目标类型中对应的类型, 无法确定时返回 nil
+func fieldType(t reflect.Type, key string) reflect.Type {
+	if t == nil {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Map:
+		return t.Elem()
+	case reflect.Struct:
+		return structField(t, key)
+	default:
+		return nil
+	}
+}
+
+// structField: 按 encoding/json 的规则查找字段类型, 精确匹配 json 名称优先, 其次不区分大小写, 最后查找嵌入的结构体
+func structField(t reflect.Type, key string) reflect.Type {
+	var fold, embedded reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if embedded == nil {
+					embedded = structField(ft, key)
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+		if name == key {
+			return f.Type
+		}
+		if fold == nil && strings.EqualFold(name, key) {
+			fold = f.Type
+		}
+	}
+	if fold != nil {
+		return fold
+	}
+	return embedded
+}
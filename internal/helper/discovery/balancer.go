@@ -0,0 +1,74 @@
+package discovery
+
+import (
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+
+	"github.com/everfir/go-helpers/env"
+)
+
+// Balancer: 负载均衡, 从服务的实例中选择一个, instances 不为空
+type Balancer interface {
+	Pick(service string, instances []Instance) (Instance, error)
+}
+
+// BalancerFunc: 函数形式的负载均衡
+type BalancerFunc func(service string, instances []Instance) (Instance, error)
+
+func (fn BalancerFunc) Pick(service string, instances []Instance) (Instance, error) {
+	return fn(service, instances)
+}
+
+// RoundRobin: 按服务轮询
+func RoundRobin() Balancer {
+	var counters sync.Map // service -> *atomic.Uint64
+	return BalancerFunc(func(service string, instances []Instance) (Instance, error) {
+		counter, _ := counters.LoadOrStore(service, new(atomic.Uint64))
+		n := counter.(*atomic.Uint64).Add(1) - 1
+		return instances[n%uint64(len(instances))], nil
+	})
+}
+
+// Weighted: 按实例权重随机选择, 权重均不大于 0 时等概率选择
+func Weighted() Balancer {
+	return BalancerFunc(func(service string, instances []Instance) (Instance, error) {
+		var total float64
+		for _, instance := range instances {
+			if instance.Weight > 0 {
+				total += instance.Weight
+			}
+		}
+		if total <= 0 {
+			return instances[rand.IntN(len(instances))], nil
+		}
+
+		n := rand.Float64() * total
+		for _, instance := range instances {
+			if instance.Weight <= 0 {
+				continue
+			}
+			if n -= instance.Weight; n < 0 {
+				return instance, nil
+			}
+		}
+		return instances[len(instances)-1], nil
+	})
+}
+
+// SameIDC: 优先选择与当前服务同机房(env.Idc())的实例, 同机房没有实例时从全部实例中选择
+func SameIDC(next Balancer) Balancer {
+	return BalancerFunc(func(service string, instances []Instance) (Instance, error) {
+		idc := env.Idc()
+		local := make([]Instance, 0, len(instances))
+		for _, instance := range instances {
+			if instance.Idc() == idc {
+				local = append(local, instance)
+			}
+		}
+		if len(local) == 0 {
+			local = instances
+		}
+		return next.Pick(service, local)
+	})
+}
@@ -0,0 +1,233 @@
+package discovery
+
+import (
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/everfir/go-helpers/internal/helper/nacos"
+)
+
+const (
+	// MetadataIdc: 实例元数据中的机房, 与 env.Idc() 一致, 用于同机房优先
+	MetadataIdc = "idc"
+	// GroupKey: 服务所在的 Nacos 分组, 默认 DEFAULT_GROUP
+	GroupKey     = "EVERFIR_DISCOVERY_GROUP"
+	DefaultGroup = "DEFAULT_GROUP"
+)
+
+// ErrNoInstance: 服务没有可用实例
+var ErrNoInstance = errors.New("[go-helper] no healthy instance")
+
+// Instance: 服务实例
+type Instance struct {
+	Id       string
+	Service  string
+	Ip       string
+	Port     uint64
+	Weight   float64
+	Cluster  string
+	Metadata map[string]string
+}
+
+// Addr: 实例地址, 例如 10.0.0.1:8080
+func (i Instance) Addr() string {
+	return net.JoinHostPort(i.Ip, strconv.FormatUint(i.Port, 10))
+}
+
+// Idc: 实例所在机房, 未注册机房时为空
+func (i Instance) Idc() string {
+	return i.Metadata[MetadataIdc]
+}
+
+// Registry: 服务注册中心
+type Registry interface {
+	// Instances 返回服务当前健康、启用的实例, 没有实例时返回空列表
+	Instances(service string) ([]Instance, error)
+	// Subscribe 订阅服务实例的变化, onChange 收到变化后的健康实例列表, 调用 cancel 取消订阅
+	Subscribe(service string, onChange func(instances []Instance)) (cancel func(), err error)
+}
+
+// Resolver: 将服务名解析为实例
+//
+// 首次解析某个服务时从注册中心拉取实例并订阅变化, 之后使用本地缓存的实例列表, 由 Balancer 选择实例
+type Resolver struct {
+	registry Registry
+	balancer Balancer
+
+	lock     sync.RWMutex
+	services map[string]*service
+}
+
+type service struct {
+	lock      sync.RWMutex
+	instances []Instance
+	updated   bool
+	cancel    func()
+	ready     chan struct{} // 首次拉取完成后关闭, 之后 err 不再变化
+	err       error
+}
+
+func (s *service) get() []Instance {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.instances
+}
+
+func (s *service) set(instances []Instance) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.instances, s.updated = instances, true
+}
+
+// NewResolver: 创建解析器, balancer 为空时使用 SameIDC(Weighted())
+func NewResolver(registry Registry, balancer Balancer) *Resolver {
+	if balancer == nil {
+		balancer = SameIDC(Weighted())
+	}
+	return &Resolver{registry: registry, balancer: balancer, services: map[string]*service{}}
+}
+
+// Instances: 服务当前的健康实例
+//
+// 首次解析时在服务自己的状态上等待拉取完成, 不阻塞其他服务的解析; 拉取失败时下次调用重新拉取
+func (r *Resolver) Instances(name string) ([]Instance, error) {
+	r.lock.RLock()
+	s, exist := r.services[name]
+	r.lock.RUnlock()
+	if !exist {
+		r.lock.Lock()
+		if s, exist = r.services[name]; !exist {
+			s = &service{ready: make(chan struct{})}
+			r.services[name] = s
+		}
+		r.lock.Unlock()
+		if !exist {
+			r.subscribe(name, s)
+		}
+	}
+
+	<-s.ready
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.get(), nil
+}
+
+// subscribe: 订阅并拉取服务实例, 失败时移除服务以便重新拉取
+func (r *Resolver) subscribe(name string, s *service) {
+	defer close(s.ready)
+
+	// 先订阅再拉取, 避免两者之间的变化丢失; 订阅已推送时以推送为准
+	cancel, err := r.registry.Subscribe(name, s.set)
+	if err == nil {
+		var instances []Instance
+		if instances, err = r.registry.Instances(name); err != nil {
+			cancel()
+		} else {
+			s.lock.Lock()
+			if !s.updated {
+				s.instances = instances
+			}
+			s.cancel = cancel
+			s.lock.Unlock()
+		}
+	}
+	if err != nil {
+		s.err = err
+		r.lock.Lock()
+		if r.services[name] == s {
+			delete(r.services, name)
+		}
+		r.lock.Unlock()
+	}
+}
+
+// Resolve: 选择服务的一个实例, 没有可用实例时返回 ErrNoInstance
+func (r *Resolver) Resolve(name string) (Instance, error) {
+	instances, err := r.Instances(name)
+	if err != nil {
+		return Instance{}, err
+	}
+	if len(instances) == 0 {
+		return Instance{}, ErrNoInstance
+	}
+	return r.balancer.Pick(name, instances)
+}
+
+// Close: 取消所有订阅
+func (r *Resolver) Close() {
+	r.lock.Lock()
+	services := r.services
+	r.services = map[string]*service{}
+	r.lock.Unlock()
+
+	for _, s := range services {
+		<-s.ready
+		if s.err == nil {
+			s.cancel()
+		}
+	}
+}
+
+var defaults = struct {
+	lock     sync.Mutex
+	registry *NacosRegistry
+	resolver *Resolver
+}{}
+
+// DefaultRegistry: 基于 Nacos 服务发现的默认注册中心, 创建失败时 panic, 需要处理错误时使用 LoadDefaultRegistry
+var DefaultRegistry func() *NacosRegistry = func() *NacosRegistry {
+	registry, err := LoadDefaultRegistry()
+	if err != nil {
+		panic(err.Error())
+	}
+	return registry
+}
+
+// LoadDefaultRegistry: 创建基于 Nacos 服务发现的默认注册中心, 连接信息与配置客户端一致(ClientOptionsFromEnv),
+// 服务分组为 EVERFIR_DISCOVERY_GROUP, 默认 DEFAULT_GROUP; 创建成功后复用, 失败时下次调用重新创建
+func LoadDefaultRegistry() (*NacosRegistry, error) {
+	defaults.lock.Lock()
+	defer defaults.lock.Unlock()
+	return loadDefaultRegistry()
+}
+
+func loadDefaultRegistry() (*NacosRegistry, error) {
+	if defaults.registry != nil {
+		return defaults.registry, nil
+	}
+	client, err := nacos.LoadNamingClient()
+	if err != nil {
+		return nil, err
+	}
+	defaults.registry = NewNacosRegistry(client, os.Getenv(GroupKey))
+	return defaults.registry, nil
+}
+
+// Default: 使用 DefaultRegistry 的默认解析器, 创建失败时 panic, 需要处理错误时使用 LoadDefault
+var Default func() *Resolver = func() *Resolver {
+	resolver, err := LoadDefault()
+	if err != nil {
+		panic(err.Error())
+	}
+	return resolver
+}
+
+// LoadDefault: 创建使用默认注册中心的解析器, 创建成功后复用, 失败时下次调用重新创建
+func LoadDefault() (*Resolver, error) {
+	defaults.lock.Lock()
+	defer defaults.lock.Unlock()
+
+	if defaults.resolver != nil {
+		return defaults.resolver, nil
+	}
+	registry, err := loadDefaultRegistry()
+	if err != nil {
+		return nil, err
+	}
+	defaults.resolver = NewResolver(registry, nil)
+	return defaults.resolver, nil
+}
@@ -0,0 +1,168 @@
+package discovery
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/everfir/go-helpers/env"
+	"github.com/zeebo/assert"
+)
+
+func TestResolver(t *testing.T) {
+	registry := NewMemoryRegistry()
+	registry.Set("user-account", Instance{Ip: "10.0.0.1", Port: 8080}, Instance{Ip: "10.0.0.2", Port: 8080})
+
+	resolver := NewResolver(registry, RoundRobin())
+	defer resolver.Close()
+
+	var addrs []string
+	for i := 0; i < 4; i++ {
+		instance, err := resolver.Resolve("user-account")
+		assert.NoError(t, err)
+		addrs = append(addrs, instance.Addr())
+	}
+	assert.DeepEqual(t, addrs, []string{"10.0.0.1:8080", "10.0.0.2:8080", "10.0.0.1:8080", "10.0.0.2:8080"})
+
+	// 订阅实例变化
+	registry.Set("user-account", Instance{Ip: "::1", Port: 9090})
+	instance, err := resolver.Resolve("user-account")
+	assert.NoError(t, err)
+	assert.Equal(t, instance.Addr(), "[::1]:9090")
+
+	registry.Set("user-account")
+	_, err = resolver.Resolve("user-account")
+	assert.True(t, errors.Is(err, ErrNoInstance))
+	_, err = resolver.Resolve("unknown")
+	assert.True(t, errors.Is(err, ErrNoInstance))
+
+	// 关闭后取消订阅
+	resolver.Close()
+	assert.Equal(t, len(registry.subscribers["user-account"]), 0)
+}
+
+// blockingRegistry: 指定服务的拉取阻塞到 release 关闭, 失败次数用完前返回错误
+type blockingRegistry struct {
+	*MemoryRegistry
+	slow    string
+	release chan struct{}
+	fails   atomic.Int32
+}
+
+func (r *blockingRegistry) Instances(service string) ([]Instance, error) {
+	if service == r.slow {
+		<-r.release
+	}
+	if r.fails.Add(-1) >= 0 {
+		return nil, errors.New("registry unavailable")
+	}
+	return r.MemoryRegistry.Instances(service)
+}
+
+func TestResolverConcurrent(t *testing.T) {
+	registry := &blockingRegistry{MemoryRegistry: NewMemoryRegistry(), slow: "slow", release: make(chan struct{})}
+	registry.Set("slow", Instance{Ip: "10.0.0.1", Port: 80})
+	registry.Set("fast", Instance{Ip: "10.0.0.2", Port: 80})
+	resolver := NewResolver(registry, nil)
+	defer resolver.Close()
+
+	// 首次拉取 slow 时不阻塞其他服务
+	done := make(chan error)
+	go func() {
+		_, err := resolver.Resolve("slow")
+		done <- err
+	}()
+	instance, err := resolver.Resolve("fast")
+	assert.NoError(t, err)
+	assert.Equal(t, instance.Ip, "10.0.0.2")
+	close(registry.release)
+	assert.NoError(t, <-done)
+
+	// 拉取失败时下次重新拉取
+	registry.fails.Store(1)
+	registry.Set("retry", Instance{Ip: "10.0.0.3", Port: 80})
+	_, err = resolver.Resolve("retry")
+	assert.Error(t, err)
+	instance, err = resolver.Resolve("retry")
+	assert.NoError(t, err)
+	assert.Equal(t, instance.Ip, "10.0.0.3")
+}
+
+func TestBalancer(t *testing.T) {
+	instances := []Instance{
+		{Id: "a", Weight: 1},
+		{Id: "b", Weight: 3},
+		{Id: "c", Weight: 0},
+	}
+	counts := map[string]int{}
+	balancer := Weighted()
+	for i := 0; i < 4000; i++ {
+		instance, err := balancer.Pick("svc", instances)
+		assert.NoError(t, err)
+		counts[instance.Id]++
+	}
+	assert.Equal(t, counts["c"], 0)
+	assert.True(t, counts["b"] > 2*counts["a"])
+
+	// 权重均为 0 时等概率选择
+	instance, err := balancer.Pick("svc", []Instance{{Id: "x"}})
+	assert.NoError(t, err)
+	assert.Equal(t, instance.Id, "x")
+
+	// 同机房优先, 同机房没有实例时使用全部实例
+	sameIdc := SameIDC(RoundRobin())
+	instances = []Instance{
+		{Id: "remote", Metadata: map[string]string{MetadataIdc: "other"}},
+		{Id: "local", Metadata: map[string]string{MetadataIdc: env.Idc()}},
+	}
+	for i := 0; i < 3; i++ {
+		instance, err = sameIdc.Pick("svc", instances)
+		assert.NoError(t, err)
+		assert.Equal(t, instance.Id, "local")
+	}
+	instance, err = sameIdc.Pick("svc", instances[:1])
+	assert.NoError(t, err)
+	assert.Equal(t, instance.Id, "remote")
+}
+
+func TestTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host + r.URL.Path))
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	port, _ := strconv.ParseUint(u.Port(), 10, 16)
+
+	registry := NewMemoryRegistry()
+	registry.Set("user-account", Instance{Ip: u.Hostname(), Port: port})
+	resolver := NewResolver(registry, nil)
+	defer resolver.Close()
+
+	client := &http.Client{Transport: &Transport{Resolver: resolver}}
+	resp, err := client.Get("http://user-account/account/check_token")
+	assert.NoError(t, err)
+	body := make([]byte, 64)
+	n, _ := resp.Body.Read(body)
+	resp.Body.Close()
+	assert.Equal(t, string(body[:n]), "user-account/account/check_token")
+
+	// 不是服务名的地址直接请求
+	resp, err = client.Get(server.URL + "/direct")
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	// 没有实例时默认报错, Fallback 时按原地址请求
+	_, err = client.Get("http://unknown-service/")
+	var resolveErr *ResolveError
+	assert.True(t, errors.As(err, &resolveErr))
+	assert.Equal(t, resolveErr.Service, "unknown-service")
+
+	assert.True(t, IsServiceName("user-account"))
+	assert.False(t, IsServiceName("user-account:8080"))
+	assert.False(t, IsServiceName("api.example.com"))
+	assert.False(t, IsServiceName("localhost"))
+}
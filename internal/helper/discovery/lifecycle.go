@@ -0,0 +1,187 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/everfir/logger-go"
+	"github.com/everfir/logger-go/structs/field"
+)
+
+const (
+	DefaultDrain           = 10 * time.Second
+	DefaultShutdownTimeout = 30 * time.Second
+)
+
+// LifecycleOptions: 实例注册与优雅退出的配置
+type LifecycleOptions struct {
+	Registrar Registrar // 默认 LoadDefaultRegistry()
+	// Drain 摘流后等待的时间, 让调用方的实例缓存与进行中的请求自然结束, 默认 10s
+	Drain time.Duration
+	// ZeroWeight 为 true 时摘流只禁用实例(权重置 0), HTTP 服务停止后再注销; 默认直接注销
+	ZeroWeight bool
+	// ShutdownTimeout 停止 HTTP 服务时等待进行中请求的时间, 默认 30s
+	ShutdownTimeout time.Duration
+	// Signals 触发退出的信号, 默认 SIGTERM、SIGINT
+	Signals []os.Signal
+}
+
+// Lifecycle: 将当前服务注册到注册中心, 退出时先摘流再停止 HTTP 服务
+type Lifecycle struct {
+	reg  Registration
+	opts LifecycleOptions
+}
+
+func NewLifecycle(reg Registration, opts LifecycleOptions) *Lifecycle {
+	if opts.Drain <= 0 {
+		opts.Drain = DefaultDrain
+	}
+	if opts.ShutdownTimeout <= 0 {
+		opts.ShutdownTimeout = DefaultShutdownTimeout
+	}
+	if len(opts.Signals) == 0 {
+		opts.Signals = []os.Signal{syscall.SIGTERM, syscall.SIGINT}
+	}
+	return &Lifecycle{reg: reg, opts: opts}
+}
+
+func (l *Lifecycle) registrar() (Registrar, error) {
+	if l.opts.Registrar == nil {
+		return LoadDefaultRegistry()
+	}
+	return l.opts.Registrar, nil
+}
+
+// Registration: 补全默认值后的注册信息, Register 之后有效
+func (l *Lifecycle) Registration() Registration {
+	return l.reg
+}
+
+// Register: 补全默认值并注册实例
+func (l *Lifecycle) Register() (err error) {
+	if l.reg, err = l.reg.complete(); err != nil {
+		return err
+	}
+	registrar, err := l.registrar()
+	if err != nil {
+		return err
+	}
+	if err = registrar.Register(l.reg); err != nil {
+		return err
+	}
+	logger.Info(
+		context.TODO(),
+		"[go-helper] service registered",
+		field.String("service", l.reg.Service),
+		field.String("addr", l.reg.Addr()),
+		field.Any("metadata", l.reg.Metadata),
+	)
+	return nil
+}
+
+// Drain: 摘流(注销或权重置 0)并等待 Drain, ctx 结束时提前返回
+func (l *Lifecycle) Drain(ctx context.Context) error {
+	registrar, err := l.registrar()
+	switch {
+	case err != nil:
+	case l.opts.ZeroWeight:
+		err = registrar.UpdateWeight(l.reg, 0)
+	default:
+		err = registrar.Deregister(l.reg)
+	}
+	logger.Info(
+		ctx,
+		"[go-helper] service draining",
+		field.String("service", l.reg.Service),
+		field.String("addr", l.reg.Addr()),
+		field.String("drain", l.opts.Drain.String()),
+		field.Bool("zeroWeight", l.opts.ZeroWeight),
+	)
+
+	timer := time.NewTimer(l.opts.Drain)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+	return err
+}
+
+// Deregister: 注销实例
+func (l *Lifecycle) Deregister() error {
+	registrar, err := l.registrar()
+	if err != nil {
+		return err
+	}
+	return registrar.Deregister(l.reg)
+}
+
+// Serve: 启动 HTTP 服务并在监听成功后注册实例, 收到退出信号或 ctx 结束时:
+//  1. 摘流: 注销实例或权重置 0, 等待 Drain
+//  2. 停止 HTTP 服务, 等待进行中的请求, 最长 ShutdownTimeout
+//  3. ZeroWeight 模式下注销实例
+//
+// Registration.Port 为 0 时使用实际监听的端口。HTTP 服务正常退出时返回 nil。
+func (l *Lifecycle) Serve(ctx context.Context, server *http.Server) error {
+	addr := server.Addr
+	if addr == "" {
+		addr = ":http"
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	if l.reg.Port == 0 {
+		_, port, _ := net.SplitHostPort(ln.Addr().String())
+		l.reg.Port, _ = strconv.ParseUint(port, 10, 16)
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(ln)
+	}()
+
+	if err = l.Register(); err != nil {
+		server.Close()
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, l.opts.Signals...)
+	defer stop()
+	select {
+	case err = <-serveErr:
+		// HTTP 服务异常退出, 注销实例
+		l.Deregister()
+		return err
+	case <-ctx.Done():
+	}
+
+	// 退出过程不受已结束的 ctx 影响, 再次收到信号时跳过等待
+	drainCtx, cancel := signal.NotifyContext(context.Background(), l.opts.Signals...)
+	defer cancel()
+	if err = l.Drain(drainCtx); err != nil {
+		logger.Warn(drainCtx, "[go-helper] service drain failed", field.String("err", err.Error()))
+	}
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), l.opts.ShutdownTimeout)
+	defer cancelShutdown()
+	err = server.Shutdown(shutdownCtx)
+
+	if l.opts.ZeroWeight {
+		if e := l.Deregister(); e != nil {
+			logger.Warn(shutdownCtx, "[go-helper] service deregister failed", field.String("err", e.Error()))
+		}
+	}
+	if e := <-serveErr; !errors.Is(e, http.ErrServerClosed) && err == nil {
+		err = e
+	}
+	logger.Info(shutdownCtx, "[go-helper] service stopped", field.String("service", l.reg.Service))
+	return err
+}
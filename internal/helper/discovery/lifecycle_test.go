@@ -0,0 +1,86 @@
+package discovery
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/zeebo/assert"
+)
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before deadline")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	for _, zeroWeight := range []bool{false, true} {
+		registry := NewMemoryRegistry()
+		lifecycle := NewLifecycle(
+			Registration{Service: "user-account", Ip: "127.0.0.1", Metadata: map[string]string{MetadataBuild: "42"}},
+			LifecycleOptions{Registrar: registry, Drain: 200 * time.Millisecond, ZeroWeight: zeroWeight},
+		)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		server := &http.Server{Addr: "127.0.0.1:0", Handler: http.NotFoundHandler()}
+		done := make(chan error, 1)
+		go func() { done <- lifecycle.Serve(ctx, server) }()
+
+		// 监听成功后注册, 使用实际端口并补充元数据
+		waitFor(t, func() bool {
+			instances, _ := registry.Instances("user-account")
+			return len(instances) == 1
+		})
+		instances, _ := registry.Instances("user-account")
+		reg := lifecycle.Registration()
+		assert.Equal(t, instances[0].Addr(), reg.Addr())
+		assert.True(t, reg.Port > 0)
+		assert.Equal(t, instances[0].Weight, 1.0)
+		assert.Equal(t, instances[0].Metadata[MetadataBuild], "42")
+		assert.True(t, instances[0].Metadata[MetadataEnv] != "")
+		assert.True(t, instances[0].Metadata[MetadataIdc] != "")
+
+		// 退出时先摘流, Drain 期间 HTTP 服务仍可访问
+		start := time.Now()
+		cancel()
+		waitFor(t, func() bool {
+			instances, _ := registry.Instances("user-account")
+			return len(instances) == 0
+		})
+		resp, err := http.Get("http://" + reg.Addr())
+		assert.NoError(t, err)
+		resp.Body.Close()
+
+		assert.NoError(t, <-done)
+		assert.True(t, time.Since(start) >= 200*time.Millisecond)
+		_, err = http.Get("http://" + reg.Addr())
+		assert.Error(t, err)
+	}
+}
+
+func TestRegistration(t *testing.T) {
+	t.Setenv(ServiceNameKey, "")
+	_, err := Registration{Port: 80}.complete()
+	assert.Error(t, err)
+
+	t.Setenv(ServiceNameKey, "user-account")
+	t.Setenv(PodIpKey, "10.0.0.1")
+	t.Setenv(ServiceVersionKey, "v1.2.3")
+	_, err = Registration{}.complete()
+	assert.Error(t, err)
+
+	reg, err := Registration{Port: 80, Metadata: map[string]string{MetadataIdc: "shanghai"}}.complete()
+	assert.NoError(t, err)
+	assert.Equal(t, reg.Service, "user-account")
+	assert.Equal(t, reg.Addr(), "10.0.0.1:80")
+	assert.Equal(t, reg.Weight, 1.0)
+	assert.Equal(t, reg.Metadata[MetadataVersion], "v1.2.3")
+	assert.Equal(t, reg.Metadata[MetadataIdc], "shanghai")
+}
@@ -0,0 +1,218 @@
+package discovery
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"runtime/debug"
+
+	"github.com/everfir/go-helpers/env"
+	"github.com/nacos-group/nacos-sdk-go/vo"
+)
+
+// 实例注册相关的环境变量与元数据
+const (
+	ServiceNameKey    = "EVERFIR_SERVICE_NAME"    // 注册的服务名
+	ServiceVersionKey = "EVERFIR_SERVICE_VERSION" // 服务版本, 默认取构建信息中的模块版本
+	ServiceBuildKey   = "EVERFIR_SERVICE_BUILD"   // 构建号, 默认取构建信息中的 vcs.revision
+	PodIpKey          = "POD_IP"                  // 注册的 IP, 未设置时使用第一个非回环 IPv4 地址
+
+	MetadataEnv     = "env"
+	MetadataVersion = "version"
+	MetadataBuild   = "build"
+)
+
+// Registration: 注册的实例信息
+type Registration struct {
+	Service  string            // 默认 EVERFIR_SERVICE_NAME
+	Ip       string            // 默认 POD_IP 或第一个非回环 IPv4 地址
+	Port     uint64            // 必填
+	Weight   float64           // 默认 1
+	Cluster  string            // 默认 DEFAULT
+	Metadata map[string]string // 自动补充 env、idc、version、build
+}
+
+// complete: 补全默认值, 不修改调用方的 Metadata
+func (reg Registration) complete() (Registration, error) {
+	if reg.Service == "" {
+		reg.Service = os.Getenv(ServiceNameKey)
+	}
+	if reg.Service == "" {
+		return reg, fmt.Errorf("[go-helper] service name is required, set %s", ServiceNameKey)
+	}
+	if reg.Port == 0 {
+		return reg, fmt.Errorf("[go-helper] service port is required")
+	}
+	if reg.Ip == "" {
+		reg.Ip = os.Getenv(PodIpKey)
+	}
+	if reg.Ip == "" {
+		ip, err := localIp()
+		if err != nil {
+			return reg, err
+		}
+		reg.Ip = ip
+	}
+	if reg.Weight <= 0 {
+		reg.Weight = 1
+	}
+
+	metadata := map[string]string{
+		MetadataEnv:     env.Env(),
+		MetadataIdc:     env.Idc(),
+		MetadataVersion: os.Getenv(ServiceVersionKey),
+		MetadataBuild:   os.Getenv(ServiceBuildKey),
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if metadata[MetadataVersion] == "" {
+			metadata[MetadataVersion] = info.Main.Version
+		}
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" && metadata[MetadataBuild] == "" {
+				metadata[MetadataBuild] = setting.Value
+			}
+		}
+	}
+	for key, val := range reg.Metadata {
+		metadata[key] = val
+	}
+	for key, val := range metadata {
+		if val == "" {
+			delete(metadata, key)
+		}
+	}
+	reg.Metadata = metadata
+	return reg, nil
+}
+
+// localIp: 第一个非回环 IPv4 地址
+func localIp() (string, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return "", fmt.Errorf("[go-helper] get local ip failed: %w", err)
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && ipNet.IP.To4() != nil {
+			return ipNet.IP.String(), nil
+		}
+	}
+	return "", fmt.Errorf("[go-helper] no local ipv4 address, set %s", PodIpKey)
+}
+
+// Registrar: 注册中心的实例注册
+type Registrar interface {
+	// Register 注册实例, 注册中心负责维持心跳
+	Register(reg Registration) error
+	// Deregister 注销实例
+	Deregister(reg Registration) error
+	// UpdateWeight 更新实例权重, 权重为 0 时实例不再接收新流量
+	UpdateWeight(reg Registration, weight float64) error
+}
+
+// Register: 注册临时实例, SDK 按 ClientConfig.BeatInterval(默认 5s) 发送心跳, 进程退出后实例自动过期
+func (r *NacosRegistry) Register(reg Registration) error {
+	ok, err := r.client.RegisterInstance(vo.RegisterInstanceParam{
+		Ip:          reg.Ip,
+		Port:        reg.Port,
+		Weight:      reg.Weight,
+		Enable:      true,
+		Healthy:     true,
+		Metadata:    reg.Metadata,
+		ClusterName: reg.Cluster,
+		ServiceName: reg.Service,
+		GroupName:   r.group,
+		Ephemeral:   true,
+	})
+	return result("register", reg, ok, err)
+}
+
+func (r *NacosRegistry) Deregister(reg Registration) error {
+	ok, err := r.client.DeregisterInstance(vo.DeregisterInstanceParam{
+		Ip:          reg.Ip,
+		Port:        reg.Port,
+		Cluster:     reg.Cluster,
+		ServiceName: reg.Service,
+		GroupName:   r.group,
+		Ephemeral:   true,
+	})
+	return result("deregister", reg, ok, err)
+}
+
+// UpdateWeight: Nacos 要求权重大于 0, 权重为 0 时改为禁用实例
+func (r *NacosRegistry) UpdateWeight(reg Registration, weight float64) error {
+	param := vo.UpdateInstanceParam{
+		Ip:          reg.Ip,
+		Port:        reg.Port,
+		ClusterName: reg.Cluster,
+		ServiceName: reg.Service,
+		GroupName:   r.group,
+		Ephemeral:   true,
+		Weight:      weight,
+		Enable:      true,
+		Metadata:    reg.Metadata,
+	}
+	if weight <= 0 {
+		param.Weight, param.Enable = reg.Weight, false
+	}
+	ok, err := r.client.UpdateInstance(param)
+	return result("update", reg, ok, err)
+}
+
+func result(op string, reg Registration, ok bool, err error) error {
+	if err != nil {
+		return fmt.Errorf("[go-helper] %s instance %s %s:%d failed: %w", op, reg.Service, reg.Ip, reg.Port, err)
+	}
+	if !ok {
+		return fmt.Errorf("[go-helper] %s instance %s %s:%d failed", op, reg.Service, reg.Ip, reg.Port)
+	}
+	return nil
+}
+
+func (r *MemoryRegistry) Register(reg Registration) error {
+	r.update(reg, func(instances []Instance) []Instance {
+		return append(instances, Instance{
+			Id:       reg.Addr(),
+			Service:  reg.Service,
+			Ip:       reg.Ip,
+			Port:     reg.Port,
+			Weight:   reg.Weight,
+			Cluster:  reg.Cluster,
+			Metadata: reg.Metadata,
+		})
+	})
+	return nil
+}
+
+func (r *MemoryRegistry) Deregister(reg Registration) error {
+	r.update(reg, func(instances []Instance) []Instance { return instances })
+	return nil
+}
+
+// UpdateWeight: 与 NacosRegistry 一致, 权重为 0 的实例不再返回
+func (r *MemoryRegistry) UpdateWeight(reg Registration, weight float64) error {
+	if weight <= 0 {
+		return r.Deregister(reg)
+	}
+	reg.Weight = weight
+	return r.Register(reg)
+}
+
+// update: 移除 reg 对应的实例后由 fn 生成新的实例列表
+func (r *MemoryRegistry) update(reg Registration, fn func([]Instance) []Instance) {
+	r.lock.RLock()
+	current := r.instances[reg.Service]
+	r.lock.RUnlock()
+
+	instances := make([]Instance, 0, len(current)+1)
+	for _, instance := range current {
+		if instance.Addr() != reg.Addr() {
+			instances = append(instances, instance)
+		}
+	}
+	r.Set(reg.Service, fn(instances)...)
+}
+
+// Addr: 实例地址
+func (reg Registration) Addr() string {
+	return Instance{Ip: reg.Ip, Port: reg.Port}.Addr()
+}
@@ -0,0 +1,149 @@
+package discovery
+
+import (
+	"context"
+	"sync"
+
+	"github.com/everfir/logger-go"
+	"github.com/everfir/logger-go/structs/field"
+	"github.com/nacos-group/nacos-sdk-go/clients/naming_client"
+	"github.com/nacos-group/nacos-sdk-go/model"
+	"github.com/nacos-group/nacos-sdk-go/vo"
+)
+
+// NacosRegistry: 基于 Nacos 服务发现的注册中心
+type NacosRegistry struct {
+	client naming_client.INamingClient
+	group  string
+}
+
+// NewNacosRegistry: group 为空时使用 DEFAULT_GROUP
+func NewNacosRegistry(client naming_client.INamingClient, group string) *NacosRegistry {
+	if group == "" {
+		group = DefaultGroup
+	}
+	return &NacosRegistry{client: client, group: group}
+}
+
+func (r *NacosRegistry) Instances(service string) ([]Instance, error) {
+	// SelectInstances 在没有实例时返回错误, 这里使用 SelectAllInstances 并自行过滤
+	instances, err := r.client.SelectAllInstances(vo.SelectAllInstancesParam{ServiceName: service, GroupName: r.group})
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]Instance, 0, len(instances))
+	for _, i := range instances {
+		if available(i.Enable, i.Healthy, i.Weight) {
+			ret = append(ret, Instance{
+				Id:       i.InstanceId,
+				Service:  service,
+				Ip:       i.Ip,
+				Port:     i.Port,
+				Weight:   i.Weight,
+				Cluster:  i.ClusterName,
+				Metadata: i.Metadata,
+			})
+		}
+	}
+	return ret, nil
+}
+
+func (r *NacosRegistry) Subscribe(service string, onChange func(instances []Instance)) (func(), error) {
+	param := &vo.SubscribeParam{
+		ServiceName: service,
+		GroupName:   r.group,
+		SubscribeCallback: func(services []model.SubscribeService, err error) {
+			if err != nil {
+				logger.Warn(
+					context.TODO(),
+					"[go-helper] subscribe service failed",
+					field.String("service", service),
+					field.String("err", err.Error()),
+				)
+				return
+			}
+
+			instances := make([]Instance, 0, len(services))
+			for _, s := range services {
+				if available(s.Enable, s.Healthy, s.Weight) {
+					instances = append(instances, Instance{
+						Id:       s.InstanceId,
+						Service:  service,
+						Ip:       s.Ip,
+						Port:     s.Port,
+						Weight:   s.Weight,
+						Cluster:  s.ClusterName,
+						Metadata: s.Metadata,
+					})
+				}
+			}
+			onChange(instances)
+			logger.Info(
+				context.TODO(),
+				"[go-helper] service instances changed",
+				field.String("service", service),
+				field.Any("instances", instances),
+			)
+		},
+	}
+	if err := r.client.Subscribe(param); err != nil {
+		return nil, err
+	}
+	return func() { r.client.Unsubscribe(param) }, nil
+}
+
+// available: 启用、健康且权重大于 0 的实例可以接收流量, 权重为 0 表示正在摘流
+func available(enable, healthy bool, weight float64) bool {
+	return enable && healthy && weight > 0
+}
+
+// MemoryRegistry: 内存注册中心, 用于测试与本地开发
+type MemoryRegistry struct {
+	lock        sync.RWMutex
+	instances   map[string][]Instance
+	subscribers map[string]map[int]func([]Instance)
+	next        int
+}
+
+func NewMemoryRegistry() *MemoryRegistry {
+	return &MemoryRegistry{instances: map[string][]Instance{}, subscribers: map[string]map[int]func([]Instance){}}
+}
+
+// Set: 设置服务的实例并通知订阅者
+func (r *MemoryRegistry) Set(service string, instances ...Instance) {
+	r.lock.Lock()
+	r.instances[service] = instances
+	subscribers := make([]func([]Instance), 0, len(r.subscribers[service]))
+	for _, fn := range r.subscribers[service] {
+		subscribers = append(subscribers, fn)
+	}
+	r.lock.Unlock()
+
+	for _, fn := range subscribers {
+		fn(instances)
+	}
+}
+
+func (r *MemoryRegistry) Instances(service string) ([]Instance, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.instances[service], nil
+}
+
+func (r *MemoryRegistry) Subscribe(service string, onChange func(instances []Instance)) (func(), error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	id := r.next
+	r.next++
+	if _, exist := r.subscribers[service]; !exist {
+		r.subscribers[service] = map[int]func([]Instance){}
+	}
+	r.subscribers[service][id] = onChange
+	return func() {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		delete(r.subscribers[service], id)
+	}, nil
+}
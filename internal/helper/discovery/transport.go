@@ -0,0 +1,97 @@
+package discovery
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/everfir/logger-go"
+	"github.com/everfir/logger-go/structs/field"
+)
+
+// Transport: 通过服务发现解析请求地址的 http.RoundTripper
+//
+// 请求 http://user-account/account/check_token 时, 将 user-account 解析为实例地址后转发,
+// Host 请求头保持为服务名。
+type Transport struct {
+	Resolver *Resolver
+	Next     http.RoundTripper // 默认 http.DefaultTransport
+	// Match 判断请求的 Host 是否为服务名, 默认匹配不含端口与 "." 且不是 localhost 的 Host
+	Match func(host string) bool
+	// Fallback 为 true 时, 服务没有实例或注册中心不可用时按原地址请求(例如交由 Kubernetes DNS 解析)
+	Fallback bool
+}
+
+// NewTransport: 创建 Transport, resolver 为空时使用 LoadDefault(), next 为空时使用 http.DefaultTransport,
+// 默认开启 Fallback 以便逐步迁移
+func NewTransport(resolver *Resolver, next http.RoundTripper) *Transport {
+	return &Transport{Resolver: resolver, Next: next, Fallback: true}
+}
+
+// IsServiceName: 默认的服务名判断, 不含端口与 "." 且不是 localhost
+func IsServiceName(host string) bool {
+	return host != "" && host != "localhost" && !strings.ContainsAny(host, ".:[")
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	match := t.Match
+	if match == nil {
+		match = IsServiceName
+	}
+
+	host := req.URL.Host
+	if !match(host) {
+		return next.RoundTrip(req)
+	}
+
+	instance, err := t.resolve(host)
+	if err != nil {
+		if t.Fallback {
+			logger.Warn(
+				req.Context(),
+				"[go-helper] resolve service failed, fallback to origin host",
+				field.String("service", host),
+				field.String("err", err.Error()),
+			)
+			return next.RoundTrip(req)
+		}
+		return nil, &ResolveError{Service: host, Err: err}
+	}
+
+	// RoundTripper 不能修改原请求
+	r := req.Clone(req.Context())
+	r.URL.Host = instance.Addr()
+	if r.Host == "" {
+		r.Host = host
+	}
+	return next.RoundTrip(r)
+}
+
+// resolve: Resolver 为空时使用 LoadDefault(), 注册中心创建失败按解析失败处理
+func (t *Transport) resolve(host string) (Instance, error) {
+	resolver := t.Resolver
+	if resolver == nil {
+		var err error
+		if resolver, err = LoadDefault(); err != nil {
+			return Instance{}, err
+		}
+	}
+	return resolver.Resolve(host)
+}
+
+// ResolveError: 服务解析失败
+type ResolveError struct {
+	Service string
+	Err     error
+}
+
+func (e *ResolveError) Error() string {
+	return "[go-helper] resolve service " + e.Service + " failed: " + e.Err.Error()
+}
+
+func (e *ResolveError) Unwrap() error {
+	return e.Err
+}
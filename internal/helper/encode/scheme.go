@@ -0,0 +1,109 @@
+package encode
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+const (
+	// SchemeXxh3V1: xxh3(accountId) % 1000, 与最初的分桶方式保持一致, 不使用盐值
+	SchemeXxh3V1 = "xxh3-v1"
+	// SchemeXxh3SaltedV2: xxh3(salt:accountId) % 10000, 不同实验之间的分桶相互独立
+	SchemeXxh3SaltedV2 = "xxh3-salted-v2"
+
+	// DefaultScheme: 未指定分桶方案时使用的方案
+	DefaultScheme = SchemeXxh3V1
+)
+
+// BucketScheme: 流量分桶方案
+//
+// 方案一经发布不可修改, 调整哈希函数、桶数量或盐值规则时应注册新名称的方案,
+// 否则所有运行中实验的用户都会被重新分配。
+type BucketScheme interface {
+	// Name: 方案名称, 例如 "xxh3-v1"
+	Name() string
+	// Buckets: 桶的数量
+	Buckets() uint64
+	// Bucket: 计算 key 所在的桶, 返回值在 [0, Buckets()) 范围内
+	Bucket(salt, key string) uint64
+}
+
+var schemes = struct {
+	lock sync.RWMutex
+	data map[string]BucketScheme
+}{
+	data: map[string]BucketScheme{
+		SchemeXxh3V1:       xxh3Scheme{name: SchemeXxh3V1, buckets: 1000},
+		SchemeXxh3SaltedV2: xxh3Scheme{name: SchemeXxh3SaltedV2, buckets: 10000, salted: true},
+	},
+}
+
+// RegisterScheme: 注册分桶方案, 名称重复时返回错误
+func RegisterScheme(scheme BucketScheme) error {
+	schemes.lock.Lock()
+	defer schemes.lock.Unlock()
+
+	if _, exist := schemes.data[scheme.Name()]; exist {
+		return fmt.Errorf("[go-helper] bucket scheme[%s] already registered", scheme.Name())
+	}
+	if scheme.Buckets() == 0 {
+		return fmt.Errorf("[go-helper] bucket scheme[%s] should have at least one bucket", scheme.Name())
+	}
+
+	schemes.data[scheme.Name()] = scheme
+	return nil
+}
+
+// GetScheme: 获取分桶方案, name 为空时返回默认方案
+func GetScheme(name string) (BucketScheme, bool) {
+	if name == "" {
+		name = DefaultScheme
+	}
+
+	schemes.lock.RLock()
+	defer schemes.lock.RUnlock()
+
+	scheme, exist := schemes.data[name]
+	return scheme, exist
+}
+
+// Schemes: 获取所有已注册的方案名称
+func Schemes() []string {
+	schemes.lock.RLock()
+	defer schemes.lock.RUnlock()
+
+	ret := make([]string, 0, len(schemes.data))
+	for name := range schemes.data {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// InRate: 判断 key 所在的桶是否落在 rate 比例内
+func InRate(scheme BucketScheme, salt, key string, rate float64) bool {
+	threshold := uint64(rate * float64(scheme.Buckets()))
+	return scheme.Bucket(salt, key) < threshold
+}
+
+type xxh3Scheme struct {
+	name    string
+	buckets uint64
+	salted  bool
+}
+
+func (s xxh3Scheme) Name() string {
+	return s.name
+}
+
+func (s xxh3Scheme) Buckets() uint64 {
+	return s.buckets
+}
+
+func (s xxh3Scheme) Bucket(salt, key string) uint64 {
+	if s.salted {
+		key = salt + ":" + key
+	}
+	return HashString(key) % s.buckets
+}
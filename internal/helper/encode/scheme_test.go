@@ -0,0 +1,44 @@
+package encode
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/zeebo/assert"
+)
+
+// xxh3-v1 必须与最初的分桶方式(HashString % 1000)保持一致
+func TestSchemeXxh3V1Compatible(t *testing.T) {
+	scheme, exist := GetScheme("")
+	assert.True(t, exist)
+	assert.Equal(t, scheme.Name(), SchemeXxh3V1)
+
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("%d", i)
+		assert.Equal(t, scheme.Bucket("salt", key), HashString(key)%1000)
+	}
+}
+
+func TestSchemeSalted(t *testing.T) {
+	scheme, exist := GetScheme(SchemeXxh3SaltedV2)
+	assert.True(t, exist)
+
+	// 不同盐值下的分桶相互独立
+	var diff int
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("%d", i)
+		if scheme.Bucket("feature_a", key) != scheme.Bucket("feature_b", key) {
+			diff++
+		}
+	}
+	assert.True(t, diff > 900)
+}
+
+func TestRegisterScheme(t *testing.T) {
+	assert.Error(t, RegisterScheme(xxh3Scheme{name: SchemeXxh3V1, buckets: 1000}))
+	assert.Error(t, RegisterScheme(xxh3Scheme{name: "empty"}))
+	assert.NoError(t, RegisterScheme(xxh3Scheme{name: "xxh3-test", buckets: 100}))
+
+	_, exist := GetScheme("xxh3-test")
+	assert.True(t, exist)
+}
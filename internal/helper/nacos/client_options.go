@@ -0,0 +1,450 @@
+package nacos
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/everfir/go-helpers/env"
+	"github.com/nacos-group/nacos-sdk-go/clients/config_client"
+	"github.com/nacos-group/nacos-sdk-go/clients/nacos_client"
+	"github.com/nacos-group/nacos-sdk-go/common/constant"
+)
+
+// Nacos 连接相关的环境变量
+const (
+	NacosAddrsKey             = "EVERFIR_NACOS_ADDRS"        // 逗号分隔的服务地址, 例如 https://10.0.0.1:8848/nacos,10.0.0.2
+	NacosIpKey                = "EVERFIR_NACOS_IP"           // 服务 IP, 未设置 EVERFIR_NACOS_ADDRS 时生效, 可逗号分隔多个
+	NacosPortKey              = "EVERFIR_NACOS_PORT"         // 地址未指定端口时使用的端口, 默认 8848
+	NacosSchemeKey            = "EVERFIR_NACOS_SCHEME"       // 地址未指定协议时使用的协议, 默认 http
+	NacosContextPathKey       = "EVERFIR_NACOS_CONTEXT_PATH" // 地址未指定路径时使用的路径, 默认 /nacos
+	NacosNamespaceKey         = "EVERFIR_NACOS_NAMESPACE"
+	NacosTimeoutKey           = "EVERFIR_NACOS_TIMEOUT"             // 请求超时, 例如 10s, 默认 60s
+	NacosCacheDirKey          = "EVERFIR_NACOS_CACHE_DIR"           // SDK 缓存目录
+	NacosLogDirKey            = "EVERFIR_NACOS_LOG_DIR"             // SDK 日志目录
+	NacosLogLevelKey          = "EVERFIR_NACOS_LOG_LEVEL"           // SDK 日志级别: debug/info/warn/error, 默认 error
+	NacosLoadCacheAtStartKey  = "EVERFIR_NACOS_LOAD_CACHE_AT_START" // 启动时是否加载 SDK 缓存, 默认 false
+	NacosTLSKey               = "EVERFIR_NACOS_TLS"                 // 是否启用 TLS, 启用后默认协议为 https
+	NacosTLSCAKey             = "EVERFIR_NACOS_TLS_CA"              // CA 证书文件
+	NacosTLSCertKey           = "EVERFIR_NACOS_TLS_CERT"            // 客户端证书文件
+	NacosTLSKeyKey            = "EVERFIR_NACOS_TLS_KEY"             // 客户端私钥文件
+	NacosTLSServerNameKey     = "EVERFIR_NACOS_TLS_SERVER_NAME"     // 校验证书时使用的服务名
+	NacosTLSInsecureKey       = "EVERFIR_NACOS_TLS_INSECURE"        // 是否跳过证书校验, 仅用于测试
+	defaultNacosPort          = 8848
+	defaultNacosScheme        = "http"
+	defaultNacosContextPath   = "/nacos"
+	defaultNacosTimeout       = 60 * time.Second
+	defaultNacosLogLevel      = "error"
+	defaultNacosServerAddrSep = ","
+)
+
+// ServerAddr: Nacos 服务地址
+type ServerAddr struct {
+	Scheme      string // http 或 https
+	Host        string
+	Port        uint64
+	ContextPath string // 例如 /nacos
+}
+
+// String: 地址的 URL 形式, 例如 http://127.0.0.1:8848/nacos
+func (addr ServerAddr) String() string {
+	return fmt.Sprintf("%s://%s%s", addr.Scheme, net.JoinHostPort(addr.Host, strconv.FormatUint(addr.Port, 10)), addr.ContextPath)
+}
+
+// ParseServerAddr: 解析服务地址, 支持 "host"、"host:port"、"scheme://host:port/contextPath",
+// 未指定的部分使用 defaults 中的值
+func ParseServerAddr(s string, defaults ServerAddr) (ServerAddr, error) {
+	addr := defaults
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return addr, fmt.Errorf("[go-helper] empty nacos server address")
+	}
+	if !strings.Contains(s, "://") {
+		s = addr.Scheme + "://" + s
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return addr, fmt.Errorf("[go-helper] invalid nacos server address %q: %w", s, err)
+	}
+	if u.Hostname() == "" {
+		return addr, fmt.Errorf("[go-helper] invalid nacos server address %q: missing host", s)
+	}
+
+	addr.Scheme = u.Scheme
+	addr.Host = u.Hostname()
+	if p := u.Port(); p != "" {
+		addr.Port, err = strconv.ParseUint(p, 10, 16)
+		if err != nil {
+			return addr, fmt.Errorf("[go-helper] invalid nacos server port %q: %w", p, err)
+		}
+	}
+	if path := strings.TrimRight(u.Path, "/"); path != "" {
+		addr.ContextPath = path
+	}
+	return addr, nil
+}
+
+// TLSOptions: TLS 配置
+type TLSOptions struct {
+	Enable             bool
+	CAFile             string // 为空时使用系统 CA
+	CertFile           string // 双向认证的客户端证书
+	KeyFile            string
+	ServerName         string
+	InsecureSkipVerify bool
+}
+
+// Config: 生成 tls.Config
+func (opts TLSOptions) Config() (*tls.Config, error) {
+	cfg := &tls.Config{
+		ServerName:         opts.ServerName,
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+	}
+	if opts.CAFile != "" {
+		ca, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("[go-helper] read nacos tls ca failed: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("[go-helper] invalid nacos tls ca: %s", opts.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+	if opts.CertFile != "" || opts.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("[go-helper] load nacos tls certificate failed: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// ClientOptions: Nacos 客户端配置
+type ClientOptions struct {
+	Servers             []ServerAddr // 多个地址时 SDK 随机选择并在请求失败时切换
+	Namespace           string
+	Username            string // 显式指定的账号, 优先于 Credentials
+	Password            string
+	Credentials         CredentialProvider // 创建客户端与 Open API 登录时读取账号, 为空且未指定 Username 时不鉴权
+	Timeout             time.Duration
+	NotLoadCacheAtStart bool
+	CacheDir            string
+	LogDir              string
+	LogLevel            string
+	TLS                 TLSOptions
+}
+
+// DefaultClientOptions: 默认配置, 服务地址与命名空间按 IDC 与环境内置, 不包含账号
+func DefaultClientOptions() ClientOptions {
+	opts := ClientOptions{
+		Namespace:           namespaceMapping()[env.Env()],
+		Timeout:             defaultNacosTimeout,
+		NotLoadCacheAtStart: true,
+		LogLevel:            defaultNacosLogLevel,
+	}
+	if ip := ipMapping()[env.Env()]; ip != "" {
+		opts.Servers = []ServerAddr{{Scheme: defaultNacosScheme, Host: ip, Port: defaultNacosPort, ContextPath: defaultNacosContextPath}}
+	}
+	return opts
+}
+
+// ClientOptionsFromEnv: 在默认配置的基础上读取环境变量
+//
+// 服务地址的优先级: EVERFIR_NACOS_ADDRS > EVERFIR_NACOS_IP > IDC 内置地址,
+// 地址中未指定的协议、端口、路径使用 EVERFIR_NACOS_SCHEME、EVERFIR_NACOS_PORT、EVERFIR_NACOS_CONTEXT_PATH。
+// 账号使用 CredentialProviderFromEnv。
+func ClientOptionsFromEnv() (ClientOptions, error) {
+	opts := DefaultClientOptions()
+	opts.Credentials = CredentialProviderFromEnv()
+
+	lookup := func(key string, fn func(v string) error) error {
+		if v, exist := os.LookupEnv(key); exist && v != "" {
+			if err := fn(v); err != nil {
+				return fmt.Errorf("[go-helper] invalid %s: %w", key, err)
+			}
+		}
+		return nil
+	}
+	parseBool := func(dst *bool) func(v string) error {
+		return func(v string) (err error) {
+			*dst, err = strconv.ParseBool(v)
+			return
+		}
+	}
+	setString := func(dst *string) func(v string) error {
+		return func(v string) error {
+			*dst = v
+			return nil
+		}
+	}
+
+	errs := []error{
+		lookup(NacosNamespaceKey, setString(&opts.Namespace)),
+		lookup(NacosTimeoutKey, func(v string) (err error) {
+			opts.Timeout, err = time.ParseDuration(v)
+			return
+		}),
+		lookup(NacosCacheDirKey, setString(&opts.CacheDir)),
+		lookup(NacosLogDirKey, setString(&opts.LogDir)),
+		lookup(NacosLogLevelKey, setString(&opts.LogLevel)),
+		lookup(NacosLoadCacheAtStartKey, func(v string) error {
+			load, err := strconv.ParseBool(v)
+			opts.NotLoadCacheAtStart = !load
+			return err
+		}),
+		lookup(NacosTLSKey, parseBool(&opts.TLS.Enable)),
+		lookup(NacosTLSCAKey, setString(&opts.TLS.CAFile)),
+		lookup(NacosTLSCertKey, setString(&opts.TLS.CertFile)),
+		lookup(NacosTLSKeyKey, setString(&opts.TLS.KeyFile)),
+		lookup(NacosTLSServerNameKey, setString(&opts.TLS.ServerName)),
+		lookup(NacosTLSInsecureKey, parseBool(&opts.TLS.InsecureSkipVerify)),
+	}
+	for _, err := range errs {
+		if err != nil {
+			return opts, err
+		}
+	}
+
+	// 地址的默认协议、端口、路径
+	defaults := ServerAddr{Scheme: defaultNacosScheme, Port: defaultNacosPort, ContextPath: defaultNacosContextPath}
+	if opts.TLS.Enable {
+		defaults.Scheme = "https"
+	}
+	errs = []error{
+		lookup(NacosSchemeKey, setString(&defaults.Scheme)),
+		lookup(NacosPortKey, func(v string) (err error) {
+			defaults.Port, err = strconv.ParseUint(v, 10, 16)
+			return
+		}),
+		lookup(NacosContextPathKey, setString(&defaults.ContextPath)),
+	}
+	for _, err := range errs {
+		if err != nil {
+			return opts, err
+		}
+	}
+
+	addrs := os.Getenv(NacosAddrsKey)
+	if addrs == "" {
+		addrs = os.Getenv(NacosIpKey)
+	}
+	if addrs == "" {
+		// 内置地址也使用环境变量中的协议、端口、路径
+		for i := range opts.Servers {
+			opts.Servers[i].Scheme, opts.Servers[i].Port, opts.Servers[i].ContextPath = defaults.Scheme, defaults.Port, defaults.ContextPath
+		}
+		return opts, opts.Validate()
+	}
+
+	servers, err := parseServerAddrs(addrs, defaults)
+	if err != nil {
+		return opts, err
+	}
+	opts.Servers = servers
+	return opts, opts.Validate()
+}
+
+func parseServerAddrs(addrs string, defaults ServerAddr) ([]ServerAddr, error) {
+	var servers []ServerAddr
+	for _, s := range strings.Split(addrs, defaultNacosServerAddrSep) {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		addr, err := ParseServerAddr(s, defaults)
+		if err != nil {
+			return nil, err
+		}
+		servers = append(servers, addr)
+	}
+	return servers, nil
+}
+
+// RegisterFlags: 注册命令行参数, 解析后覆盖对应配置, 适用于命令行工具
+//
+//	-nacos-addrs、-nacos-namespace、-nacos-timeout、-nacos-cache-dir、-nacos-log-dir、-nacos-log-level、
+//	-nacos-tls、-nacos-tls-ca、-nacos-tls-cert、-nacos-tls-key、-nacos-tls-server-name、-nacos-tls-insecure
+func (opts *ClientOptions) RegisterFlags(fs *flag.FlagSet) {
+	fs.Var((*serverAddrsFlag)(opts), "nacos-addrs", "comma separated nacos server addresses, e.g. https://10.0.0.1:8848/nacos,10.0.0.2")
+	fs.StringVar(&opts.Namespace, "nacos-namespace", opts.Namespace, "nacos namespace id")
+	fs.DurationVar(&opts.Timeout, "nacos-timeout", opts.Timeout, "nacos request timeout")
+	fs.StringVar(&opts.CacheDir, "nacos-cache-dir", opts.CacheDir, "nacos sdk cache dir")
+	fs.StringVar(&opts.LogDir, "nacos-log-dir", opts.LogDir, "nacos sdk log dir")
+	fs.StringVar(&opts.LogLevel, "nacos-log-level", opts.LogLevel, "nacos sdk log level: debug, info, warn, error")
+	fs.BoolVar(&opts.TLS.Enable, "nacos-tls", opts.TLS.Enable, "enable tls")
+	fs.StringVar(&opts.TLS.CAFile, "nacos-tls-ca", opts.TLS.CAFile, "tls ca file")
+	fs.StringVar(&opts.TLS.CertFile, "nacos-tls-cert", opts.TLS.CertFile, "tls client certificate file")
+	fs.StringVar(&opts.TLS.KeyFile, "nacos-tls-key", opts.TLS.KeyFile, "tls client key file")
+	fs.StringVar(&opts.TLS.ServerName, "nacos-tls-server-name", opts.TLS.ServerName, "tls server name")
+	fs.BoolVar(&opts.TLS.InsecureSkipVerify, "nacos-tls-insecure", opts.TLS.InsecureSkipVerify, "skip tls certificate verification")
+}
+
+// serverAddrsFlag: -nacos-addrs 参数, 使用与环境变量相同的默认协议、端口、路径
+type serverAddrsFlag ClientOptions
+
+func (f *serverAddrsFlag) String() string {
+	addrs := make([]string, 0, len(f.Servers))
+	for _, addr := range f.Servers {
+		addrs = append(addrs, addr.String())
+	}
+	return strings.Join(addrs, defaultNacosServerAddrSep)
+}
+
+func (f *serverAddrsFlag) Set(v string) error {
+	defaults := ServerAddr{Scheme: defaultNacosScheme, Port: defaultNacosPort, ContextPath: defaultNacosContextPath}
+	if f.TLS.Enable {
+		defaults.Scheme = "https"
+	}
+	servers, err := parseServerAddrs(v, defaults)
+	if err != nil {
+		return err
+	}
+	f.Servers = servers
+	return nil
+}
+
+func (opts ClientOptions) Validate() error {
+	if len(opts.Servers) == 0 {
+		return fmt.Errorf("[go-helper] nacos server address is required, set %s or %s", NacosAddrsKey, NacosIpKey)
+	}
+	for _, addr := range opts.Servers {
+		if addr.Scheme != "http" && addr.Scheme != "https" {
+			return fmt.Errorf("[go-helper] unsupported nacos scheme: %s", addr.Scheme)
+		}
+	}
+	if opts.Timeout <= 0 {
+		return fmt.Errorf("[go-helper] nacos timeout should be positive, got %s", opts.Timeout)
+	}
+	switch opts.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("[go-helper] unsupported nacos log level: %s", opts.LogLevel)
+	}
+	return nil
+}
+
+// Credential: 当前使用的账号, 显式指定的 Username 优先于 Credentials
+func (opts ClientOptions) Credential() (Credential, error) {
+	return resolveCredential(Credential{Username: opts.Username, Password: opts.Password}, opts.Credentials)
+}
+
+func (opts ClientOptions) clientConfig(credential Credential) constant.ClientConfig {
+	return constant.ClientConfig{
+		NamespaceId:         opts.Namespace,
+		TimeoutMs:           uint64(opts.Timeout.Milliseconds()),
+		NotLoadCacheAtStart: opts.NotLoadCacheAtStart,
+		LogDir:              opts.LogDir,
+		CacheDir:            opts.CacheDir,
+		LogLevel:            opts.LogLevel,
+		Username:            credential.Username,
+		Password:            credential.Password,
+	}
+}
+
+func (opts ClientOptions) serverConfigs() []constant.ServerConfig {
+	sc := make([]constant.ServerConfig, 0, len(opts.Servers))
+	for _, addr := range opts.Servers {
+		sc = append(sc, constant.ServerConfig{
+			Scheme:      addr.Scheme,
+			ContextPath: addr.ContextPath,
+			IpAddr:      addr.Host,
+			Port:        addr.Port,
+		})
+	}
+	return sc
+}
+
+// httpClient: 请求 Nacos 使用的 http.Client, 启用 TLS 时使用自定义证书
+func (opts ClientOptions) httpClient() (*http.Client, error) {
+	client := &http.Client{Timeout: opts.Timeout}
+	if !opts.TLS.Enable {
+		return client, nil
+	}
+
+	cfg, err := opts.TLS.Config()
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = cfg
+	client.Transport = transport
+	return client, nil
+}
+
+// OpenAPI: 基于同一份配置创建 Open API 客户端, 第一个服务地址连接失败时依次尝试其余地址, 统一使用第一个服务地址的路径
+func (opts ClientOptions) OpenAPI() (*OpenAPI, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	client, err := opts.httpClient()
+	if err != nil {
+		return nil, err
+	}
+
+	addr := opts.Servers[0]
+	addrs := make([]string, 0, len(opts.Servers)-1)
+	for _, server := range opts.Servers[1:] {
+		addrs = append(addrs, strings.TrimSuffix(server.String(), server.ContextPath))
+	}
+	return &OpenAPI{
+		Addr:        strings.TrimSuffix(addr.String(), addr.ContextPath),
+		Addrs:       addrs,
+		ContextPath: addr.ContextPath,
+		Namespace:   opts.Namespace,
+		Username:    opts.Username,
+		Password:    opts.Password,
+		Credentials: opts.Credentials,
+		Client:      client,
+	}, nil
+}
+
+// NewConfigClient: 按配置创建 Nacos 配置客户端
+func NewConfigClient(opts ClientOptions) (config_client.IConfigClient, error) {
+	client, err := opts.nacosClient()
+	if err != nil {
+		return nil, err
+	}
+	return config_client.NewConfigClient(client)
+}
+
+// nacosClient: 配置客户端与服务发现客户端共用的连接配置, 请求使用支持 TLS 的 httpAgent
+func (opts ClientOptions) nacosClient() (*nacos_client.NacosClient, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	// SDK 只在创建时读取账号, 轮换密码后需要重新创建客户端
+	credential, err := opts.Credential()
+	if err != nil {
+		return nil, err
+	}
+
+	client := &nacos_client.NacosClient{}
+	if err = client.SetClientConfig(opts.clientConfig(credential)); err != nil {
+		return nil, err
+	}
+	if err = client.SetServerConfig(opts.serverConfigs()); err != nil {
+		return nil, err
+	}
+
+	httpClient, err := opts.httpClient()
+	if err != nil {
+		return nil, err
+	}
+	// 超时由 SDK 按请求指定, 长轮询的超时大于 opts.Timeout
+	httpClient.Timeout = 0
+	if err = client.SetHttpAgent(&httpAgent{client: httpClient}); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
@@ -0,0 +1,114 @@
+package nacos
+
+import (
+	"encoding/pem"
+	"flag"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nacos-group/nacos-sdk-go/vo"
+	"github.com/zeebo/assert"
+)
+
+func TestClientOptionsFromEnv(t *testing.T) {
+	t.Setenv(NacosIpKey, "10.0.0.9")
+	t.Setenv(NacosPortKey, "8849")
+	opts, err := ClientOptionsFromEnv()
+	assert.NoError(t, err)
+	assert.DeepEqual(t, opts.Servers, []ServerAddr{{Scheme: "http", Host: "10.0.0.9", Port: 8849, ContextPath: "/nacos"}})
+	assert.Equal(t, opts.Timeout, 60*time.Second)
+	assert.True(t, opts.NotLoadCacheAtStart)
+
+	// EVERFIR_NACOS_ADDRS 优先于 EVERFIR_NACOS_IP, 启用 TLS 时默认协议为 https
+	t.Setenv(NacosAddrsKey, "10.0.0.1, http://10.0.0.2:80/ctx/ ,[::1]:9000")
+	t.Setenv(NacosTLSKey, "true")
+	t.Setenv(NacosTimeoutKey, "5s")
+	t.Setenv(NacosCacheDirKey, "/tmp/nacos")
+	t.Setenv(NacosLogLevelKey, "warn")
+	t.Setenv(NacosLoadCacheAtStartKey, "true")
+	opts, err = ClientOptionsFromEnv()
+	assert.NoError(t, err)
+	assert.DeepEqual(t, opts.Servers, []ServerAddr{
+		{Scheme: "https", Host: "10.0.0.1", Port: 8849, ContextPath: "/nacos"},
+		{Scheme: "http", Host: "10.0.0.2", Port: 80, ContextPath: "/ctx"},
+		{Scheme: "https", Host: "::1", Port: 9000, ContextPath: "/nacos"},
+	})
+	assert.Equal(t, opts.Servers[2].String(), "https://[::1]:9000/nacos")
+	assert.Equal(t, opts.Timeout, 5*time.Second)
+	assert.Equal(t, opts.CacheDir, "/tmp/nacos")
+	assert.Equal(t, opts.LogLevel, "warn")
+	assert.False(t, opts.NotLoadCacheAtStart)
+	assert.True(t, opts.TLS.Enable)
+
+	// 命令行参数覆盖环境变量
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	opts.RegisterFlags(fs)
+	assert.NoError(t, fs.Parse([]string{"-nacos-addrs", "h1:1,h2", "-nacos-timeout", "3s"}))
+	assert.Equal(t, len(opts.Servers), 2)
+	assert.Equal(t, opts.Servers[1].String(), "https://h2:8848/nacos")
+	assert.Equal(t, opts.Timeout, 3*time.Second)
+
+	for key, value := range map[string]string{
+		NacosTimeoutKey:  "abc",
+		NacosAddrsKey:    "ftp://10.0.0.1",
+		NacosLogLevelKey: "trace",
+		NacosPortKey:     "70000",
+	} {
+		t.Run(key, func(t *testing.T) {
+			t.Setenv(key, value)
+			_, err := ClientOptionsFromEnv()
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestNewConfigClientTLS(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.URL.Path, "/nacos/v1/cs/configs")
+		assert.Equal(t, r.URL.Query().Get("dataId"), "gray.json")
+		w.Write([]byte(`{"enable":true}`))
+	}))
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	ca := filepath.Join(t.TempDir(), "ca.pem")
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	assert.NoError(t, os.WriteFile(ca, pemBytes, 0o600))
+
+	addr, err := ParseServerAddr(server.URL, ServerAddr{Port: 8848, ContextPath: "/nacos"})
+	assert.NoError(t, err)
+	opts := ClientOptions{
+		Servers:             []ServerAddr{addr},
+		Timeout:             time.Second,
+		NotLoadCacheAtStart: true,
+		CacheDir:            t.TempDir(),
+		LogDir:              t.TempDir(),
+		LogLevel:            "error",
+		TLS:                 TLSOptions{Enable: true, CAFile: ca},
+	}
+
+	client, err := NewConfigClient(opts)
+	assert.NoError(t, err)
+	content, err := client.GetConfig(vo.ConfigParam{DataId: "gray.json", Group: "test"})
+	assert.NoError(t, err)
+	assert.Equal(t, content, `{"enable":true}`)
+
+	// 未信任服务端证书时请求失败, 使用新的缓存目录避免读到上次请求的缓存
+	opts.TLS.CAFile = ""
+	opts.CacheDir = t.TempDir()
+	client, err = NewConfigClient(opts)
+	assert.NoError(t, err)
+	_, err = client.GetConfig(vo.ConfigParam{DataId: "gray.json", Group: "test"})
+	assert.Error(t, err)
+
+	api, err := (ClientOptions{Servers: []ServerAddr{addr}, Timeout: time.Second, LogLevel: "error"}).OpenAPI()
+	assert.NoError(t, err)
+	assert.Equal(t, api.url(api.Addr, "/v1/cs/configs"), server.URL+"/nacos/v1/cs/configs")
+}
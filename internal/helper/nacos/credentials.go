@@ -0,0 +1,246 @@
+package nacos
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Nacos 账号相关的环境变量
+const (
+	NacosUsernameKey         = "EVERFIR_NACOS_USERNAME"
+	NacosPasswordKey         = "EVERFIR_NACOS_PASSWORD"
+	NacosCredentialsFileKey  = "EVERFIR_NACOS_CREDENTIALS_FILE" // 挂载的账号文件或目录, 见 FileCredentialProvider
+	defaultCredentialsReload = 10 * time.Second
+	redacted                 = "******"
+)
+
+// ErrNoCredential: 账号提供者没有可用账号
+var ErrNoCredential = errors.New("[go-helper] no nacos credential")
+
+// Redact: 隐藏敏感信息, 空字符串保持为空以便区分是否配置
+func Redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redacted
+}
+
+// Credential: Nacos 账号
+type Credential struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// String: 密码脱敏, 避免通过 fmt 或日志输出密码
+func (c Credential) String() string {
+	return fmt.Sprintf("{Username:%s Password:%s}", c.Username, Redact(c.Password))
+}
+
+func (c Credential) GoString() string {
+	return c.String()
+}
+
+// MarshalJSON: 密码脱敏, 避免以 JSON 形式记录日志时输出密码
+func (c Credential) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{"username": c.Username, "password": Redact(c.Password)})
+}
+
+// CredentialProvider: Nacos 账号提供者
+//
+// Open API 每次登录时读取账号, 轮换密码只需更新环境变量或账号文件;
+// Nacos SDK 的配置与服务发现客户端只在创建时读取账号, 之后一直使用该账号登录, 轮换密码后需要重启服务,
+// 轮换期间新旧密码需要同时有效
+type CredentialProvider interface {
+	// Credential 返回当前账号, 没有可用账号时返回 ErrNoCredential
+	Credential() (Credential, error)
+}
+
+// CredentialProviderFunc: 函数形式的账号提供者
+type CredentialProviderFunc func() (Credential, error)
+
+func (fn CredentialProviderFunc) Credential() (Credential, error) {
+	return fn()
+}
+
+// EnvCredentialProvider: 从环境变量读取账号, 默认 EVERFIR_NACOS_USERNAME、EVERFIR_NACOS_PASSWORD
+type EnvCredentialProvider struct {
+	UsernameKey string
+	PasswordKey string
+}
+
+func (p EnvCredentialProvider) Credential() (Credential, error) {
+	usernameKey, passwordKey := p.UsernameKey, p.PasswordKey
+	if usernameKey == "" {
+		usernameKey = NacosUsernameKey
+	}
+	if passwordKey == "" {
+		passwordKey = NacosPasswordKey
+	}
+
+	c := Credential{Username: os.Getenv(usernameKey), Password: os.Getenv(passwordKey)}
+	if c.Username == "" {
+		return c, ErrNoCredential
+	}
+	return c, nil
+}
+
+// FileCredentialProvider: 从挂载的 secrets 文件读取账号, 文件变化后自动重新加载
+//
+// path 支持两种形式:
+//   - 文件: JSON 格式, 例如 {"username":"nacos","password":"xxx"}
+//   - 目录: Kubernetes Secret 挂载目录, 读取其中的 username 与 password 文件
+//
+// 每隔 interval 检查一次文件修改时间, 读取失败时继续使用上次成功读取的账号;
+// 重新加载的账号只对之后的读取生效, 已创建的 Nacos SDK 客户端不会重新登录, 见 CredentialProvider
+type FileCredentialProvider struct {
+	path     string
+	interval time.Duration
+
+	lock       sync.Mutex
+	credential Credential
+	mtime      time.Time
+	checked    time.Time
+	loaded     bool
+}
+
+// NewFileCredentialProvider: 创建文件账号提供者, interval <= 0 时使用默认的 10s
+func NewFileCredentialProvider(path string, interval time.Duration) *FileCredentialProvider {
+	if interval <= 0 {
+		interval = defaultCredentialsReload
+	}
+	return &FileCredentialProvider{path: path, interval: interval}
+}
+
+func (p *FileCredentialProvider) Credential() (Credential, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	now := time.Now()
+	if p.loaded && now.Sub(p.checked) < p.interval {
+		return p.credential, nil
+	}
+	p.checked = now
+
+	mtime, err := p.modTime()
+	if err != nil {
+		if p.loaded {
+			return p.credential, nil
+		}
+		return Credential{}, fmt.Errorf("[go-helper] read nacos credentials failed: %w", err)
+	}
+	if p.loaded && mtime.Equal(p.mtime) {
+		return p.credential, nil
+	}
+
+	credential, err := p.read()
+	if err != nil {
+		if p.loaded {
+			return p.credential, nil
+		}
+		return Credential{}, fmt.Errorf("[go-helper] read nacos credentials failed: %w", err)
+	}
+	if credential.Username == "" {
+		return credential, ErrNoCredential
+	}
+
+	p.credential, p.mtime, p.loaded = credential, mtime, true
+	return credential, nil
+}
+
+// modTime: 文件或目录中账号文件的最新修改时间, Kubernetes 通过替换符号链接更新 Secret, Stat 会跟随链接
+func (p *FileCredentialProvider) modTime() (time.Time, error) {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !info.IsDir() {
+		return info.ModTime(), nil
+	}
+
+	var latest time.Time
+	for _, name := range []string{"username", "password"} {
+		info, err := os.Stat(filepath.Join(p.path, name))
+		if err != nil {
+			return latest, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+func (p *FileCredentialProvider) read() (c Credential, err error) {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return
+	}
+
+	if !info.IsDir() {
+		content, err := os.ReadFile(p.path)
+		if err != nil {
+			return c, err
+		}
+		err = json.Unmarshal(content, &c)
+		return c, err
+	}
+
+	username, err := os.ReadFile(filepath.Join(p.path, "username"))
+	if err != nil {
+		return
+	}
+	password, err := os.ReadFile(filepath.Join(p.path, "password"))
+	if err != nil {
+		return
+	}
+	return Credential{Username: strings.TrimSpace(string(username)), Password: strings.TrimSpace(string(password))}, nil
+}
+
+// ChainCredentialProvider: 依次尝试多个账号提供者, 返回第一个可用账号
+type ChainCredentialProvider []CredentialProvider
+
+func (chain ChainCredentialProvider) Credential() (Credential, error) {
+	var errs []error
+	for _, provider := range chain {
+		c, err := provider.Credential()
+		if err == nil {
+			return c, nil
+		}
+		if !errors.Is(err, ErrNoCredential) {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return Credential{}, errors.Join(errs...)
+	}
+	return Credential{}, ErrNoCredential
+}
+
+// CredentialProviderFromEnv: 默认的账号提供者, 依次尝试
+//   - 环境变量 EVERFIR_NACOS_USERNAME、EVERFIR_NACOS_PASSWORD
+//   - EVERFIR_NACOS_CREDENTIALS_FILE 指定的账号文件
+func CredentialProviderFromEnv() CredentialProvider {
+	chain := ChainCredentialProvider{EnvCredentialProvider{}}
+	if path := os.Getenv(NacosCredentialsFileKey); path != "" {
+		chain = append(chain, NewFileCredentialProvider(path, 0))
+	}
+	return chain
+}
+
+// resolveCredential: 显式配置的用户名优先, 其次使用 provider, 都没有时不鉴权
+func resolveCredential(explicit Credential, provider CredentialProvider) (Credential, error) {
+	if explicit.Username != "" || provider == nil {
+		return explicit, nil
+	}
+	c, err := provider.Credential()
+	if errors.Is(err, ErrNoCredential) {
+		return Credential{}, nil
+	}
+	return c, err
+}
@@ -0,0 +1,108 @@
+package nacos
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zeebo/assert"
+)
+
+func TestCredentialProvider(t *testing.T) {
+	c := Credential{Username: "nacos", Password: "secret"}
+	assert.Equal(t, fmt.Sprint(c), "{Username:nacos Password:******}")
+	assert.Equal(t, fmt.Sprintf("%#v", c), "{Username:nacos Password:******}")
+	content, err := json.Marshal(c)
+	assert.NoError(t, err)
+	assert.Equal(t, string(content), `{"password":"******","username":"nacos"}`)
+
+	// 环境变量
+	_, err = EnvCredentialProvider{}.Credential()
+	assert.Equal(t, err, ErrNoCredential)
+	t.Setenv(NacosUsernameKey, "env")
+	t.Setenv(NacosPasswordKey, "env-secret")
+	c, err = EnvCredentialProvider{}.Credential()
+	assert.NoError(t, err)
+	assert.Equal(t, c, Credential{Username: "env", Password: "env-secret"})
+
+	// JSON 文件, 修改后重新加载, 文件损坏时继续使用上次的账号
+	file := filepath.Join(t.TempDir(), "nacos.json")
+	assert.NoError(t, os.WriteFile(file, []byte(`{"username":"file","password":"v1"}`), 0o600))
+	provider := NewFileCredentialProvider(file, time.Nanosecond)
+	c, err = provider.Credential()
+	assert.NoError(t, err)
+	assert.Equal(t, c.Password, "v1")
+
+	assert.NoError(t, os.WriteFile(file, []byte(`{"username":"file","password":"v2"}`), 0o600))
+	assert.NoError(t, os.Chtimes(file, time.Now(), time.Now().Add(time.Second)))
+	c, err = provider.Credential()
+	assert.NoError(t, err)
+	assert.Equal(t, c.Password, "v2")
+
+	assert.NoError(t, os.WriteFile(file, []byte(`{`), 0o600))
+	assert.NoError(t, os.Chtimes(file, time.Now(), time.Now().Add(2*time.Second)))
+	c, err = provider.Credential()
+	assert.NoError(t, err)
+	assert.Equal(t, c.Password, "v2")
+
+	// Kubernetes Secret 目录
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "username"), []byte("dir\n"), 0o600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "password"), []byte("dir-secret\n"), 0o600))
+	c, err = NewFileCredentialProvider(dir, 0).Credential()
+	assert.NoError(t, err)
+	assert.Equal(t, c, Credential{Username: "dir", Password: "dir-secret"})
+
+	_, err = NewFileCredentialProvider(filepath.Join(dir, "missing"), 0).Credential()
+	assert.Error(t, err)
+
+	// 链式: 环境变量优先, 其次文件
+	t.Setenv(NacosUsernameKey, "")
+	t.Setenv(NacosCredentialsFileKey, dir)
+	chain := CredentialProviderFromEnv()
+	c, err = chain.Credential()
+	assert.NoError(t, err)
+	assert.Equal(t, c.Username, "dir")
+	assert.Equal(t, len(chain.(ChainCredentialProvider)), 2)
+
+	// 显式指定的账号优先, 没有账号时不鉴权
+	c, err = resolveCredential(Credential{Username: "explicit"}, chain)
+	assert.NoError(t, err)
+	assert.Equal(t, c.Username, "explicit")
+	c, err = resolveCredential(Credential{}, ChainCredentialProvider{EnvCredentialProvider{UsernameKey: "EVERFIR_TEST_MISSING"}})
+	assert.NoError(t, err)
+	assert.Equal(t, c, Credential{})
+}
+
+func TestOpenAPICredentialRotation(t *testing.T) {
+	var passwords []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/nacos/v1/auth/login" {
+			passwords = append(passwords, r.FormValue("password"))
+			// tokenTtl 小于 1 分钟, 每次请求都重新登录
+			w.Write([]byte(`{"accessToken":"token","tokenTtl":1}`))
+			return
+		}
+		w.Write([]byte(`{"pageItems":[]}`))
+	}))
+	defer server.Close()
+
+	password := "v1"
+	api := &OpenAPI{
+		Addr: server.URL,
+		Credentials: CredentialProviderFunc(func() (Credential, error) {
+			return Credential{Username: "nacos", Password: password}, nil
+		}),
+	}
+	_, err := api.ListHistory("gray.json", "test", 1, 10)
+	assert.NoError(t, err)
+	password = "v2"
+	_, err = api.ListHistory("gray.json", "test", 1, 10)
+	assert.NoError(t, err)
+	assert.DeepEqual(t, passwords, []string{"v1", "v2"})
+}
@@ -0,0 +1,328 @@
+package nacos
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/everfir/go-helpers/internal/helper/codec"
+)
+
+// 配置的加载方式
+const (
+	ConfigMode_Plain   = "plain"
+	ConfigMode_Gray    = "gray"
+	ConfigMode_Overlay = "overlay"
+)
+
+// secretKeys: 字段名(忽略大小写与 _、-)包含这些词时视为敏感字段, 输出时脱敏
+var secretKeys = []string{"password", "passwd", "secret", "token", "credential", "privatekey", "accesskey", "apikey"}
+
+// GroupStatus: 配置分组的状态
+type GroupStatus struct {
+	Group       string     `json:"group"`
+	Md5         string     `json:"md5"`
+	UpdatedAt   time.Time  `json:"updated_at"`              // 最近一次生效的时间
+	LastError   string     `json:"last_error,omitempty"`    // 最近一次被拒绝的变更的原因, 解析失败或 Validate 未通过
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"` // 最近一次被拒绝的时间
+	Rejected    int64      `json:"rejected"`                // 被拒绝的变更次数
+	Content     any        `json:"content,omitempty"`       // 脱敏后的当前配置, text 格式或无法解析时为空
+}
+
+// ConfigStatus: 通过 GetConfigAndListen 系列函数创建的配置的状态
+type ConfigStatus struct {
+	DataId    string        `json:"data_id"`
+	Format    codec.Format  `json:"format"`
+	Mode      string        `json:"mode"`
+	Loaded    bool          `json:"loaded"`               // 是否加载成功
+	LoadedAt  time.Time     `json:"loaded_at"`            // 加载(或加载失败)的时间
+	LastError string        `json:"last_error,omitempty"` // 加载失败的原因
+	Groups    []GroupStatus `json:"groups"`
+}
+
+// configTracker: 记录一个配置的加载与变更情况
+type configTracker struct {
+	registry *configRegistry
+	id       uint64
+	dataId   string
+	format   codec.Format
+	mode     string
+
+	lock     sync.Mutex
+	loaded   bool
+	loadedAt time.Time
+	err      string
+	groups   map[string]*groupTracker
+}
+
+type groupTracker struct {
+	md5         string
+	content     string
+	updatedAt   time.Time
+	lastError   string
+	lastErrorAt time.Time
+	rejected    int64
+}
+
+func (t *configTracker) group(group string) *groupTracker {
+	g, exist := t.groups[group]
+	if !exist {
+		g = &groupTracker{}
+		t.groups[group] = g
+	}
+	return g
+}
+
+// update: 分组配置生效
+func (t *configTracker) update(group, md5, content string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	g := t.group(group)
+	g.md5, g.content, g.updatedAt = md5, content, time.Now()
+}
+
+// reject: 分组配置的变更被拒绝, 继续使用旧配置
+func (t *configTracker) reject(group string, err error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	g := t.group(group)
+	g.lastError, g.lastErrorAt = err.Error(), time.Now()
+	g.rejected++
+}
+
+// remove: 分组被删除
+func (t *configTracker) remove(group string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	delete(t.groups, group)
+}
+
+// done: 加载完成, err 不为空表示加载失败; 同一 dataId 加载成功后移除之前加载失败的记录
+func (t *configTracker) done(err error) {
+	t.lock.Lock()
+	t.loaded, t.loadedAt = err == nil, time.Now()
+	if err != nil {
+		t.err = err.Error()
+	}
+	t.lock.Unlock()
+
+	if err == nil {
+		t.registry.loaded(t)
+	}
+}
+
+// close: 配置关闭后不再记录
+func (t *configTracker) close() {
+	t.registry.lock.Lock()
+	defer t.registry.lock.Unlock()
+
+	delete(t.registry.trackers, t.id)
+}
+
+func (t *configTracker) status() ConfigStatus {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	status := ConfigStatus{
+		DataId:    t.dataId,
+		Format:    t.format,
+		Mode:      t.mode,
+		Loaded:    t.loaded,
+		LoadedAt:  t.loadedAt,
+		LastError: t.err,
+		Groups:    make([]GroupStatus, 0, len(t.groups)),
+	}
+	for name, g := range t.groups {
+		group := GroupStatus{
+			Group:     name,
+			Md5:       g.md5,
+			UpdatedAt: g.updatedAt,
+			LastError: g.lastError,
+			Rejected:  g.rejected,
+			Content:   redactContent(t.format, g.content),
+		}
+		if !g.lastErrorAt.IsZero() {
+			at := g.lastErrorAt
+			group.LastErrorAt = &at
+		}
+		status.Groups = append(status.Groups, group)
+	}
+	sort.Slice(status.Groups, func(i, j int) bool { return status.Groups[i].Group < status.Groups[j].Group })
+	return status
+}
+
+// configRegistry: 进程内所有配置的状态
+type configRegistry struct {
+	lock     sync.Mutex
+	seq      uint64
+	trackers map[uint64]*configTracker
+	required map[string]struct{}
+}
+
+var configs = &configRegistry{trackers: map[uint64]*configTracker{}, required: map[string]struct{}{}}
+
+// track: 开始记录一个配置
+func (r *configRegistry) track(dataId string, format codec.Format, mode string) *configTracker {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.seq++
+	t := &configTracker{
+		registry: r,
+		id:       r.seq,
+		dataId:   dataId,
+		format:   format,
+		mode:     mode,
+		groups:   map[string]*groupTracker{},
+	}
+	r.trackers[t.id] = t
+	return t
+}
+
+// loaded: 移除同一 dataId 之前加载失败的记录
+func (r *configRegistry) loaded(t *configTracker) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for id, other := range r.trackers {
+		if id == t.id || other.dataId != t.dataId {
+			continue
+		}
+		other.lock.Lock()
+		failed := !other.loaded && other.err != ""
+		other.lock.Unlock()
+		if failed {
+			delete(r.trackers, id)
+		}
+	}
+}
+
+func (r *configRegistry) statuses() []ConfigStatus {
+	r.lock.Lock()
+	trackers := make([]*configTracker, 0, len(r.trackers))
+	for _, t := range r.trackers {
+		trackers = append(trackers, t)
+	}
+	r.lock.Unlock()
+
+	sort.Slice(trackers, func(i, j int) bool {
+		if trackers[i].dataId != trackers[j].dataId {
+			return trackers[i].dataId < trackers[j].dataId
+		}
+		return trackers[i].id < trackers[j].id
+	})
+	statuses := make([]ConfigStatus, 0, len(trackers))
+	for _, t := range trackers {
+		statuses = append(statuses, t.status())
+	}
+	return statuses
+}
+
+// ConfigStatuses 获取进程内通过 GetConfigAndListen 系列函数创建的所有配置的状态, 配置内容中的敏感字段已脱敏
+func ConfigStatuses() []ConfigStatus {
+	return configs.statuses()
+}
+
+// RequireConfigs 声明服务依赖的配置, 这些配置没有加载成功时 CheckConfigHealth 返回错误
+func RequireConfigs(dataIds ...string) {
+	configs.lock.Lock()
+	defer configs.lock.Unlock()
+
+	for _, dataId := range dataIds {
+		configs.required[dataId] = struct{}{}
+	}
+}
+
+// CheckConfigHealth 检查配置状态, 以下情况返回错误:
+//   - 配置加载失败, 之后同一 dataId 加载成功时恢复
+//   - RequireConfigs 声明的配置未加载(包括正在加载)
+//
+// 变更被拒绝(继续使用旧配置)不影响健康状态, 通过 ConfigStatuses 中的 Rejected、LastError 观察
+func CheckConfigHealth() error {
+	configs.lock.Lock()
+	required := make(map[string]struct{}, len(configs.required))
+	for dataId := range configs.required {
+		required[dataId] = struct{}{}
+	}
+	configs.lock.Unlock()
+
+	var errs []error
+	for _, status := range configs.statuses() {
+		if !status.Loaded && status.LastError != "" {
+			errs = append(errs, fmt.Errorf("config %s load failed: %s", status.DataId, status.LastError))
+			continue
+		}
+		if !status.Loaded {
+			continue
+		}
+		delete(required, status.DataId)
+	}
+
+	missing := make([]string, 0, len(required))
+	for dataId := range required {
+		missing = append(missing, dataId)
+	}
+	sort.Strings(missing)
+	for _, dataId := range missing {
+		errs = append(errs, fmt.Errorf("config %s missing", dataId))
+	}
+	return errors.Join(errs...)
+}
+
+// redactContent: 解析配置内容并对敏感字段脱敏, 无法解析时返回 nil
+func redactContent(format codec.Format, content string) any {
+	if strings.TrimSpace(content) == "" {
+		return nil
+	}
+	tree, err := codec.DecodeTree(format, content)
+	if err != nil {
+		return nil
+	}
+	return redactTree(tree)
+}
+
+func redactTree(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		ret := make(map[string]any, len(v))
+		for key, val := range v {
+			if isSecretKey(key) {
+				if s, ok := val.(string); ok {
+					ret[key] = Redact(s)
+				} else if val != nil {
+					ret[key] = redacted
+				} else {
+					ret[key] = nil
+				}
+				continue
+			}
+			ret[key] = redactTree(val)
+		}
+		return ret
+	case []any:
+		ret := make([]any, len(v))
+		for i, val := range v {
+			ret[i] = redactTree(val)
+		}
+		return ret
+	default:
+		return v
+	}
+}
+
+// isSecretKey: 字段名是否为敏感字段
+func isSecretKey(key string) bool {
+	key = strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(key))
+	for _, secret := range secretKeys {
+		if strings.Contains(key, secret) {
+			return true
+		}
+	}
+	return false
+}
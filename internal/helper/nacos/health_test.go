@@ -0,0 +1,75 @@
+package nacos
+
+import (
+	"testing"
+
+	"github.com/everfir/go-helpers/env"
+	"github.com/zeebo/assert"
+)
+
+type secretConfig struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+	Db       struct {
+		AccessKey string `json:"access_key"`
+	} `json:"db"`
+}
+
+func (c *secretConfig) Validate() error {
+	return (&yamlConfig{Name: c.Name}).Validate()
+}
+
+func TestConfigHealth(t *testing.T) {
+	old := configs
+	configs = &configRegistry{trackers: map[uint64]*configTracker{}, required: map[string]struct{}{}}
+	defer func() { configs = old }()
+
+	source := NewMemorySource()
+	RequireConfigs("app.json")
+
+	// 加载失败
+	_, err := GetConfigAndListenFromSource[secretConfig](source, "app.json")
+	assert.Error(t, err)
+	statuses := ConfigStatuses()
+	assert.Equal(t, len(statuses), 1)
+	assert.False(t, statuses[0].Loaded)
+	assert.True(t, statuses[0].LastError != "")
+	assert.Error(t, CheckConfigHealth())
+
+	// 加载成功后移除加载失败的记录
+	content := `{"name":"a","password":"p@ss","db":{"access_key":"ak"}}`
+	assert.NoError(t, source.Publish(PublishParam{DataId: "app.json", Group: env.Env(), Content: content}))
+	cfg, err := GetConfigAndListenFromSource[secretConfig](source, "app.json")
+	assert.NoError(t, err)
+	assert.NoError(t, CheckConfigHealth())
+
+	statuses = ConfigStatuses()
+	assert.Equal(t, len(statuses), 1)
+	assert.True(t, statuses[0].Loaded)
+	assert.Equal(t, statuses[0].Mode, ConfigMode_Plain)
+	assert.Equal(t, len(statuses[0].Groups), 1)
+	group := statuses[0].Groups[0]
+	assert.Equal(t, group.Group, env.Env())
+	assert.Equal(t, group.Md5, contentMd5(content))
+	assert.Equal(t, group.Rejected, int64(0))
+	assert.DeepEqual(t, group.Content, map[string]any{
+		"name":     "a",
+		"password": redacted,
+		"db":       map[string]any{"access_key": redacted},
+	})
+
+	// 被拒绝的变更
+	assert.NoError(t, source.Publish(PublishParam{DataId: "app.json", Group: env.Env(), Content: `{"name":""}`}))
+	assert.NoError(t, source.Publish(PublishParam{DataId: "app.json", Group: env.Env(), Content: `{`}))
+	group = ConfigStatuses()[0].Groups[0]
+	assert.Equal(t, group.Rejected, int64(2))
+	assert.Equal(t, group.Md5, contentMd5(content))
+	assert.True(t, group.LastError != "")
+	assert.True(t, group.LastErrorAt != nil)
+	assert.NoError(t, CheckConfigHealth())
+
+	// 关闭后不再记录
+	cfg.Close()
+	assert.Equal(t, len(ConfigStatuses()), 0)
+	assert.Error(t, CheckConfigHealth())
+}
@@ -0,0 +1,96 @@
+package nacos
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nacos-group/nacos-sdk-go/util"
+)
+
+// httpAgent: 使用自定义 http.Client 的 http_agent.IHttpAgent 实现
+//
+// SDK 默认的 HttpAgent 每次请求都创建 http.Client{} 且无法配置 TLS, 这里除 Transport 外保持与其一致的请求编码
+type httpAgent struct {
+	client *http.Client
+}
+
+func (agent *httpAgent) do(method, path string, header http.Header, timeoutMs uint64, body string) (*http.Response, error) {
+	request, err := http.NewRequest(method, path, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header = header
+
+	client := *agent.client
+	client.Timeout = time.Millisecond * time.Duration(timeoutMs)
+	return client.Do(request)
+}
+
+// query: 与 SDK 一致, 参数不做转义直接拼接
+func query(path string, params map[string]string) string {
+	if !strings.HasSuffix(path, "?") {
+		path = path + "?"
+	}
+	for key, value := range params {
+		path = path + key + "=" + value + "&"
+	}
+	return strings.TrimSuffix(path, "&")
+}
+
+func (agent *httpAgent) Get(path string, header http.Header, timeoutMs uint64, params map[string]string) (*http.Response, error) {
+	return agent.do(http.MethodGet, query(path, params), header, timeoutMs, "")
+}
+
+func (agent *httpAgent) Delete(path string, header http.Header, timeoutMs uint64, params map[string]string) (*http.Response, error) {
+	return agent.do(http.MethodDelete, query(path, params), header, timeoutMs, "")
+}
+
+func (agent *httpAgent) Post(path string, header http.Header, timeoutMs uint64, params map[string]string) (*http.Response, error) {
+	return agent.do(http.MethodPost, path, header, timeoutMs, util.GetUrlFormedMap(params))
+}
+
+func (agent *httpAgent) Put(path string, header http.Header, timeoutMs uint64, params map[string]string) (*http.Response, error) {
+	var body string
+	for key, value := range params {
+		if len(value) > 0 {
+			body += key + "=" + value + "&"
+		}
+	}
+	return agent.do(http.MethodPut, path, header, timeoutMs, strings.TrimSuffix(body, "&"))
+}
+
+func (agent *httpAgent) Request(method string, path string, header http.Header, timeoutMs uint64, params map[string]string) (*http.Response, error) {
+	switch method {
+	case http.MethodGet:
+		return agent.Get(path, header, timeoutMs, params)
+	case http.MethodPost:
+		return agent.Post(path, header, timeoutMs, params)
+	case http.MethodPut:
+		return agent.Put(path, header, timeoutMs, params)
+	case http.MethodDelete:
+		return agent.Delete(path, header, timeoutMs, params)
+	default:
+		return nil, errors.New("[go-helper] not available method: " + method)
+	}
+}
+
+// RequestOnlyResult: 请求失败或状态码非 200 时返回空字符串
+func (agent *httpAgent) RequestOnlyResult(method string, path string, header http.Header, timeoutMs uint64, params map[string]string) string {
+	response, err := agent.Request(method, path, header, timeoutMs, params)
+	if err != nil {
+		return ""
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return ""
+	}
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return ""
+	}
+	return string(body)
+}
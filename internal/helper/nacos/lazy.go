@@ -0,0 +1,305 @@
+package nacos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	nacos_config "github.com/everfir/go-helpers/define/config"
+	"github.com/everfir/logger-go"
+	"github.com/everfir/logger-go/structs/field"
+)
+
+// FailMode: 依赖的配置不可用(加载失败)时的降级方式
+type FailMode int32
+
+const (
+	FailMode_Default FailMode = iota // 使用各组件文档中说明的默认降级方式
+	FailMode_Open                    // 放行, 按没有限制处理
+	FailMode_Closed                  // 拒绝, 返回错误
+)
+
+func (m FailMode) String() string {
+	switch m {
+	case FailMode_Open:
+		return "open"
+	case FailMode_Closed:
+		return "closed"
+	default:
+		return "default"
+	}
+}
+
+// Resolve 返回实际生效的降级方式, FailMode_Default 时返回 def
+func (m FailMode) Resolve(def FailMode) FailMode {
+	if m == FailMode_Default {
+		return def
+	}
+	return m
+}
+
+// 配置加载的默认参数
+const (
+	DefaultReloadInterval = 5 * time.Second        // LazyConfig 加载失败后, Get 再次在后台加载的最小间隔
+	DefaultInitTimeout    = 30 * time.Second       // InitConfigs 的 ctx 没有 deadline 时使用的超时时间
+	DefaultInitRetries    = 3                      // InitConfigs 的默认重试次数
+	DefaultInitBackoff    = 500 * time.Millisecond // InitConfigs 首次重试的间隔, 之后每次翻倍
+)
+
+// ErrConfigLoading: 配置正在后台首次加载, 还没有加载结果
+var ErrConfigLoading = errors.New("[go-helper] config is loading")
+
+// LazyConfig: 延迟加载的配置, 首次 Get 或 InitConfigs 时从 LoadConfigSource 返回的配置源加载
+//
+// 与 sync.OnceValue 不同, 加载失败时返回错误而不是 panic, 也不会缓存失败:
+// 之后的 Get 间隔 DefaultReloadInterval 在后台重新尝试加载, 加载成功后一直使用同一个配置
+//
+// Get 在请求路径上调用, 不会等待加载: 配置源不可用时加载可能阻塞到客户端超时,
+// 同一时间只有一个后台加载, Get 直接返回上一次的错误, 需要在启动时加载的配置使用 InitConfigs
+type LazyConfig[T any] struct {
+	dataId string
+	opts   []Option
+
+	config  atomic.Pointer[nacos_config.NacosConfig[T]]
+	loading sync.Mutex // 串行化加载, Get 不获取该锁
+
+	lock      sync.Mutex // 保护以下字段
+	err       error
+	failedAt  time.Time
+	reloading bool
+}
+
+// NewLazyConfig 创建延迟加载的配置并注册, InitConfigs 在服务启动时统一加载已注册的配置
+func NewLazyConfig[T any](dataId string, opts ...Option) *LazyConfig[T] {
+	c := &LazyConfig[T]{dataId: dataId, opts: opts}
+	lazyConfigs.register(c)
+	return c
+}
+
+// DataId 配置的 dataId
+func (c *LazyConfig[T]) DataId() string {
+	return c.dataId
+}
+
+// Get 获取配置, 不会阻塞: 尚未加载时在后台加载, 并返回上一次加载的错误, 首次加载未完成时返回 ErrConfigLoading;
+// 加载失败后 DefaultReloadInterval 内不再重新加载
+func (c *LazyConfig[T]) Get() (*nacos_config.NacosConfig[T], error) {
+	if config := c.config.Load(); config != nil {
+		return config, nil
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if config := c.config.Load(); config != nil {
+		return config, nil
+	}
+	if !c.reloading && (c.err == nil || time.Since(c.failedAt) >= DefaultReloadInterval) {
+		c.reloading = true
+		go c.reload()
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	return nil, fmt.Errorf("%w: %s", ErrConfigLoading, c.dataId)
+}
+
+func (c *LazyConfig[T]) reload() {
+	_ = c.Load()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.reloading = false
+}
+
+// Load 同步加载配置, 忽略加载失败后的重试间隔, 已加载时直接返回
+func (c *LazyConfig[T]) Load() error {
+	c.loading.Lock()
+	defer c.loading.Unlock()
+
+	if config := c.config.Load(); config != nil {
+		return nil
+	}
+
+	config, err := c.source()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if err != nil {
+		c.err, c.failedAt = fmt.Errorf("[go-helper] load config %s failed: %w", c.dataId, err), time.Now()
+		logger.Warn(context.TODO(), "[go-helper] load config failed", field.String("dataId", c.dataId), field.String("err", err.Error()))
+		return c.err
+	}
+
+	c.err = nil
+	c.config.Store(config)
+	return nil
+}
+
+// Reset 关闭已加载的配置并清除加载错误, 下次 Get 重新加载, 用于测试之间隔离配置源
+func (c *LazyConfig[T]) Reset() {
+	c.loading.Lock()
+	defer c.loading.Unlock()
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if config := c.config.Swap(nil); config != nil {
+		config.Close()
+	}
+	c.err, c.failedAt = nil, time.Time{}
+}
+
+func (c *LazyConfig[T]) source() (*nacos_config.NacosConfig[T], error) {
+	source, err := LoadConfigSource()
+	if err != nil {
+		return nil, err
+	}
+	return GetConfigAndListenFromSource[T](source, c.dataId, c.opts...)
+}
+
+// lazyLoader: 不区分配置类型的 LazyConfig
+type lazyLoader interface {
+	DataId() string
+	Load() error
+}
+
+type lazyRegistry struct {
+	lock    sync.Mutex
+	loaders []lazyLoader
+}
+
+var lazyConfigs = &lazyRegistry{}
+
+func (r *lazyRegistry) register(loader lazyLoader) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.loaders = append(r.loaders, loader)
+}
+
+// selected: 按 dataId 选择已注册的配置
+func (r *lazyRegistry) selected(dataIds []string) ([]lazyLoader, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var (
+		ret  []lazyLoader
+		errs []error
+	)
+	for _, dataId := range dataIds {
+		found := false
+		for _, loader := range r.loaders {
+			if loader.DataId() == dataId {
+				ret, found = append(ret, loader), true
+			}
+		}
+		if !found {
+			errs = append(errs, fmt.Errorf("[go-helper] config %s is not registered", dataId))
+		}
+	}
+	return ret, errors.Join(errs...)
+}
+
+// InitOptions: InitConfigs 的选项
+type InitOptions struct {
+	// Configs 需要加载的配置 dataId, 必须是通过 NewLazyConfig 注册的配置; 为空时不加载任何配置,
+	// 引入某个包(例如 middleware)就会注册其使用的配置, 服务不一定拥有这些配置, 因此不默认加载全部
+	Configs []string
+	// Retries 加载失败后的重试次数, 默认 DefaultInitRetries, 小于 0 时不重试
+	Retries int
+	// Backoff 首次重试的间隔, 之后每次翻倍, 默认 DefaultInitBackoff
+	Backoff time.Duration
+}
+
+// InitConfigs 在服务启动时加载 opts.Configs 指定的配置, 失败时按选项重试, 直到全部加载成功、
+// 重试次数用完或 ctx 结束; ctx 没有 deadline 时最多等待 DefaultInitTimeout
+//
+// 加载的配置通过 RequireConfigs 声明为依赖, 未加载成功时 CheckConfigHealth 返回错误;
+// 返回的错误包含每个加载失败的配置, 服务可以据此退出, 或忽略错误使用各组件的降级行为启动
+func InitConfigs(ctx context.Context, opts InitOptions) error {
+	if opts.Retries == 0 {
+		opts.Retries = DefaultInitRetries
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = DefaultInitBackoff
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultInitTimeout)
+		defer cancel()
+	}
+
+	pending, err := lazyConfigs.selected(opts.Configs)
+	if err != nil {
+		return err
+	}
+	dataIds := make([]string, 0, len(pending))
+	for _, loader := range pending {
+		dataIds = append(dataIds, loader.DataId())
+	}
+	sort.Strings(dataIds)
+	RequireConfigs(dataIds...)
+
+	backoff := opts.Backoff
+	for attempt := 0; ; attempt++ {
+		failed, errs := loadAll(ctx, pending)
+		if len(failed) == 0 {
+			return nil
+		}
+		pending = failed
+
+		if attempt >= opts.Retries || ctx.Err() != nil {
+			return errors.Join(errs...)
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(errs...)
+		}
+		backoff *= 2
+	}
+}
+
+// loadAll: 并发加载配置, 返回加载失败的配置及错误; ctx 结束时仍在加载的配置视为失败, 在后台继续加载
+func loadAll(ctx context.Context, loaders []lazyLoader) ([]lazyLoader, []error) {
+	type result struct {
+		index int
+		err   error
+	}
+	results := make(chan result, len(loaders))
+	for i, loader := range loaders {
+		go func(i int, loader lazyLoader) {
+			results <- result{index: i, err: loader.Load()}
+		}(i, loader)
+	}
+
+	var (
+		failed []lazyLoader
+		errs   []error
+		done   = make([]bool, len(loaders))
+	)
+	for range loaders {
+		select {
+		case r := <-results:
+			done[r.index] = true
+			if r.err != nil {
+				failed, errs = append(failed, loaders[r.index]), append(errs, r.err)
+			}
+		case <-ctx.Done():
+			for i, loader := range loaders {
+				if !done[i] {
+					failed = append(failed, loader)
+					errs = append(errs, fmt.Errorf("[go-helper] load config %s failed: %w", loader.DataId(), ctx.Err()))
+				}
+			}
+			return failed, errs
+		}
+	}
+	return failed, errs
+}
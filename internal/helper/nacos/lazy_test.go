@@ -0,0 +1,153 @@
+package nacos
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	nacos_config "github.com/everfir/go-helpers/define/config"
+	"github.com/everfir/go-helpers/env"
+	"github.com/zeebo/assert"
+)
+
+// blockingSource: Get 阻塞到 release 关闭, 模拟不可达的配置源
+type blockingSource struct {
+	*MemorySource
+	release chan struct{}
+}
+
+func (s blockingSource) Get(dataId, group string) (string, error) {
+	<-s.release
+	return s.MemorySource.Get(dataId, group)
+}
+
+func TestLazyConfig(t *testing.T) {
+	oldConfigs, oldLazy := configs, lazyConfigs
+	configs = &configRegistry{trackers: map[uint64]*configTracker{}, required: map[string]struct{}{}}
+	lazyConfigs = &lazyRegistry{}
+	source := NewMemorySource()
+	SetConfigSource(source)
+	defer func() {
+		configs, lazyConfigs = oldConfigs, oldLazy
+		SetConfigSource(nil)
+	}()
+
+	cfg := NewLazyConfig[map[string]string]("lazy.json")
+	assert.Equal(t, cfg.DataId(), "lazy.json")
+
+	// get: 等待后台加载结束
+	get := func() (*nacos_config.NacosConfig[map[string]string], error) {
+		for {
+			config, err := cfg.Get()
+			if !errors.Is(err, ErrConfigLoading) {
+				return config, err
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	// 首次 Get 在后台加载, 加载失败时返回错误, 重试间隔内不重新加载
+	_, err := cfg.Get()
+	assert.True(t, errors.Is(err, ErrConfigLoading))
+	_, err = get()
+	assert.Error(t, err)
+	assert.NoError(t, source.Publish(PublishParam{DataId: "lazy.json", Group: env.Env(), Content: `{"a":"1"}`}))
+	_, err2 := cfg.Get()
+	assert.Equal(t, err2, err)
+
+	// Load 忽略重试间隔
+	assert.NoError(t, cfg.Load())
+	config, err := cfg.Get()
+	assert.NoError(t, err)
+	v, _ := config.Get()
+	assert.Equal(t, v["a"], "1")
+
+	// 已加载后一直使用同一个配置
+	again, err := cfg.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, again, config)
+
+	// Reset 后重新加载, 不重复注册
+	assert.NoError(t, source.Publish(PublishParam{DataId: "lazy.json", Group: env.Env(), Content: `{"a":"2"}`}))
+	cfg.Reset()
+	reloaded, err := get()
+	assert.NoError(t, err)
+	assert.True(t, reloaded != config)
+	v, _ = reloaded.Get()
+	assert.Equal(t, v["a"], "2")
+	assert.Equal(t, len(lazyConfigs.loaders), 1)
+
+	// 配置源阻塞时 Get 不等待加载
+	release := make(chan struct{})
+	SetConfigSource(blockingSource{MemorySource: source, release: release})
+	cfg.Reset()
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		_, err = cfg.Get()
+		assert.True(t, errors.Is(err, ErrConfigLoading))
+	}
+	assert.True(t, time.Since(start) < time.Second)
+	close(release)
+	_, err = get()
+	assert.NoError(t, err)
+}
+
+func TestInitConfigs(t *testing.T) {
+	oldConfigs, oldLazy := configs, lazyConfigs
+	configs = &configRegistry{trackers: map[uint64]*configTracker{}, required: map[string]struct{}{}}
+	lazyConfigs = &lazyRegistry{}
+	source := NewMemorySource()
+	SetConfigSource(source)
+	defer func() {
+		configs, lazyConfigs = oldConfigs, oldLazy
+		SetConfigSource(nil)
+	}()
+
+	ready := NewLazyConfig[map[string]string]("ready.json")
+	later := NewLazyConfig[map[string]string]("later.json")
+	NewLazyConfig[map[string]string]("missing.json")
+	assert.NoError(t, source.Publish(PublishParam{DataId: "ready.json", Group: env.Env(), Content: `{}`}))
+
+	// 未注册的配置
+	err := InitConfigs(context.Background(), InitOptions{Configs: []string{"unknown.json"}})
+	assert.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "unknown.json"))
+
+	// 重试期间配置发布后加载成功
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = source.Publish(PublishParam{DataId: "later.json", Group: env.Env(), Content: `{"b":"2"}`})
+	}()
+	err = InitConfigs(context.Background(), InitOptions{
+		Configs: []string{"ready.json", "later.json"},
+		Retries: 10,
+		Backoff: 20 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+	_, err = ready.Get()
+	assert.NoError(t, err)
+	config, err := later.Get()
+	assert.NoError(t, err)
+	v, _ := config.Get()
+	assert.Equal(t, v["b"], "2")
+	assert.NoError(t, CheckConfigHealth())
+
+	// 没有指定配置时不加载
+	assert.NoError(t, InitConfigs(context.Background(), InitOptions{}))
+	assert.NoError(t, CheckConfigHealth())
+
+	// 超时后返回加载失败的配置, 并声明为依赖
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err = InitConfigs(ctx, InitOptions{
+		Configs: []string{"ready.json", "later.json", "missing.json"},
+		Retries: 100,
+		Backoff: 10 * time.Millisecond,
+	})
+	assert.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "missing.json"))
+	assert.False(t, strings.Contains(err.Error(), "ready.json"))
+	assert.Error(t, CheckConfigHealth())
+}
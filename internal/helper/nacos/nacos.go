@@ -2,72 +2,93 @@ package nacos
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	nacos_config "github.com/everfir/go-helpers/define/config"
 	"github.com/everfir/go-helpers/env"
+	"github.com/everfir/go-helpers/internal/helper/codec"
+	"github.com/everfir/go-helpers/internal/helper/secret"
 	"github.com/everfir/go-helpers/internal/structs"
 	internal_config "github.com/everfir/go-helpers/internal/structs/config"
 	"github.com/everfir/logger-go"
 	"github.com/everfir/logger-go/structs/field"
-	"github.com/nacos-group/nacos-sdk-go/clients"
 	"github.com/nacos-group/nacos-sdk-go/clients/config_client"
-	"github.com/nacos-group/nacos-sdk-go/common/constant"
-	"github.com/nacos-group/nacos-sdk-go/vo"
 )
 
-var GetNacosClient func() config_client.IConfigClient = sync.OnceValue(func() config_client.IConfigClient {
+// clientOptionsFromEnv: 进程内只解析一次环境变量, GetNacosClient 与 Open API 使用同一份配置
+var clientOptionsFromEnv = sync.OnceValues(ClientOptionsFromEnv)
+
+var nacosClient = struct {
+	lock   sync.Mutex
+	client config_client.IConfigClient
+}{}
+
+// GetNacosClient 获取默认的 Nacos 配置客户端, 创建失败时 panic, 需要处理错误时使用 LoadNacosClient
+var GetNacosClient func() config_client.IConfigClient = func() config_client.IConfigClient {
+	client, err := LoadNacosClient()
+	if err != nil {
+		panic(err.Error())
+	}
+	return client
+}
+
+// LoadNacosClient 按 ClientOptionsFromEnv 创建默认的 Nacos 配置客户端, 创建成功后复用, 失败时下次调用重新创建
+func LoadNacosClient() (config_client.IConfigClient, error) {
+	nacosClient.lock.Lock()
+	defer nacosClient.lock.Unlock()
+
+	if nacosClient.client != nil {
+		return nacosClient.client, nil
+	}
+
 	var ctx = context.Background()
 
-	namespace := Namespace()
-	ipAddr := NacosIp()
-	username, passward := AuthInfo()
-	logger.Info(
-		ctx,
-		"[go-helper] GetNacosClient",
-		field.String("namespace", namespace),
-		field.String("ipAddr", ipAddr),
-		field.String("username", username),
-		field.String("passward", passward),
-	)
+	opts, err := clientOptionsFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("[go-helper] Init nacos client failed: %w", err)
+	}
 
-	cc := constant.ClientConfig{
-		NamespaceId:         namespace,
-		TimeoutMs:           60000,
-		NotLoadCacheAtStart: true,
-		LogDir:              "",
-		CacheDir:            "",
-		LogLevel:            "error",
-		Username:            username,
-		Password:            passward,
+	credential, err := opts.Credential()
+	if err != nil {
+		return nil, fmt.Errorf("[go-helper] Init nacos client failed: %w", err)
 	}
 
-	sc := []constant.ServerConfig{
-		{
-			IpAddr: ipAddr,
-			Port:   8848,
-		},
+	servers := make([]string, 0, len(opts.Servers))
+	for _, addr := range opts.Servers {
+		servers = append(servers, addr.String())
 	}
+	logger.Info(
+		ctx,
+		"[go-helper] GetNacosClient",
+		field.String("namespace", opts.Namespace),
+		field.Any("servers", servers),
+		field.String("timeout", opts.Timeout.String()),
+		field.Bool("tls", opts.TLS.Enable),
+		field.String("username", credential.Username),
+		field.String("password", Redact(credential.Password)),
+	)
 
-	configClient, err := clients.CreateConfigClient(map[string]interface{}{
-		"clientConfig":  cc,
-		"serverConfigs": sc,
-	})
+	configClient, err := NewConfigClient(opts)
 	if err != nil {
-		panic(fmt.Sprintf("[go-helper] Init nacos client failed: %v ip:%s", err, ipAddr))
+		return nil, fmt.Errorf("[go-helper] Init nacos client failed: %w servers:%v", err, servers)
 	}
 
 	logger.Info(ctx, "[go-helper] GetNacosClient success")
-	return configClient
-})
+	nacosClient.client = configClient
+	return configClient, nil
+}
 
 // GetConfigAndListen 从 Nacos 获取配置并监听配置变更
 //
 // 参数:
 //   - client: Nacos 配置客户端，用于与 Nacos 服务器进行交互
 //   - dataId: 配置的唯一标识，用于指定要获取的配置项
+//   - opts: 可选参数，如 WithFormat 指定配置格式，默认根据 dataId 扩展名推断
 //
 // 返回值:
 //   - config: 包含配置数据的 NacosConfig 对象，
@@ -91,16 +112,38 @@ var GetNacosClient func() config_client.IConfigClient = sync.OnceValue(func() co
 //	if err != nil {
 //	    log.Fatal(err)
 //	}
-func GetConfigAndListen[T any](client config_client.IConfigClient, dataId string) (config *nacos_config.NacosConfig[T], err error) {
+func GetConfigAndListen[T any](client config_client.IConfigClient, dataId string, opts ...Option) (config *nacos_config.NacosConfig[T], err error) {
+	return GetConfigAndListenFromSource[T](NewNacosSource(client), dataId, opts...)
+}
+
+// GetConfigAndListenFromSource 从指定配置源获取配置并监听配置变更，行为与 GetConfigAndListen 一致
+func GetConfigAndListenFromSource[T any](source ConfigSource, dataId string, opts ...Option) (config *nacos_config.NacosConfig[T], err error) {
+	o := newOptions(dataId, opts...)
+	if o.overlay {
+		return GetOverlayConfigAndListenFromSource[T](source, dataId, opts...)
+	}
+	if o.gray {
+		return GetConfigAndListenWithGrayFromSource[T](source, dataId, opts...)
+	}
+
+	tracker := configs.track(dataId, o.format, ConfigMode_Plain)
+	defer func() { tracker.done(err) }()
+
+	if err = o.registerDefault(source, dataId, env.Env()); err != nil {
+		return
+	}
+
 	var conf *internal_config.Config[T]
-	conf, err = getConfigAndListen[T](client, dataId, env.Env())
+	conf, err = getConfigAndListen[T](source, dataId, env.Env(), o.format, tracker)
 	if err != nil {
 		return
 	}
 
 	data := make(map[string]*internal_config.Config[T])
 	data[env.Env()] = conf
-	return nacos_config.NewNacosConfig[T](data), nil
+	config = nacos_config.NewNacosConfig[T](data)
+	config.OnClose(tracker.close)
+	return config, nil
 }
 
 // GetConfigAndListenWithGray 从 Nacos 获取配置并监听配置变更，
@@ -109,12 +152,18 @@ func GetConfigAndListen[T any](client config_client.IConfigClient, dataId string
 // 参数:
 //   - client: Nacos 配置客户端，用于与 Nacos 服务器进行交互
 //   - dataId: 配置的唯一标识，用于指定要获取的配置项
+//   - opts: 可选参数，如 WithFormat 指定配置格式，默认根据 dataId 扩展名推断
 //
 // 返回值:
 //   - config: 包含所有灰度配置数据的 NacosConfig 对象，
 //     该对象的 Data 字段会根据环境存储配置
 //   - err: 错误信息，如果获取配置或监听过程中发生错误，则返回相应的错误信息
 //
+// 分组发现:
+//   - 启动后每隔 DefaultDiscoveryInterval（可通过 WithDiscoveryInterval 调整）重新搜索分组，
+//     新发布的灰度分组（如 production_c）会自动加载并监听，被删除的分组会取消监听并移除，
+//     对应分组上注册的监听器会收到变更通知；调用 NacosConfig.Close 停止发现
+//
 // 使用场景:
 //   - 当需要根据灰度配置获取配置时，可以使用该函数
 //   - 适用于动态配置管理的场景，例如微服务架构中的灰度发布
@@ -135,18 +184,30 @@ func GetConfigAndListen[T any](client config_client.IConfigClient, dataId string
 func GetConfigAndListenWithGray[T any](
 	client config_client.IConfigClient,
 	dataId string,
+	opts ...Option,
+) (config *nacos_config.NacosConfig[T], err error) {
+	return GetConfigAndListenWithGrayFromSource[T](NewNacosSource(client), dataId, opts...)
+}
+
+// GetConfigAndListenWithGrayFromSource 从指定配置源获取配置并监听配置变更，
+// 行为与 GetConfigAndListenWithGray 一致
+func GetConfigAndListenWithGrayFromSource[T any](
+	source ConfigSource,
+	dataId string,
+	opts ...Option,
 ) (config *nacos_config.NacosConfig[T], err error) {
+	o := newOptions(dataId, opts...)
 	logger.Debug(context.Background(), "GetConfigAndListenWithGray", field.String("dataId", dataId))
 
-	// 搜索该 DataId 下所有的 Group 配置
-	searchConfigParam := vo.SearchConfigParam{
-		Search: "accurate", // 精确搜索
-		DataId: dataId,     // 指定要查询的 DataId
-		// 这里可以添加其他参数，例如 Namespace，如果需要的话
+	tracker := configs.track(dataId, o.format, ConfigMode_Gray)
+	defer func() { tracker.done(err) }()
+
+	if err = o.registerDefault(source, dataId, env.Env()); err != nil {
+		return nil, err
 	}
 
-	// 执行搜索以获取所有相关配置
-	searchConfig, err := client.SearchConfig(searchConfigParam)
+	// 搜索该 DataId 下所有的 Group 配置
+	groups, err := source.SearchGroups(dataId)
 	if err != nil {
 		// 处理错误，记录日志并返回
 		return nil, fmt.Errorf("[go-helper] Search config failed, err: %w", err)
@@ -154,35 +215,150 @@ func GetConfigAndListenWithGray[T any](
 
 	// 将搜索到的配置按 Group 分组
 	var group2Config = make(map[string]struct{})
-	for _, config := range searchConfig.PageItems {
-		group2Config[config.Group] = struct{}{}
+	for _, group := range groups {
+		group2Config[group] = struct{}{}
 	}
 	logger.Debug(context.Background(), "group2Config", field.Any("group2Config", group2Config))
 
 	// 获取所有的 DataId 配置
 	var data = make(map[string]*internal_config.Config[T])
 	for group, _ := range group2Config {
-		logger.Debug(context.Background(), "getConfigAndListen", field.String("dataId", dataId), field.String("group", group))
 		// 获取配置并监听配置变更
 		var conf *internal_config.Config[T]
-		conf, err = getConfigAndListen[T](client, dataId, group)
+		conf, err = getGroupConfigAndListen[T](source, dataId, group, o.format, tracker)
 		if err != nil {
-			return nil, fmt.Errorf("[go-helper] Get config and listen failed for group %s, err: %w", group, err)
+			return nil, err
 		}
-		logger.Info(
-			context.Background(),
-			"getConfigAndListen",
-			field.String("dataId", dataId),
-			field.String("group", group),
-			field.Any("conf", conf.Get()),
-		)
 
 		// 将获取到的配置存储到数据映射中
 		data[group] = conf
 	}
 
-	// 返回包含所有灰度配置的 NacosConfig 对象
-	return nacos_config.NewNacosConfig[T](data), nil
+	// 返回包含所有灰度配置的 NacosConfig 对象，并定期发现新增、删除的灰度分组
+	config = nacos_config.NewNacosConfig[T](data)
+	config.OnClose(tracker.close)
+	if o.discovery > 0 {
+		stop := make(chan struct{})
+		config.OnClose(func() { close(stop) })
+		go discoverGroups[T](config, source, dataId, o, tracker, stop)
+	}
+	return config, nil
+}
+
+// getGroupConfigAndListen 获取灰度分组的配置并监听配置变更
+func getGroupConfigAndListen[T any](
+	source ConfigSource,
+	dataId string,
+	group string,
+	format codec.Format,
+	tracker *configTracker,
+) (*internal_config.Config[T], error) {
+	logger.Debug(context.Background(), "getConfigAndListen", field.String("dataId", dataId), field.String("group", group))
+	conf, err := getConfigAndListen[T](source, dataId, group, format, tracker)
+	if err != nil {
+		return nil, fmt.Errorf("[go-helper] Get config and listen failed for group %s, err: %w", group, err)
+	}
+	// 配置已解密, 只记录版本, 不记录内容
+	logger.Info(
+		context.Background(),
+		"getConfigAndListen",
+		field.String("dataId", dataId),
+		field.String("group", group),
+		field.String("md5", conf.Metadata.Md5),
+	)
+	return conf, nil
+}
+
+// discoverGroups 定期搜索 dataId 下的分组：
+//   - 新增的分组加载配置并监听，挂载到 config 上
+//   - 删除的分组取消监听（配置源支持 Unwatcher 时）并从 config 中移除，当前环境的默认分组不会被移除
+func discoverGroups[T any](
+	config *nacos_config.NacosConfig[T],
+	source ConfigSource,
+	dataId string,
+	o *options,
+	tracker *configTracker,
+	stop <-chan struct{},
+) {
+	ticker := time.NewTicker(o.discovery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		groups, err := source.SearchGroups(dataId)
+		if err != nil {
+			logger.Warn(
+				context.TODO(),
+				"[go-helper] discover config groups failed",
+				field.String("dataId", dataId),
+				field.String("err", err.Error()),
+			)
+			continue
+		}
+
+		current := map[string]struct{}{}
+		for _, group := range config.Groups() {
+			current[group] = struct{}{}
+		}
+
+		found := map[string]struct{}{}
+		for _, group := range groups {
+			found[group] = struct{}{}
+			if _, exist := current[group]; exist {
+				continue
+			}
+
+			conf, err := getGroupConfigAndListen[T](source, dataId, group, o.format, tracker)
+			if err != nil {
+				logger.Warn(
+					context.TODO(),
+					"[go-helper] load discovered config group failed",
+					field.String("dataId", dataId),
+					field.String("group", group),
+					field.String("err", err.Error()),
+				)
+				continue
+			}
+			config.AddGroup(group, conf)
+			logger.Info(
+				context.TODO(),
+				"[go-helper] config group added",
+				field.String("dataId", dataId),
+				field.String("group", group),
+			)
+		}
+
+		for group := range current {
+			if _, exist := found[group]; exist || group == env.Env() {
+				continue
+			}
+
+			if u, ok := source.(Unwatcher); ok {
+				if err := u.Unwatch(dataId, group); err != nil {
+					logger.Warn(
+						context.TODO(),
+						"[go-helper] unwatch config group failed",
+						field.String("dataId", dataId),
+						field.String("group", group),
+						field.String("err", err.Error()),
+					)
+				}
+			}
+			config.RemoveGroup(group)
+			tracker.remove(group)
+			logger.Info(
+				context.TODO(),
+				"[go-helper] config group removed",
+				field.String("dataId", dataId),
+				field.String("group", group),
+			)
+		}
+	}
 }
 
 // getConfigAndListen 从 Nacos 获取配置并监听配置变更
@@ -190,9 +366,11 @@ func GetConfigAndListenWithGray[T any](
 // T: 配置结构体类型，表示要解析的配置数据类型
 //
 // 参数:
-//   - client: Nacos 配置客户端，用于与 Nacos 服务器进行交互
+//   - source: 配置源，用于获取与监听配置
 //   - dataId: 配置的唯一标识，用于指定要获取的配置项
 //   - group: 配置所属的分组，用于区分不同的配置组
+//   - format: 配置格式，决定使用的解码器
+//   - tracker: 记录配置生效与被拒绝的变更，见 ConfigStatuses
 //
 // 返回值:
 //   - config: 包含配置数据的 Config 对象，
@@ -203,15 +381,14 @@ func GetConfigAndListenWithGray[T any](
 //   - 当需要从 Nacos 获取特定配置并实时监听配置变更时，可以使用该函数
 //   - 适用于动态配置管理的场景，例如微服务架构中的配置管理
 func getConfigAndListen[T any](
-	client config_client.IConfigClient,
+	source ConfigSource,
 	dataId string,
 	group string,
+	format codec.Format,
+	tracker *configTracker,
 ) (config *internal_config.Config[T], err error) {
-	// 从 Nacos 获取配置
-	cfg, err := client.GetConfig(vo.ConfigParam{
-		DataId: dataId,
-		Group:  group,
-	})
+	// 从配置源获取配置
+	cfg, err := source.Get(dataId, group)
 	logger.Debug(
 		context.Background(),
 		"getConfigAndListen",
@@ -226,71 +403,49 @@ func getConfigAndListen[T any](
 		return
 	}
 
-	// 创建新的配置对象并解析 JSON
+	// 解析配置, 补全默认值、解密并校验
 	config = internal_config.NewConfig[T]()
-	err = json.Unmarshal([]byte(cfg), config.Data)
-	if err != nil {
-		return nil, fmt.Errorf("[go-helper] JSON unmarshal failed: %w", err)
-	}
-
-	// 如果配置结构体实现了 Validator 接口，执行验证
-	if v, ok := any(config.Data).(structs.Validator); ok {
-		if e := v.Validate(); e != nil {
-			err = fmt.Errorf("[go-helper] Validate config failed, config:%w", e)
-			return
-		}
-	}
-
-	// 如果配置结构体实现了 Formatter 接口，执行格式化
-	if v, ok := any(config.Data).(structs.Formatter); ok {
-		v.Format()
+	if config.Data, err = decodeConfig[T](format, cfg); err != nil {
+		return nil, fmt.Errorf("[go-helper] %w", err)
 	}
+	config.Metadata = internal_config.Metadata{Group: group, DataId: dataId, Md5: contentMd5(cfg)}
+	config.Content = cfg
+	snapshot(source, dataId, group, cfg)
+	tracker.update(group, config.Metadata.Md5, cfg)
 
 	// 监听配置变更
-	err = client.ListenConfig(vo.ConfigParam{
-		DataId: dataId,
-		Group:  group,
-		OnChange: func(namespace, group, dataId, data string) {
-			// 配置变更时，解析新的配置
-			conf := new(T)
-			err := json.Unmarshal([]byte(data), conf)
-			if err != nil {
-				logger.Warn(
-					context.TODO(),
-					"[go-helper] ConfigOnChange Unmarshal config failed",
-					field.String("err", err.Error()),
-				)
-				return
-			}
-
-			// 如果配置结构体实现了 Validator 接口，执行验证
-			if v, ok := any(conf).(structs.Validator); ok {
-				if e := v.Validate(); e != nil {
-					logger.Warn(
-						context.TODO(),
-						"[go-helper] Validate config failed",
-						field.String("err", e.Error()),
-					)
-					return
-				}
-			}
-
-			// 如果配置结构体实现了 Formatter 接口，执行格式化
-			if v, ok := any(conf).(structs.Formatter); ok {
-				v.Format()
-			}
-
-			// 更新配置并记录日志
-			config.Set(conf)
-			logger.Info(
+	err = source.Watch(dataId, group, func(change ConfigChange) {
+		// 配置变更时，解析新的配置，失败时保留旧配置
+		conf, err := decodeConfig[T](format, change.Content)
+		if err != nil {
+			tracker.reject(group, err)
+			logger.Warn(
 				context.TODO(),
-				"[go-helper] nacos config changed",
-				field.String("namespace", namespace),
-				field.String("group", group),
+				"[go-helper] ConfigOnChange load config failed",
 				field.String("dataId", dataId),
-				field.String("data", data),
+				field.String("group", group),
+				field.String("err", err.Error()),
 			)
-		},
+			return
+		}
+
+		// 更新配置并记录日志
+		snapshot(source, dataId, group, change.Content)
+		tracker.update(group, contentMd5(change.Content), change.Content)
+		config.Update(conf, internal_config.Metadata{
+			Namespace: change.Namespace,
+			Group:     change.Group,
+			DataId:    change.DataId,
+			Md5:       contentMd5(change.Content),
+		}, change.Content)
+		logger.Info(
+			context.TODO(),
+			"[go-helper] nacos config changed",
+			field.String("namespace", change.Namespace),
+			field.String("group", change.Group),
+			field.String("dataId", change.DataId),
+			field.String("data", change.Content),
+		)
 	})
 
 	// 如果监听配置时发生错误，记录警告日志
@@ -307,3 +462,251 @@ func getConfigAndListen[T any](
 
 	return config, nil
 }
+
+// decodeConfig 按格式解析配置, 依次补全默认值(struct tag 与环境变量覆盖)、解密、执行 Validator 与 Formatter,
+// 加载、监听变更、分层合并与 PublishCAS 发布前的校验共用
+func decodeConfig[T any](format codec.Format, content string) (*T, error) {
+	data := new(T)
+	if err := codec.Decode(format, content, data); err != nil {
+		return nil, fmt.Errorf("%s unmarshal failed: %w", format, err)
+	}
+	// 按配置内容判断字段是否缺失, 显式配置的 false、0 不使用默认值; text 格式没有结构
+	var tree any
+	if format != codec.Format_Text {
+		tree, _ = codec.DecodeTree(format, content)
+	}
+	if err := structs.ApplyMissingDefaults(data, tree); err != nil {
+		return nil, fmt.Errorf("apply config defaults failed: %w", err)
+	}
+	// 解密 enc: 前缀或 encrypted tag 标记的字段
+	if err := secret.DecryptFields(data); err != nil {
+		return nil, fmt.Errorf("decrypt config failed: %w", err)
+	}
+	if v, ok := any(data).(structs.Validator); ok {
+		if err := v.Validate(); err != nil {
+			return nil, fmt.Errorf("Validate config failed, config:%w", err)
+		}
+	}
+	if v, ok := any(data).(structs.Formatter); ok {
+		v.Format()
+	}
+	return data, nil
+}
+
+// snapshot 配置通过校验后写入本地快照，作为配置源不可用时的降级数据
+func snapshot(source ConfigSource, dataId, group, content string) {
+	if s, ok := source.(snapshotter); ok {
+		s.Snapshot(dataId, group, content)
+	}
+}
+
+// contentMd5 与 Nacos 一致，配置版本取原始内容的 md5
+func contentMd5(content string) string {
+	sum := md5.Sum([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// Publish 按配置格式序列化 data 并发布到配置源
+func Publish[T any](source ConfigSource, dataId string, data T, opts ...Option) (err error) {
+	o := newOptions(dataId, opts...)
+
+	content, err := codec.Encode(o.format, data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	// 发布配置到配置源
+	err = source.Publish(PublishParam{
+		DataId:  dataId,
+		Group:   o.group(),
+		Content: content,               // 配置内容
+		Type:    o.format.ConfigType(), // 配置类型
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish config: %w", err)
+	}
+	return nil
+}
+
+// CASResult: PublishCAS 的结果, 用于审计
+type CASResult struct {
+	DataId   string
+	Group    string
+	Content  string // 最终发布的内容
+	Md5      string // 最终发布内容的 md5, 即配置版本
+	PrevMd5  string // 修改前的配置版本
+	Attempts int    // 发布尝试次数
+}
+
+// Read 读取配置的当前值与版本(md5), 不注册监听; 内容为空视为配置不存在, 返回零值与空版本
+//
+// 返回的是配置的原始值(不补全默认值、不解密), 用于修改后重新发布
+func Read[T any](source ConfigSource, dataId string, opts ...Option) (data T, md5 string, err error) {
+	o := newOptions(dataId, opts...)
+
+	current, err := source.Get(dataId, o.group())
+	if err != nil {
+		return data, "", fmt.Errorf("[go-helper] get config failed: %w", err)
+	}
+	if current == "" {
+		return data, "", nil
+	}
+	if err = codec.Decode(o.format, current, &data); err != nil {
+		return data, "", fmt.Errorf("[go-helper] %s unmarshal failed: %w", o.format, err)
+	}
+	return data, contentMd5(current), nil
+}
+
+// PublishCAS 读取配置、修改后以 casMd5 发布, 避免并发修改互相覆盖
+//
+// 流程:
+//  1. 读取配置当前内容与 md5, 按配置格式解析为 T 的原始值(配置不存在时为零值)
+//  2. 调用 mutate 修改配置, mutate 返回错误时放弃发布
+//  3. 按与加载配置相同的流程(补全默认值、解密、Validator)校验修改后的内容, 发布的仍是原始值
+//  4. 以读取时的 md5 作为 casMd5 发布, 冲突(ErrCASConflict)时从第 1 步重试, 最多重试 WithCASRetries 次
+//
+// 配置源需要实现 CASPublisher, 否则返回 ErrCASUnsupported。配置不存在时要求配置源原子地创建,
+// 不支持时(例如 Nacos Open API)返回 ErrCASCreateUnsupported, 需要先通过 Publish 创建配置。
+func PublishCAS[T any](source ConfigSource, dataId string, mutate func(data *T) error, opts ...Option) (result CASResult, err error) {
+	o := newOptions(dataId, opts...)
+	return publishCAS[T](source, dataId, o, func() (prevMd5, content string, err error) {
+		// 读取当前配置
+		data := new(T)
+		*data, prevMd5, err = Read[T](source, dataId, opts...)
+		if err != nil {
+			return "", "", err
+		}
+
+		if err = mutate(data); err != nil {
+			return "", "", err
+		}
+
+		content, err = codec.Encode(o.format, data)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to marshal config: %w", err)
+		}
+		return prevMd5, content, nil
+	})
+}
+
+// publishCAS: 调用 next 读取当前版本并生成新内容, 按加载流程校验后以 casMd5 发布, 冲突时从 next 重试
+func publishCAS[T any](source ConfigSource, dataId string, o *options, next func() (prevMd5, content string, err error)) (result CASResult, err error) {
+	publisher, ok := source.(CASPublisher)
+	if !ok {
+		return result, ErrCASUnsupported
+	}
+
+	result = CASResult{DataId: dataId, Group: o.group()}
+	for result.Attempts < o.casRetries+1 {
+		result.Attempts++
+
+		var content string
+		result.PrevMd5, content, err = next()
+		if err != nil {
+			return result, err
+		}
+
+		// 与加载配置使用相同的流程校验
+		if _, err = decodeConfig[T](o.format, content); err != nil {
+			return result, fmt.Errorf("[go-helper] %w", err)
+		}
+
+		err = publisher.PublishCAS(PublishParam{
+			DataId:  dataId,
+			Group:   result.Group,
+			Content: content,
+			Type:    o.format.ConfigType(),
+		}, result.PrevMd5)
+		if errors.Is(err, ErrCASConflict) {
+			logger.Warn(
+				context.TODO(),
+				"[go-helper] publish config conflict, retry",
+				field.String("dataId", dataId),
+				field.String("group", result.Group),
+				field.Any("attempts", result.Attempts),
+			)
+			continue
+		}
+		if err != nil {
+			return result, fmt.Errorf("failed to publish config: %w", err)
+		}
+
+		result.Content = content
+		result.Md5 = contentMd5(content)
+		logger.Info(
+			context.TODO(),
+			"[go-helper] publish config with cas",
+			field.String("dataId", dataId),
+			field.String("group", result.Group),
+			field.String("prevMd5", result.PrevMd5),
+			field.String("md5", result.Md5),
+		)
+		return result, nil
+	}
+	return result, ErrCASConflict
+}
+
+// ListHistory 分页列出配置的历史版本(时间倒序, pageNo 从 1 开始), 分组由 WithTrafficGroup 指定
+func ListHistory(source ConfigSource, dataId string, pageNo, pageSize int, opts ...Option) ([]ConfigHistory, error) {
+	o := newOptions(dataId, opts...)
+	h, ok := source.(HistorySource)
+	if !ok {
+		return nil, ErrHistoryUnsupported
+	}
+	return h.ListHistory(dataId, o.group(), pageNo, pageSize)
+}
+
+// GetVersion 获取配置的指定历史版本并按配置格式解析为 T
+func GetVersion[T any](source ConfigSource, dataId string, id string, opts ...Option) (data T, history ConfigHistory, err error) {
+	o := newOptions(dataId, opts...)
+	h, ok := source.(HistorySource)
+	if !ok {
+		return data, history, ErrHistoryUnsupported
+	}
+
+	history, err = h.GetHistory(dataId, o.group(), id)
+	if err != nil {
+		return data, history, fmt.Errorf("[go-helper] get config history failed: %w", err)
+	}
+	if err = codec.Decode(o.format, history.Content, &data); err != nil {
+		return data, history, fmt.Errorf("[go-helper] %s unmarshal failed: %w", o.format, err)
+	}
+	return data, history, nil
+}
+
+// Rollback 将配置回滚到指定历史版本
+//
+// 历史版本的内容按 T 校验(与加载配置相同的流程)后原样以 casMd5 发布, 保留 T 中没有的字段、注释与格式,
+// 冲突检测与重试同 PublishCAS。
+func Rollback[T any](source ConfigSource, dataId string, id string, opts ...Option) (CASResult, error) {
+	_, history, err := GetVersion[T](source, dataId, id, opts...)
+	if err != nil {
+		return CASResult{}, err
+	}
+
+	o := newOptions(dataId, opts...)
+	result, err := publishCAS[T](source, dataId, o, func() (prevMd5, content string, err error) {
+		current, err := source.Get(dataId, o.group())
+		if err != nil {
+			return "", "", fmt.Errorf("[go-helper] get config failed: %w", err)
+		}
+		if current != "" {
+			prevMd5 = contentMd5(current)
+		}
+		return prevMd5, history.Content, nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	logger.Info(
+		context.TODO(),
+		"[go-helper] rollback config",
+		field.String("dataId", dataId),
+		field.String("group", result.Group),
+		field.String("version", history.Id),
+		field.String("versionMd5", history.Md5),
+		field.String("md5", result.Md5),
+	)
+	return result, nil
+}
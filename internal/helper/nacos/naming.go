@@ -0,0 +1,63 @@
+package nacos
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/everfir/logger-go"
+	"github.com/everfir/logger-go/structs/field"
+	"github.com/nacos-group/nacos-sdk-go/clients/naming_client"
+)
+
+// NewNamingClient: 按配置创建 Nacos 服务发现客户端, 连接方式与配置客户端一致
+func NewNamingClient(opts ClientOptions) (naming_client.INamingClient, error) {
+	client, err := opts.nacosClient()
+	if err != nil {
+		return nil, err
+	}
+
+	naming, err := naming_client.NewNamingClient(client)
+	if err != nil {
+		return nil, err
+	}
+	return &naming, nil
+}
+
+var namingClient = struct {
+	lock   sync.Mutex
+	client naming_client.INamingClient
+}{}
+
+// GetNamingClient: 获取默认的服务发现客户端, 创建失败时 panic, 需要处理错误时使用 LoadNamingClient
+var GetNamingClient func() naming_client.INamingClient = func() naming_client.INamingClient {
+	client, err := LoadNamingClient()
+	if err != nil {
+		panic(err.Error())
+	}
+	return client
+}
+
+// LoadNamingClient: 使用环境变量配置(ClientOptionsFromEnv)创建默认的服务发现客户端, 创建成功后复用, 失败时下次调用重新创建
+func LoadNamingClient() (naming_client.INamingClient, error) {
+	namingClient.lock.Lock()
+	defer namingClient.lock.Unlock()
+
+	if namingClient.client != nil {
+		return namingClient.client, nil
+	}
+
+	opts, err := clientOptionsFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("[go-helper] Init nacos naming client failed: %w", err)
+	}
+
+	client, err := NewNamingClient(opts)
+	if err != nil {
+		return nil, fmt.Errorf("[go-helper] Init nacos naming client failed: %w", err)
+	}
+
+	logger.Info(context.Background(), "[go-helper] GetNamingClient success", field.String("namespace", opts.Namespace))
+	namingClient.client = client
+	return client, nil
+}
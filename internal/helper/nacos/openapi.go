@@ -0,0 +1,297 @@
+package nacos
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrCASConflict: 配置在读取后被其他人修改, casMd5 校验失败
+var ErrCASConflict = errors.New("[go-helper] config changed concurrently, cas md5 mismatch")
+
+// ErrCASCreateUnsupported: 配置不存在, 配置源无法原子地创建配置(Nacos 对空的 casMd5 不做校验)
+var ErrCASCreateUnsupported = errors.New("[go-helper] config does not exist and the source cannot create it atomically, create it with Publish first")
+
+// ErrCASUnsupported: 配置源不支持 CAS 发布
+var ErrCASUnsupported = errors.New("[go-helper] config source does not support cas publish")
+
+// ErrHistoryUnsupported: 配置源不支持查询历史版本
+var ErrHistoryUnsupported = errors.New("[go-helper] config source does not support history")
+
+// casConflictMessage: casMd5 不匹配时 Nacos 返回的错误信息(小写)
+const casConflictMessage = "cas publish fail"
+
+// CASPublisher: 支持 CAS 发布的配置源
+type CASPublisher interface {
+	// PublishCAS 仅当配置当前内容的 md5 等于 casMd5 时发布, 否则返回 ErrCASConflict
+	// casMd5 为空表示配置不存在
+	PublishCAS(param PublishParam, casMd5 string) error
+}
+
+// OpenAPI: Nacos Open API 客户端
+//
+// nacos-sdk-go v1.1.5 的 vo.ConfigParam 不支持 casMd5, 带 casMd5 的发布直接调用 Open API:
+// POST {Addr}{ContextPath}/v1/cs/configs
+//
+// 连接 Addr 失败时依次尝试 Addrs; 已发出的请求(包括超时和非 200 响应)不会重试, 避免重复发布
+type OpenAPI struct {
+	Addr        string   // 服务地址, 例如 http://127.0.0.1:8848
+	Addrs       []string // 备用服务地址, 与 Addr 共用 ContextPath
+	ContextPath string   // 默认 /nacos
+	Namespace   string
+	Username    string
+	Password    string
+	Credentials CredentialProvider // 未指定 Username 时每次登录从 Credentials 读取账号, 支持密码轮换
+	Client      *http.Client       // 默认超时 10s
+
+	lock    sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func (api *OpenAPI) client() *http.Client {
+	if api.Client != nil {
+		return api.Client
+	}
+	return &http.Client{Timeout: 10 * time.Second}
+}
+
+func (api *OpenAPI) url(addr, path string) string {
+	contextPath := api.ContextPath
+	if contextPath == "" {
+		contextPath = "/nacos"
+	}
+	return strings.TrimRight(addr, "/") + contextPath + path
+}
+
+// send: 按 Addr、Addrs 的顺序发送请求, form 不为 nil 时 POST 表单, 否则 GET
+// 只在建立连接失败时尝试下一个地址, 此时请求一定没有发出
+func (api *OpenAPI) send(path string, query, form url.Values, header http.Header) (*http.Response, error) {
+	var err error
+	for _, addr := range append([]string{api.Addr}, api.Addrs...) {
+		u := api.url(addr, path)
+		if len(query) > 0 {
+			u += "?" + query.Encode()
+		}
+
+		var req *http.Request
+		if form != nil {
+			req, err = http.NewRequest(http.MethodPost, u, strings.NewReader(form.Encode()))
+		} else {
+			req, err = http.NewRequest(http.MethodGet, u, nil)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		if form != nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+
+		var resp *http.Response
+		resp, err = api.client().Do(req)
+
+		var opErr *net.OpError
+		if err == nil || !errors.As(err, &opErr) || opErr.Op != "dial" {
+			return resp, err
+		}
+	}
+	return nil, err
+}
+
+// accessToken: 获取登录 token, 未配置账号时不鉴权, token 在过期前 1 分钟刷新
+func (api *OpenAPI) accessToken() (string, error) {
+	api.lock.Lock()
+	defer api.lock.Unlock()
+
+	if api.token != "" && time.Now().Before(api.expires) {
+		return api.token, nil
+	}
+
+	credential, err := resolveCredential(Credential{Username: api.Username, Password: api.Password}, api.Credentials)
+	if err != nil {
+		return "", err
+	}
+	if credential.Username == "" {
+		return "", nil
+	}
+
+	resp, err := api.send("/v1/auth/login", nil, url.Values{
+		"username": {credential.Username},
+		"password": {credential.Password},
+	}, nil)
+	if err != nil {
+		return "", fmt.Errorf("[go-helper] nacos login failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("[go-helper] nacos login failed, status:%d", resp.StatusCode)
+	}
+
+	var ret struct {
+		AccessToken string `json:"accessToken"`
+		TokenTtl    int64  `json:"tokenTtl"`
+	}
+	if err = json.Unmarshal(body, &ret); err != nil {
+		return "", fmt.Errorf("[go-helper] nacos login response invalid: %w", err)
+	}
+
+	api.token = ret.AccessToken
+	api.expires = time.Now().Add(time.Duration(ret.TokenTtl)*time.Second - time.Minute)
+	return api.token, nil
+}
+
+// PublishCAS 带 casMd5 发布配置; casMd5 为空(配置不存在)时 Nacos 不做校验, 无法保证只创建一次, 返回 ErrCASCreateUnsupported
+func (api *OpenAPI) PublishCAS(param PublishParam, casMd5 string) error {
+	if casMd5 == "" {
+		return ErrCASCreateUnsupported
+	}
+
+	token, err := api.accessToken()
+	if err != nil {
+		return err
+	}
+
+	form := url.Values{
+		"dataId":  {param.DataId},
+		"group":   {param.Group},
+		"content": {param.Content},
+		"type":    {param.Type},
+	}
+	if api.Namespace != "" {
+		form.Set("tenant", api.Namespace)
+	}
+	// Nacos 服务端从请求头读取 casMd5(与 betaIps 相同), 表单中也带上以兼容读取参数的版本
+	form.Set("casMd5", casMd5)
+	header := http.Header{}
+	header.Set("casMd5", casMd5)
+
+	query := url.Values{}
+	if token != "" {
+		query.Set("accessToken", token)
+	}
+	resp, err := api.send("/v1/cs/configs", query, form, header)
+	if err != nil {
+		return fmt.Errorf("[go-helper] nacos publish config failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	result := strings.TrimSpace(string(body))
+	switch {
+	case resp.StatusCode == http.StatusOK && strings.EqualFold(result, "true"):
+		return nil
+	// casMd5 不匹配时 Nacos 返回 500(2.x 的 v2 错误码为 409) 和 "Cas publish fail, server md5 may have changed."
+	case (resp.StatusCode == http.StatusInternalServerError || resp.StatusCode == http.StatusConflict) &&
+		strings.Contains(strings.ToLower(result), casConflictMessage):
+		return ErrCASConflict
+	default:
+		return fmt.Errorf("[go-helper] nacos publish config failed, status:%d body:%s", resp.StatusCode, result)
+	}
+}
+
+// ConfigHistory: 配置的历史版本
+type ConfigHistory struct {
+	Id               string `json:"id"`
+	DataId           string `json:"dataId"`
+	Group            string `json:"group"`
+	Md5              string `json:"md5"`
+	Content          string `json:"content,omitempty"` // 只有获取单个版本时返回
+	OpType           string `json:"opType"`            // I: 新增, U: 修改, D: 删除
+	SrcUser          string `json:"srcUser"`
+	SrcIp            string `json:"srcIp"`
+	CreatedTime      string `json:"createdTime"`
+	LastModifiedTime string `json:"lastModifiedTime"`
+}
+
+// HistorySource: 支持查询配置历史的配置源
+type HistorySource interface {
+	// ListHistory 按时间倒序分页列出历史版本, pageNo 从 1 开始
+	ListHistory(dataId, group string, pageNo, pageSize int) ([]ConfigHistory, error)
+	// GetHistory 获取指定历史版本, 包含配置内容
+	GetHistory(dataId, group, id string) (ConfigHistory, error)
+}
+
+// historyItem: Open API 返回的历史记录, id 在不同版本中可能是数字或字符串
+type historyItem struct {
+	ConfigHistory
+	Id json.Number `json:"id"`
+}
+
+func (item historyItem) history() ConfigHistory {
+	h := item.ConfigHistory
+	h.Id = item.Id.String()
+	return h
+}
+
+// get: 带鉴权的 GET 请求, 解析 JSON 响应到 v
+func (api *OpenAPI) get(path string, query url.Values, v any) error {
+	token, err := api.accessToken()
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		query.Set("accessToken", token)
+	}
+	if api.Namespace != "" {
+		query.Set("tenant", api.Namespace)
+	}
+
+	resp, err := api.send(path, query, nil, nil)
+	if err != nil {
+		return fmt.Errorf("[go-helper] nacos request %s failed: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("[go-helper] nacos request %s failed, status:%d body:%s", path, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err = json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("[go-helper] nacos response of %s invalid: %w", path, err)
+	}
+	return nil
+}
+
+func (api *OpenAPI) ListHistory(dataId, group string, pageNo, pageSize int) ([]ConfigHistory, error) {
+	var page struct {
+		PageItems []historyItem `json:"pageItems"`
+	}
+	err := api.get("/v1/cs/history", url.Values{
+		"search":   {"accurate"},
+		"dataId":   {dataId},
+		"group":    {group},
+		"pageNo":   {fmt.Sprint(pageNo)},
+		"pageSize": {fmt.Sprint(pageSize)},
+	}, &page)
+	if err != nil {
+		return nil, err
+	}
+
+	histories := make([]ConfigHistory, 0, len(page.PageItems))
+	for _, item := range page.PageItems {
+		histories = append(histories, item.history())
+	}
+	return histories, nil
+}
+
+func (api *OpenAPI) GetHistory(dataId, group, id string) (ConfigHistory, error) {
+	var item historyItem
+	err := api.get("/v1/cs/history", url.Values{
+		"nid":    {id},
+		"dataId": {dataId},
+		"group":  {group},
+	}, &item)
+	return item.history(), err
+}
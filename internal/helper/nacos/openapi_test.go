@@ -0,0 +1,157 @@
+package nacos
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/everfir/go-helpers/env"
+	"github.com/zeebo/assert"
+)
+
+func TestPublishCAS(t *testing.T) {
+	source := NewMemorySource()
+	assert.NoError(t, source.Publish(PublishParam{DataId: "shutdown.json", Group: env.Env(), Content: `{"a":true}`}))
+
+	// 第一次修改期间配置被其他人修改, 重新读取后基于最新配置修改
+	calls := 0
+	result, err := PublishCAS(source, "shutdown.json", func(data *map[string]bool) error {
+		calls++
+		if calls == 1 {
+			assert.NoError(t, source.Publish(PublishParam{DataId: "shutdown.json", Group: env.Env(), Content: `{"a":true,"b":true}`}))
+		}
+		(*data)["c"] = true
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, result.Attempts, 2)
+	assert.Equal(t, result.Content, `{"a":true,"b":true,"c":true}`)
+	assert.Equal(t, result.Md5, contentMd5(result.Content))
+	assert.Equal(t, result.PrevMd5, contentMd5(`{"a":true,"b":true}`))
+
+	content, err := source.Get("shutdown.json", env.Env())
+	assert.NoError(t, err)
+	assert.Equal(t, content, result.Content)
+
+	// 持续冲突时重试次数有限
+	_, err = PublishCAS(source, "shutdown.json", func(data *map[string]bool) error {
+		calls++
+		return source.Publish(PublishParam{DataId: "shutdown.json", Group: env.Env(), Content: fmt.Sprintf(`{"n%d":true}`, calls)})
+	}, WithCASRetries(1))
+	assert.True(t, errors.Is(err, ErrCASConflict))
+
+	// 校验失败时不发布
+	_, err = PublishCAS(source, "app.yaml", func(data *yamlConfig) error { return nil })
+	assert.Error(t, err)
+
+	// 不支持 CAS 的配置源
+	_, err = PublishCAS(NewEnvSource(), "shutdown.json", func(data *map[string]bool) error { return nil })
+	assert.True(t, errors.Is(err, ErrCASUnsupported))
+}
+
+func TestOpenAPIPublishCAS(t *testing.T) {
+	var current = "v1"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/nacos/v1/auth/login":
+			w.Write([]byte(`{"accessToken":"token","tokenTtl":18000}`))
+		case "/nacos/v1/cs/configs":
+			assert.Equal(t, r.URL.Query().Get("accessToken"), "token")
+			assert.NoError(t, r.ParseForm())
+			assert.Equal(t, r.PostForm.Get("tenant"), "ns")
+			// 与 Nacos 服务端相同, 从请求头读取 casMd5, 缺少时退化为直接覆盖
+			if md5 := r.Header.Get("casMd5"); md5 != "" && md5 != contentMd5(current) {
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte("Cas publish fail, server md5 may have changed."))
+				return
+			}
+			if r.PostForm.Get("content") == "broken" {
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte("publish failed, caused by cascading errors"))
+				return
+			}
+			current = r.PostForm.Get("content")
+			w.Write([]byte("true"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	api := &OpenAPI{Addr: server.URL, Namespace: "ns", Username: "nacos", Password: "nacos"}
+	param := PublishParam{DataId: "gray.json", Group: "test", Content: "v2", Type: "json"}
+	assert.NoError(t, api.PublishCAS(param, contentMd5("v1")))
+	assert.Equal(t, current, "v2")
+	assert.True(t, errors.Is(api.PublishCAS(param, contentMd5("v1")), ErrCASConflict))
+	assert.Equal(t, current, "v2")
+
+	// 其他服务端错误不是冲突
+	err := api.PublishCAS(PublishParam{DataId: "gray.json", Group: "test", Content: "broken", Type: "json"}, contentMd5("v2"))
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrCASConflict))
+
+	// Nacos 对空的 casMd5 不做校验, 不能用于创建配置
+	assert.True(t, errors.Is(api.PublishCAS(param, ""), ErrCASCreateUnsupported))
+	assert.Equal(t, current, "v2")
+
+	// 第一个地址无法连接时使用备用地址
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+	api = &OpenAPI{Addr: dead.URL, Addrs: []string{server.URL}, Namespace: "ns", Username: "nacos", Password: "nacos"}
+	param.Content = "v3"
+	assert.NoError(t, api.PublishCAS(param, contentMd5("v2")))
+	assert.Equal(t, current, "v3")
+
+	api = &OpenAPI{Addr: dead.URL, Namespace: "ns", Username: "nacos", Password: "nacos"}
+	assert.Error(t, api.PublishCAS(param, contentMd5("v3")))
+}
+
+func TestHistoryRollback(t *testing.T) {
+	source := NewMemorySource()
+	assert.NoError(t, Publish(source, "app.yaml", yamlConfig{Name: "v1"}))
+	assert.NoError(t, Publish(source, "app.yaml", yamlConfig{Name: "v2"}))
+
+	histories, err := ListHistory(source, "app.yaml", 1, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, len(histories), 2)
+	assert.Equal(t, histories[0].OpType, "U")
+	assert.Equal(t, histories[1].OpType, "I")
+
+	version, history, err := GetVersion[yamlConfig](source, "app.yaml", histories[1].Id)
+	assert.NoError(t, err)
+	assert.Equal(t, version.Name, "v1")
+	assert.Equal(t, history.Content, "name: v1\n")
+
+	result, err := Rollback[yamlConfig](source, "app.yaml", histories[1].Id)
+	assert.NoError(t, err)
+	assert.Equal(t, result.Content, "name: v1\n")
+	current, md5, err := Read[yamlConfig](source, "app.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, current.Name, "v1")
+	assert.Equal(t, md5, result.Md5)
+
+	// 历史内容原样发布, 保留 T 中没有的字段与注释
+	raw := "# owner: ops\nname: v3\nextra: keep # used by other consumers\n"
+	assert.NoError(t, source.Publish(PublishParam{DataId: "app.yaml", Group: env.Env(), Content: raw}))
+	assert.NoError(t, Publish(source, "app.yaml", yamlConfig{Name: "v4"}))
+	histories, err = ListHistory(source, "app.yaml", 1, 2)
+	assert.NoError(t, err)
+	result, err = Rollback[yamlConfig](source, "app.yaml", histories[1].Id)
+	assert.NoError(t, err)
+	assert.Equal(t, result.Content, raw)
+	content, err := source.Get("app.yaml", env.Env())
+	assert.NoError(t, err)
+	assert.Equal(t, content, raw)
+
+	// 回滚到无法通过校验的版本会被拒绝
+	assert.NoError(t, source.Publish(PublishParam{DataId: "app.yaml", Group: env.Env(), Content: "name: ''\n"}))
+	histories, err = ListHistory(source, "app.yaml", 1, 1)
+	assert.NoError(t, err)
+	_, err = Rollback[yamlConfig](source, "app.yaml", histories[0].Id)
+	assert.Error(t, err)
+
+	_, err = ListHistory(NewEnvSource(), "app.yaml", 1, 10)
+	assert.True(t, errors.Is(err, ErrHistoryUnsupported))
+}
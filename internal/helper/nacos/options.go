@@ -0,0 +1,118 @@
+package nacos
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/everfir/go-helpers/consts"
+	"github.com/everfir/go-helpers/env"
+	"github.com/everfir/go-helpers/internal/helper/codec"
+)
+
+// Option 获取、发布配置时的可选参数
+type Option func(*options)
+
+type options struct {
+	format       codec.Format
+	gray         bool
+	overlay      bool
+	trafficGroup *consts.TrafficGroup
+	defaultValue any
+	discovery    time.Duration
+	casRetries   int
+}
+
+// DefaultCASRetries: PublishCAS 冲突时的默认重试次数
+const DefaultCASRetries = 3
+
+// DefaultDiscoveryInterval: 灰度分组发现的默认间隔
+const DefaultDiscoveryInterval = 30 * time.Second
+
+func newOptions(dataId string, opts ...Option) *options {
+	o := &options{discovery: DefaultDiscoveryInterval, casRetries: DefaultCASRetries}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.format == "" {
+		o.format = codec.FormatOf(dataId)
+	}
+	return o
+}
+
+// group: 发布配置的分组，未指定流量分组时为当前环境
+func (o *options) group() string {
+	if o.trafficGroup == nil {
+		return env.Env()
+	}
+	return fmt.Sprintf("%s_%s", env.Env(), o.trafficGroup.Group())
+}
+
+// WithFormat 指定配置格式，默认根据 dataId 的扩展名推断
+func WithFormat(format codec.Format) Option {
+	return func(o *options) {
+		o.format = format
+	}
+}
+
+// WithGray 获取配置时加载该 dataId 下所有灰度分组的配置
+func WithGray() Option {
+	return func(o *options) {
+		o.gray = true
+	}
+}
+
+// WithOverlay 以分层模式加载配置：当前环境分组为基础配置，{env}_{group} 分组为流量分组补丁，
+// {env}.{business} 分组为业务补丁，按 基础配置 -> 流量分组补丁 -> 业务补丁 的顺序以 RFC 7386 合并，
+// 补丁中值为 null 的字段会被删除。合并结果缓存，只在某一层变化时重新计算。
+//
+// 通过 NacosConfig.Get 获取流量分组的合并结果，通过 NacosConfig.GetWithBusiness 获取业务的合并结果。
+func WithOverlay() Option {
+	return func(o *options) {
+		o.overlay = true
+	}
+}
+
+// WithDefault 指定编译期默认配置，配置源与本地快照均不可用时使用
+//
+// 只对支持降级的配置源（FallbackSource）生效，默认配置按配置格式序列化后注册到当前环境分组。
+func WithDefault(value any) Option {
+	return func(o *options) {
+		o.defaultValue = value
+	}
+}
+
+// WithDiscoveryInterval 指定灰度配置（WithGray、WithOverlay）定期发现新增、删除分组的间隔，小于等于 0 时关闭发现
+func WithDiscoveryInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.discovery = interval
+	}
+}
+
+// WithCASRetries 指定 PublishCAS 冲突时的重试次数，默认 DefaultCASRetries
+func WithCASRetries(retries int) Option {
+	return func(o *options) {
+		o.casRetries = retries
+	}
+}
+
+// WithTrafficGroup 发布配置时发布到指定流量分组
+func WithTrafficGroup(group consts.TrafficGroup) Option {
+	return func(o *options) {
+		o.trafficGroup = &group
+	}
+}
+
+// registerDefault: 向支持降级的配置源注册默认配置
+func (o *options) registerDefault(source ConfigSource, dataId, group string) error {
+	d, ok := source.(defaulter)
+	if !ok || o.defaultValue == nil {
+		return nil
+	}
+
+	content, err := codec.Encode(o.format, o.defaultValue)
+	if err != nil {
+		return fmt.Errorf("[go-helper] marshal default config failed: %w", err)
+	}
+	d.SetDefault(dataId, group, content)
+	return nil
+}
@@ -0,0 +1,361 @@
+package nacos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	nacos_config "github.com/everfir/go-helpers/define/config"
+	"github.com/everfir/go-helpers/env"
+	"github.com/everfir/go-helpers/internal/helper/codec"
+	internal_config "github.com/everfir/go-helpers/internal/structs/config"
+	"github.com/everfir/logger-go"
+	"github.com/everfir/logger-go/structs/field"
+)
+
+// overlay: 分层配置, 按 基础配置 -> 流量分组补丁 -> 业务补丁 的顺序以 RFC 7386 合并
+//
+// Nacos 分组与层的对应关系:
+//   - {env}: 基础配置, 例如 production
+//   - {env}_{group}: 流量分组补丁, 例如 production_b
+//   - {env}.{business}: 业务补丁, 例如 production.momo
+//
+// 合并结果按分组键缓存在 NacosConfig 中, 只在某一层变化时重新计算, 结果未变化时不通知监听器
+type overlay[T any] struct {
+	lock    sync.Mutex
+	source  ConfigSource
+	dataId  string
+	format  codec.Format
+	config  *nacos_config.NacosConfig[T]
+	layers  map[string]any                        // Nacos 分组 -> 解析后的配置
+	pending map[string]string                     // Nacos 分组 -> 尚未写入快照的原始内容, 依赖该层的结果都构建成功后写入
+	results map[string]*internal_config.Config[T] // 分组键 -> 合并结果
+	tracker *configTracker
+}
+
+// GetOverlayConfigAndListenFromSource 以分层模式获取配置并监听各层的变更, 见 WithOverlay
+func GetOverlayConfigAndListenFromSource[T any](
+	source ConfigSource,
+	dataId string,
+	opts ...Option,
+) (config *nacos_config.NacosConfig[T], err error) {
+	o := newOptions(dataId, opts...)
+	tracker := configs.track(dataId, o.format, ConfigMode_Overlay)
+	defer func() { tracker.done(err) }()
+
+	if o.format == codec.Format_Text {
+		return nil, fmt.Errorf("[go-helper] overlay does not support %s format", o.format)
+	}
+	if err = o.registerDefault(source, dataId, env.Env()); err != nil {
+		return nil, err
+	}
+
+	groups, err := source.SearchGroups(dataId)
+	if err != nil {
+		return nil, fmt.Errorf("[go-helper] Search config failed, err: %w", err)
+	}
+
+	ov := &overlay[T]{
+		source:  source,
+		dataId:  dataId,
+		format:  o.format,
+		config:  nacos_config.NewNacosConfig[T](map[string]*internal_config.Config[T]{}),
+		layers:  map[string]any{},
+		pending: map[string]string{},
+		results: map[string]*internal_config.Config[T]{},
+		tracker: tracker,
+	}
+	// 基础配置不存在时也需要加载, 以便使用降级配置并监听其创建
+	groups = append(groups, env.Env())
+	for _, group := range groups {
+		if _, exist := ov.layers[group]; exist || !isOverlayLayer(group) {
+			continue
+		}
+		if err = ov.load(group); err != nil {
+			return nil, err
+		}
+	}
+
+	ov.lock.Lock()
+	err = ov.rebuild()
+	ov.lock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	ov.config.OnClose(tracker.close)
+	if o.discovery > 0 {
+		stop := make(chan struct{})
+		ov.config.OnClose(func() { close(stop) })
+		go ov.discover(o.discovery, stop)
+	}
+	return ov.config, nil
+}
+
+// isOverlayLayer: 是否为当前环境的某一层
+func isOverlayLayer(group string) bool {
+	return group == env.Env() ||
+		strings.HasPrefix(group, env.Env()+"_") && !strings.Contains(group, ".") ||
+		strings.HasPrefix(group, env.Env()+".")
+}
+
+// load: 加载一层配置并监听变更
+func (ov *overlay[T]) load(group string) error {
+	content, err := ov.source.Get(ov.dataId, group)
+	if err != nil {
+		return fmt.Errorf("[go-helper] Get config from nacos failed, group:%s err: %w", group, err)
+	}
+	layer, err := ov.decode(content)
+	if err != nil {
+		return fmt.Errorf("[go-helper] %s unmarshal failed, group:%s err: %w", ov.format, group, err)
+	}
+	ov.lock.Lock()
+	ov.layers[group] = layer
+	ov.pending[group] = content
+	ov.lock.Unlock()
+
+	// 配置源可能在 Watch 中同步回调, 不能持有锁
+	return ov.source.Watch(ov.dataId, group, func(change ConfigChange) {
+		ov.onChange(group, change.Content)
+	})
+}
+
+// decode: 空内容表示该层不存在
+func (ov *overlay[T]) decode(content string) (any, error) {
+	if strings.TrimSpace(content) == "" {
+		return nil, nil
+	}
+	return codec.DecodeTree(ov.format, content)
+}
+
+func (ov *overlay[T]) onChange(group, content string) {
+	layer, err := ov.decode(content)
+	if err != nil {
+		ov.tracker.reject(group, fmt.Errorf("%s unmarshal failed: %w", ov.format, err))
+		logger.Warn(
+			context.TODO(),
+			"[go-helper] overlay layer unmarshal failed",
+			field.String("dataId", ov.dataId),
+			field.String("group", group),
+			field.String("err", err.Error()),
+		)
+		return
+	}
+
+	ov.lock.Lock()
+	defer ov.lock.Unlock()
+
+	// 已移除的层
+	if _, exist := ov.layers[group]; !exist {
+		return
+	}
+	ov.layers[group] = layer
+	ov.pending[group] = content
+	if err = ov.rebuild(); err != nil {
+		logger.Warn(
+			context.TODO(),
+			"[go-helper] overlay config rebuild failed",
+			field.String("dataId", ov.dataId),
+			field.String("group", group),
+			field.String("err", err.Error()),
+		)
+	}
+}
+
+// stack: 每个合并结果的分组键及其依次合并的层
+func (ov *overlay[T]) stack() map[string][]string {
+	base := env.Env()
+	var groups, businesses []string
+	for group := range ov.layers {
+		switch {
+		case group == base:
+		case strings.HasPrefix(group, base+"."):
+			businesses = append(businesses, group)
+		default:
+			groups = append(groups, group)
+		}
+	}
+
+	stack := map[string][]string{base: {base}}
+	for _, group := range groups {
+		stack[group] = []string{base, group}
+	}
+	for _, business := range businesses {
+		name := strings.TrimPrefix(business, base+".")
+		stack[business] = []string{base, business}
+		for _, group := range groups {
+			stack[nacos_config.BusinessGroup(group, name)] = []string{base, group, business}
+		}
+	}
+	return stack
+}
+
+// rebuild: 重新合并所有结果, 调用方需持有锁
+//
+// 某个结果合并或校验失败时保留该结果的旧值, 其余结果照常更新;
+// 层的快照只在依赖该层的结果都构建成功后写入, 避免无法使用的内容成为降级时的配置
+func (ov *overlay[T]) rebuild() error {
+	stack := ov.stack()
+	keys := make([]string, 0, len(stack))
+	for key := range stack {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var (
+		errs   []error
+		failed = map[string]struct{}{}
+	)
+	for _, key := range keys {
+		var merged any
+		for _, group := range stack[key] {
+			if layer := ov.layers[group]; layer != nil {
+				merged = codec.MergePatch(merged, layer)
+			}
+		}
+		data, content, err := ov.build(merged)
+		if err != nil {
+			ov.tracker.reject(key, err)
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+			for _, group := range stack[key] {
+				failed[group] = struct{}{}
+			}
+			continue
+		}
+
+		meta := internal_config.Metadata{Group: key, DataId: ov.dataId, Md5: contentMd5(content)}
+		conf, exist := ov.results[key]
+		if !exist {
+			conf = internal_config.NewConfig[T]()
+			conf.Data, conf.Metadata, conf.Content = data, meta, content
+			ov.results[key] = conf
+			ov.tracker.update(key, meta.Md5, content)
+			ov.config.AddGroup(key, conf)
+			continue
+		}
+		if _, old, _ := conf.Snapshot(); old.Md5 != meta.Md5 {
+			ov.tracker.update(key, meta.Md5, content)
+			conf.Update(data, meta, content)
+		}
+	}
+
+	for key := range ov.results {
+		if _, exist := stack[key]; !exist {
+			delete(ov.results, key)
+			ov.tracker.remove(key)
+			ov.config.RemoveGroup(key)
+		}
+	}
+
+	for group, content := range ov.pending {
+		if _, exist := failed[group]; !exist {
+			snapshot(ov.source, ov.dataId, group, content)
+			delete(ov.pending, group)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// build: 将合并后的配置解析为 T, 补全默认值、解密后执行 Validator、Formatter
+func (ov *overlay[T]) build(merged any) (*T, string, error) {
+	b, err := json.Marshal(codec.TypedTree(merged, new(T)))
+	if err != nil {
+		return nil, "", err
+	}
+	content := string(b)
+
+	data, err := decodeConfig[T](codec.Format_JSON, content)
+	if err != nil {
+		return nil, "", fmt.Errorf("[go-helper] %w", err)
+	}
+	return data, content, nil
+}
+
+// discover: 定期搜索新增、删除的层, 基础配置不会被移除
+func (ov *overlay[T]) discover(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		groups, err := ov.source.SearchGroups(ov.dataId)
+		if err != nil {
+			logger.Warn(
+				context.TODO(),
+				"[go-helper] discover overlay layers failed",
+				field.String("dataId", ov.dataId),
+				field.String("err", err.Error()),
+			)
+			continue
+		}
+
+		ov.lock.Lock()
+		loaded := make(map[string]struct{}, len(ov.layers))
+		for group := range ov.layers {
+			loaded[group] = struct{}{}
+		}
+		ov.lock.Unlock()
+
+		found := map[string]struct{}{env.Env(): {}}
+		changed := false
+		for _, group := range groups {
+			found[group] = struct{}{}
+			if _, exist := loaded[group]; exist || !isOverlayLayer(group) {
+				continue
+			}
+			if err := ov.load(group); err != nil {
+				logger.Warn(
+					context.TODO(),
+					"[go-helper] load discovered overlay layer failed",
+					field.String("dataId", ov.dataId),
+					field.String("group", group),
+					field.String("err", err.Error()),
+				)
+				continue
+			}
+			changed = true
+		}
+
+		ov.lock.Lock()
+		for group := range loaded {
+			if _, exist := found[group]; exist {
+				continue
+			}
+			if u, ok := ov.source.(Unwatcher); ok {
+				if err := u.Unwatch(ov.dataId, group); err != nil {
+					logger.Warn(
+						context.TODO(),
+						"[go-helper] unwatch overlay layer failed",
+						field.String("dataId", ov.dataId),
+						field.String("group", group),
+						field.String("err", err.Error()),
+					)
+				}
+			}
+			delete(ov.layers, group)
+			delete(ov.pending, group)
+			changed = true
+		}
+		if changed {
+			if err := ov.rebuild(); err != nil {
+				logger.Warn(
+					context.TODO(),
+					"[go-helper] overlay config rebuild failed",
+					field.String("dataId", ov.dataId),
+					field.String("err", err.Error()),
+				)
+			}
+		}
+		ov.lock.Unlock()
+	}
+}
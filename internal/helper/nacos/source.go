@@ -0,0 +1,242 @@
+package nacos
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/nacos-group/nacos-sdk-go/clients/config_client"
+	"github.com/nacos-group/nacos-sdk-go/vo"
+)
+
+const (
+	// ConfigSourceKey: 环境变量中指定默认配置源, 可选 nacos(默认)、dir、env
+	ConfigSourceKey = "EVERFIR_CONFIG_SOURCE"
+	// ConfigDirKey: 环境变量中指定本地配置目录, 配置源为 dir 时生效
+	ConfigDirKey = "EVERFIR_CONFIG_DIR"
+)
+
+// ConfigChange: 配置变更通知
+type ConfigChange struct {
+	Namespace string
+	Group     string
+	DataId    string
+	Content   string
+}
+
+// PublishParam: 发布配置参数
+type PublishParam struct {
+	DataId  string
+	Group   string
+	Content string
+	Type    string // 配置格式, 例如 json
+}
+
+// ConfigSource: 配置源, 屏蔽 Nacos、本地目录、环境变量等不同存储的差异
+type ConfigSource interface {
+	// Get 获取指定 dataId 与分组的配置内容
+	Get(dataId, group string) (string, error)
+	// Watch 监听配置变更, 配置变更时回调 onChange
+	Watch(dataId, group string, onChange func(change ConfigChange)) error
+	// SearchGroups 搜索 dataId 下存在的所有分组
+	SearchGroups(dataId string) ([]string, error)
+	// Publish 发布配置
+	Publish(param PublishParam) error
+}
+
+// Unwatcher: 支持取消监听的配置源, 灰度分组被删除时用于取消该分组的监听
+type Unwatcher interface {
+	// Unwatch 取消指定 dataId 与分组的所有监听
+	Unwatch(dataId, group string) error
+}
+
+// NewNacosSource 基于 Nacos 配置客户端创建配置源
+func NewNacosSource(client config_client.IConfigClient) *NacosSource {
+	return &NacosSource{client: client}
+}
+
+type NacosSource struct {
+	client  config_client.IConfigClient
+	openAPI *OpenAPI
+}
+
+// WithOpenAPI 设置 Nacos Open API 客户端, 用于 SDK 不支持的 CAS 发布
+func (s *NacosSource) WithOpenAPI(api *OpenAPI) *NacosSource {
+	s.openAPI = api
+	return s
+}
+
+// Client 获取底层的 Nacos 配置客户端
+func (s *NacosSource) Client() config_client.IConfigClient {
+	return s.client
+}
+
+func (s *NacosSource) Get(dataId, group string) (string, error) {
+	return s.client.GetConfig(vo.ConfigParam{
+		DataId: dataId,
+		Group:  group,
+	})
+}
+
+func (s *NacosSource) Watch(dataId, group string, onChange func(change ConfigChange)) error {
+	return s.client.ListenConfig(vo.ConfigParam{
+		DataId: dataId,
+		Group:  group,
+		OnChange: func(namespace, group, dataId, data string) {
+			onChange(ConfigChange{
+				Namespace: namespace,
+				Group:     group,
+				DataId:    dataId,
+				Content:   data,
+			})
+		},
+	})
+}
+
+func (s *NacosSource) Unwatch(dataId, group string) error {
+	return s.client.CancelListenConfig(vo.ConfigParam{
+		DataId: dataId,
+		Group:  group,
+	})
+}
+
+func (s *NacosSource) SearchGroups(dataId string) ([]string, error) {
+	// 搜索该 DataId 下所有的 Group 配置
+	searchConfig, err := s.client.SearchConfig(vo.SearchConfigParam{
+		Search: "accurate", // 精确搜索
+		DataId: dataId,     // 指定要查询的 DataId
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]string, 0, len(searchConfig.PageItems))
+	for _, config := range searchConfig.PageItems {
+		groups = append(groups, config.Group)
+	}
+	return groups, nil
+}
+
+func (s *NacosSource) Publish(param PublishParam) error {
+	configType := vo.ConfigType(param.Type)
+	if configType == "" {
+		configType = vo.JSON
+	}
+
+	success, err := s.client.PublishConfig(vo.ConfigParam{
+		DataId:  param.DataId,
+		Group:   param.Group,
+		Content: param.Content,
+		Type:    configType,
+	})
+	if err != nil {
+		return err
+	}
+	if !success {
+		return fmt.Errorf("publish config failed without error")
+	}
+	return nil
+}
+
+// PublishCAS 通过 Open API 带 casMd5 发布配置, 未设置 Open API 时返回 ErrCASUnsupported
+func (s *NacosSource) PublishCAS(param PublishParam, casMd5 string) error {
+	if s.openAPI == nil {
+		return ErrCASUnsupported
+	}
+	if param.Type == "" {
+		param.Type = string(vo.JSON)
+	}
+	return s.openAPI.PublishCAS(param, casMd5)
+}
+
+// ListHistory 通过 Open API 查询历史版本, 未设置 Open API 时返回 ErrHistoryUnsupported
+func (s *NacosSource) ListHistory(dataId, group string, pageNo, pageSize int) ([]ConfigHistory, error) {
+	if s.openAPI == nil {
+		return nil, ErrHistoryUnsupported
+	}
+	return s.openAPI.ListHistory(dataId, group, pageNo, pageSize)
+}
+
+func (s *NacosSource) GetHistory(dataId, group, id string) (ConfigHistory, error) {
+	if s.openAPI == nil {
+		return ConfigHistory{}, ErrHistoryUnsupported
+	}
+	return s.openAPI.GetHistory(dataId, group, id)
+}
+
+var configSource = struct {
+	lock   sync.Mutex
+	source ConfigSource
+}{}
+
+// SetConfigSource 设置内置配置（停服、业务、灰度、账号）使用的默认配置源，
+// 需要在首次加载配置前调用。
+func SetConfigSource(source ConfigSource) {
+	configSource.lock.Lock()
+	defer configSource.lock.Unlock()
+
+	configSource.source = source
+}
+
+// GetConfigSource 获取默认配置源
+//
+// 未通过 SetConfigSource 设置时，根据环境变量 EVERFIR_CONFIG_SOURCE 创建：
+//   - nacos（默认）: 使用 GetNacosClient 创建的 Nacos 客户端，并按 EVERFIR_CONFIG_FALLBACK 等环境变量
+//     包装为 FallbackSource，Nacos 不可用时使用本地快照或编译期默认值启动
+//   - dir: 使用 EVERFIR_CONFIG_DIR 指定的本地目录
+//   - env: 使用环境变量
+//
+// 创建失败时 panic, 需要处理错误时使用 LoadConfigSource
+func GetConfigSource() ConfigSource {
+	source, err := LoadConfigSource()
+	if err != nil {
+		panic(err.Error())
+	}
+	return source
+}
+
+// LoadConfigSource 获取默认配置源, 行为与 GetConfigSource 一致, 创建失败时返回错误, 下次调用重新创建
+func LoadConfigSource() (ConfigSource, error) {
+	configSource.lock.Lock()
+	defer configSource.lock.Unlock()
+
+	if configSource.source != nil {
+		return configSource.source, nil
+	}
+
+	switch kind := os.Getenv(ConfigSourceKey); kind {
+	case "", "nacos":
+		opts, err := FallbackOptionsFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		clientOpts, err := clientOptionsFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		api, err := clientOpts.OpenAPI()
+		if err != nil {
+			return nil, err
+		}
+		client, err := LoadNacosClient()
+		if err != nil {
+			return nil, err
+		}
+		source, err := NewFallbackSource(NewNacosSource(client).WithOpenAPI(api), opts)
+		if err != nil {
+			return nil, err
+		}
+		configSource.source = source
+	case "dir":
+		source, err := NewDirSource(os.Getenv(ConfigDirKey))
+		if err != nil {
+			return nil, fmt.Errorf("[go-helper] Init dir config source failed: %w", err)
+		}
+		configSource.source = source
+	case "env":
+		configSource.source = NewEnvSource()
+	default:
+		return nil, fmt.Errorf("[go-helper] unknown config source: %s", kind)
+	}
+	return configSource.source, nil
+}
@@ -0,0 +1,226 @@
+package nacos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/everfir/logger-go"
+	"github.com/everfir/logger-go/structs/field"
+	"github.com/fsnotify/fsnotify"
+)
+
+// NewDirSource 基于本地目录创建配置源，适用于本地开发与 CI
+//
+// 目录结构为 {dir}/{group}/{dataId}，例如 ./configs/test/shutdown.json。
+// 通过 inotify（fsnotify）监听文件变更，文件内容变化时回调监听器。
+func NewDirSource(dir string) (*DirSource, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("[go-helper] config dir should not be empty")
+	}
+	if info, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("[go-helper] stat config dir failed: %w", err)
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("[go-helper] config dir[%s] is not a directory", dir)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("[go-helper] create fsnotify watcher failed: %w", err)
+	}
+
+	source := &DirSource{
+		dir:       dir,
+		watcher:   watcher,
+		watchDirs: map[string]struct{}{},
+		listeners: map[string][]func(change ConfigChange){},
+		contents:  map[string]string{},
+	}
+	go source.loop()
+	return source, nil
+}
+
+type DirSource struct {
+	dir     string
+	watcher *fsnotify.Watcher
+
+	publishLock sync.Mutex // 保证同一进程内 PublishCAS 的比较与写入是原子的
+
+	lock      sync.Mutex
+	watchDirs map[string]struct{}                    // 已监听的分组目录
+	listeners map[string][]func(change ConfigChange) // key: 文件路径
+	contents  map[string]string                      // key: 文件路径, 最近一次通知的内容
+}
+
+func (s *DirSource) path(dataId, group string) string {
+	return filepath.Join(s.dir, group, dataId)
+}
+
+func (s *DirSource) Get(dataId, group string) (string, error) {
+	b, err := os.ReadFile(s.path(dataId, group))
+	if err != nil {
+		return "", fmt.Errorf("[go-helper] read config file failed: %w", err)
+	}
+	return string(b), nil
+}
+
+func (s *DirSource) Watch(dataId, group string, onChange func(change ConfigChange)) error {
+	path := s.path(dataId, group)
+	groupDir := filepath.Dir(path)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, exist := s.watchDirs[groupDir]; !exist {
+		if err := os.MkdirAll(groupDir, 0o755); err != nil {
+			return fmt.Errorf("[go-helper] create config group dir failed: %w", err)
+		}
+		// 监听目录而不是文件, 以便感知编辑器"写临时文件再重命名"的保存方式
+		if err := s.watcher.Add(groupDir); err != nil {
+			return fmt.Errorf("[go-helper] watch config dir failed: %w", err)
+		}
+		s.watchDirs[groupDir] = struct{}{}
+	}
+
+	if _, exist := s.contents[path]; !exist {
+		b, _ := os.ReadFile(path)
+		s.contents[path] = string(b)
+	}
+	s.listeners[path] = append(s.listeners[path], onChange)
+	return nil
+}
+
+func (s *DirSource) Unwatch(dataId, group string) error {
+	path := s.path(dataId, group)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.listeners, path)
+	delete(s.contents, path)
+	return nil
+}
+
+func (s *DirSource) SearchGroups(dataId string) ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("[go-helper] read config dir failed: %w", err)
+	}
+
+	groups := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(s.path(dataId, entry.Name())); err == nil {
+			groups = append(groups, entry.Name())
+		}
+	}
+	return groups, nil
+}
+
+// Publish 原子写入配置文件（写临时文件后重命名）
+func (s *DirSource) Publish(param PublishParam) error {
+	return writeFileAtomic(s.path(param.DataId, param.Group), param.Content)
+}
+
+// PublishCAS 当前文件内容的 md5 等于 casMd5 时写入, 只保证同一进程内的原子性
+func (s *DirSource) PublishCAS(param PublishParam, casMd5 string) error {
+	s.publishLock.Lock()
+	defer s.publishLock.Unlock()
+
+	current, err := os.ReadFile(s.path(param.DataId, param.Group))
+	switch {
+	case err == nil && contentMd5(string(current)) != casMd5:
+		return ErrCASConflict
+	case errors.Is(err, os.ErrNotExist) && casMd5 != "":
+		return ErrCASConflict
+	case err != nil && !errors.Is(err, os.ErrNotExist):
+		return fmt.Errorf("[go-helper] read config file failed: %w", err)
+	}
+	return s.Publish(param)
+}
+
+// writeFileAtomic: 原子写入文件（写临时文件后重命名）, 读取方不会读到写了一半的内容
+func writeFileAtomic(path, content string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("[go-helper] create config group dir failed: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("[go-helper] create temp config file failed: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.WriteString(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("[go-helper] write temp config file failed: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("[go-helper] close temp config file failed: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("[go-helper] rename config file failed: %w", err)
+	}
+	return nil
+}
+
+// Close 停止监听文件变更
+func (s *DirSource) Close() error {
+	return s.watcher.Close()
+}
+
+func (s *DirSource) loop() {
+	for {
+		select {
+		case event, ok := <-s.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) || event.Has(fsnotify.Rename) {
+				s.notify(filepath.Clean(event.Name))
+			}
+		case err, ok := <-s.watcher.Errors:
+			if !ok {
+				return
+			}
+			logger.Warn(context.TODO(), "[go-helper] config dir watcher error", field.String("err", err.Error()))
+		}
+	}
+}
+
+// notify: 文件内容发生变化时回调监听器
+func (s *DirSource) notify(path string) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		// 重命名导致的旧文件消失, 等待新文件的 Create 事件
+		if !errors.Is(err, os.ErrNotExist) {
+			logger.Warn(context.TODO(), "[go-helper] read config file failed", field.String("path", path), field.String("err", err.Error()))
+		}
+		return
+	}
+
+	s.lock.Lock()
+	listeners := s.listeners[path]
+	if len(listeners) == 0 || s.contents[path] == string(b) {
+		s.lock.Unlock()
+		return
+	}
+	s.contents[path] = string(b)
+	listeners = append([]func(change ConfigChange){}, listeners...)
+	s.lock.Unlock()
+
+	rel, _ := filepath.Rel(s.dir, path)
+	change := ConfigChange{
+		Group:   filepath.Dir(rel),
+		DataId:  filepath.Base(rel),
+		Content: string(b),
+	}
+	for _, onChange := range listeners {
+		onChange(change)
+	}
+}
@@ -0,0 +1,75 @@
+package nacos
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// EnvSourcePrefix: 环境变量配置源的变量名前缀
+const EnvSourcePrefix = "EVERFIR_CONFIG_"
+
+// NewEnvSource 基于环境变量创建只读配置源
+//
+// 变量名为 EVERFIR_CONFIG_{GROUP}_{DATAID}，字母转为大写，非字母数字字符替换为下划线，
+// 例如 test 分组的 shutdown.json 对应 EVERFIR_CONFIG_TEST_SHUTDOWN_JSON。
+// 环境变量在进程运行期间不会变化，因此 Watch 不会产生回调，Publish 返回错误。
+func NewEnvSource() *EnvSource {
+	return &EnvSource{}
+}
+
+type EnvSource struct{}
+
+// EnvSourceKey 获取配置对应的环境变量名
+func EnvSourceKey(dataId, group string) string {
+	return EnvSourcePrefix + envName(group) + "_" + envName(dataId)
+}
+
+func envName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
+
+func (s *EnvSource) Get(dataId, group string) (string, error) {
+	key := EnvSourceKey(dataId, group)
+	content, exist := os.LookupEnv(key)
+	if !exist {
+		return "", fmt.Errorf("[go-helper] config env[%s] not found", key)
+	}
+	return content, nil
+}
+
+func (s *EnvSource) Watch(dataId, group string, onChange func(change ConfigChange)) error {
+	return nil
+}
+
+// SearchGroups 根据环境变量名反推分组，分组名统一转为小写
+func (s *EnvSource) SearchGroups(dataId string) ([]string, error) {
+	suffix := "_" + envName(dataId)
+
+	var groups []string
+	for _, kv := range os.Environ() {
+		key, _, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(key, EnvSourcePrefix) || !strings.HasSuffix(key, suffix) {
+			continue
+		}
+
+		group := strings.TrimSuffix(strings.TrimPrefix(key, EnvSourcePrefix), suffix)
+		if group != "" {
+			groups = append(groups, strings.ToLower(group))
+		}
+	}
+	return groups, nil
+}
+
+func (s *EnvSource) Publish(param PublishParam) error {
+	return fmt.Errorf("[go-helper] env config source is read-only")
+}
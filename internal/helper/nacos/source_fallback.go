@@ -0,0 +1,467 @@
+package nacos
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/everfir/logger-go"
+	"github.com/everfir/logger-go/structs/field"
+)
+
+const (
+	// ConfigFallbackKey: 环境变量中指定配置源不可用时的降级策略, 可选 snapshot(默认)、default、none
+	ConfigFallbackKey = "EVERFIR_CONFIG_FALLBACK"
+	// ConfigSnapshotDirKey: 环境变量中指定本地快照目录
+	ConfigSnapshotDirKey = "EVERFIR_CONFIG_SNAPSHOT_DIR"
+	// ConfigRetryIntervalKey: 环境变量中指定降级后重连配置源的间隔, 例如 10s
+	ConfigRetryIntervalKey = "EVERFIR_CONFIG_RETRY_INTERVAL"
+)
+
+// FallbackPolicy: 配置源不可用时的降级策略
+type FallbackPolicy string
+
+const (
+	FallbackPolicy_None     FallbackPolicy = "none"     // 不降级, 与未包装的配置源行为一致
+	FallbackPolicy_Snapshot FallbackPolicy = "snapshot" // 优先使用本地快照, 其次使用编译期默认值
+	FallbackPolicy_Default  FallbackPolicy = "default"  // 只使用编译期默认值
+)
+
+// ActiveSource: 配置当前的实际来源
+type ActiveSource string
+
+const (
+	ActiveSource_Live     ActiveSource = "live"     // 配置源(Nacos)
+	ActiveSource_Snapshot ActiveSource = "snapshot" // 本地快照
+	ActiveSource_Default  ActiveSource = "default"  // 编译期默认值
+)
+
+// FallbackOptions: 降级配置
+type FallbackOptions struct {
+	Policy        FallbackPolicy
+	SnapshotDir   string        // 快照目录, 结构与 DirSource 一致: {dir}/{group}/{dataId}, 为空时不写快照
+	RetryInterval time.Duration // 降级后重连配置源的间隔
+}
+
+// DefaultFallbackOptions: 默认降级配置
+//   - Policy: snapshot
+//   - SnapshotDir: {os.TempDir()}/everfir-config-snapshot
+//   - RetryInterval: 10s
+func DefaultFallbackOptions() FallbackOptions {
+	return FallbackOptions{
+		Policy:        FallbackPolicy_Snapshot,
+		SnapshotDir:   filepath.Join(os.TempDir(), "everfir-config-snapshot"),
+		RetryInterval: 10 * time.Second,
+	}
+}
+
+// FallbackOptionsFromEnv: 从环境变量读取降级配置, 未设置的项使用默认值
+func FallbackOptionsFromEnv() (FallbackOptions, error) {
+	opts := DefaultFallbackOptions()
+	if policy := os.Getenv(ConfigFallbackKey); policy != "" {
+		opts.Policy = FallbackPolicy(policy)
+	}
+	if dir := os.Getenv(ConfigSnapshotDirKey); dir != "" {
+		opts.SnapshotDir = dir
+	}
+	if interval := os.Getenv(ConfigRetryIntervalKey); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil {
+			return opts, fmt.Errorf("[go-helper] invalid %s: %w", ConfigRetryIntervalKey, err)
+		}
+		opts.RetryInterval = d
+	}
+	return opts, opts.Validate()
+}
+
+func (opts FallbackOptions) Validate() error {
+	switch opts.Policy {
+	case FallbackPolicy_None, FallbackPolicy_Snapshot, FallbackPolicy_Default:
+	default:
+		return fmt.Errorf("[go-helper] unknown config fallback policy: %s", opts.Policy)
+	}
+	if opts.RetryInterval <= 0 {
+		return fmt.Errorf("[go-helper] config retry interval should be positive, got %s", opts.RetryInterval)
+	}
+	return nil
+}
+
+// snapshotter: 支持写入本地快照的配置源, 配置通过校验后调用
+type snapshotter interface {
+	Snapshot(dataId, group, content string)
+}
+
+// defaulter: 支持编译期默认配置的配置源
+type defaulter interface {
+	SetDefault(dataId, group, content string)
+}
+
+// NewFallbackSource 为配置源增加本地快照与降级能力
+//
+//   - 每个通过校验的配置版本会原子写入快照目录
+//   - 配置源不可用时按降级策略使用快照或编译期默认值(SetDefault), 服务可以在配置源宕机时正常启动
+//   - 降级后后台定期重连配置源, 恢复后切换回实时配置并通知监听器
+func NewFallbackSource(primary ConfigSource, opts FallbackOptions) (*FallbackSource, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	return &FallbackSource{
+		primary:  primary,
+		opts:     opts,
+		defaults: map[string]string{},
+		states:   map[string]fallbackState{},
+		watches:  map[string][]*fallbackWatch{},
+		stop:     make(chan struct{}),
+	}, nil
+}
+
+type FallbackSource struct {
+	primary ConfigSource
+	opts    FallbackOptions
+
+	lock      sync.Mutex
+	defaults  map[string]string           // key: {group}/{dataId}
+	states    map[string]fallbackState    // key: {group}/{dataId}
+	watches   map[string][]*fallbackWatch // key: {group}/{dataId}
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// fallbackState: 配置的实际来源与最近一次获取的内容
+type fallbackState struct {
+	active  ActiveSource
+	content string
+}
+
+func fallbackKey(dataId, group string) string {
+	return group + "/" + dataId
+}
+
+// Primary 获取被包装的配置源
+func (s *FallbackSource) Primary() ConfigSource {
+	return s.primary
+}
+
+// SetDefault 设置编译期默认配置, 配置源与快照均不可用时使用
+func (s *FallbackSource) SetDefault(dataId, group, content string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.defaults[fallbackKey(dataId, group)] = content
+}
+
+// ActiveSource 获取配置当前的实际来源, 尚未加载时返回空字符串
+func (s *FallbackSource) ActiveSource(dataId, group string) ActiveSource {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.states[fallbackKey(dataId, group)].active
+}
+
+func (s *FallbackSource) state(dataId, group string) fallbackState {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.states[fallbackKey(dataId, group)]
+}
+
+// setActive: 记录配置的实际来源与内容, 来源发生变化时记录日志
+func (s *FallbackSource) setActive(dataId, group string, active ActiveSource, content string) {
+	s.lock.Lock()
+	prev := s.states[fallbackKey(dataId, group)].active
+	s.states[fallbackKey(dataId, group)] = fallbackState{active: active, content: content}
+	s.lock.Unlock()
+
+	if prev == active {
+		return
+	}
+	if active == ActiveSource_Live {
+		logger.Info(
+			context.TODO(),
+			"[go-helper] config source active",
+			field.String("dataId", dataId),
+			field.String("group", group),
+			field.String("source", string(active)),
+		)
+		return
+	}
+	logger.Warn(
+		context.TODO(),
+		"[go-helper] config source unavailable, fallback",
+		field.String("dataId", dataId),
+		field.String("group", group),
+		field.String("source", string(active)),
+	)
+}
+
+func (s *FallbackSource) snapshotPath(dataId, group string) string {
+	return filepath.Join(s.opts.SnapshotDir, group, dataId)
+}
+
+// fallback: 按降级策略获取配置
+func (s *FallbackSource) fallback(dataId, group string) (string, ActiveSource, bool) {
+	if s.opts.Policy == FallbackPolicy_Snapshot && s.opts.SnapshotDir != "" {
+		if b, err := os.ReadFile(s.snapshotPath(dataId, group)); err == nil {
+			return string(b), ActiveSource_Snapshot, true
+		}
+	}
+	if s.opts.Policy == FallbackPolicy_Snapshot || s.opts.Policy == FallbackPolicy_Default {
+		s.lock.Lock()
+		content, exist := s.defaults[fallbackKey(dataId, group)]
+		s.lock.Unlock()
+		if exist {
+			return content, ActiveSource_Default, true
+		}
+	}
+	return "", "", false
+}
+
+func (s *FallbackSource) Get(dataId, group string) (string, error) {
+	content, err := s.primary.Get(dataId, group)
+	if err == nil {
+		s.setActive(dataId, group, ActiveSource_Live, content)
+		return content, nil
+	}
+
+	fallback, active, ok := s.fallback(dataId, group)
+	if !ok {
+		return "", err
+	}
+	logger.Warn(
+		context.TODO(),
+		"[go-helper] get config from source failed",
+		field.String("dataId", dataId),
+		field.String("group", group),
+		field.String("err", err.Error()),
+	)
+	s.setActive(dataId, group, active, fallback)
+	return fallback, nil
+}
+
+// Watch 监听配置变更
+//
+// 配置当前来自快照或默认值、或配置源监听失败时, 后台按 RetryInterval 重连配置源,
+// 恢复后以实时配置回调 onChange。同一内容不会重复回调。
+func (s *FallbackSource) Watch(dataId, group string, onChange func(change ConfigChange)) error {
+	state := s.state(dataId, group)
+	w := &fallbackWatch{
+		source:   s,
+		dataId:   dataId,
+		group:    group,
+		onChange: onChange,
+		live:     state.active == ActiveSource_Live,
+		content:  state.content,
+	}
+
+	err := s.primary.Watch(dataId, group, w.deliver)
+	if err != nil && s.opts.Policy == FallbackPolicy_None {
+		return err
+	}
+	w.watched = err == nil
+
+	s.lock.Lock()
+	s.watches[fallbackKey(dataId, group)] = append(s.watches[fallbackKey(dataId, group)], w)
+	s.lock.Unlock()
+
+	if !w.watched || !w.live {
+		if err != nil {
+			logger.Warn(
+				context.TODO(),
+				"[go-helper] watch config from source failed, retry in background",
+				field.String("dataId", dataId),
+				field.String("group", group),
+				field.String("err", err.Error()),
+			)
+		}
+		go w.retry()
+	}
+	return nil
+}
+
+// Unwatch 取消监听并停止对应的后台重连, 被包装的配置源支持 Unwatcher 时一并取消
+func (s *FallbackSource) Unwatch(dataId, group string) error {
+	s.lock.Lock()
+	watches := s.watches[fallbackKey(dataId, group)]
+	delete(s.watches, fallbackKey(dataId, group))
+	delete(s.states, fallbackKey(dataId, group))
+	s.lock.Unlock()
+
+	for _, w := range watches {
+		w.cancel()
+	}
+	if u, ok := s.primary.(Unwatcher); ok {
+		return u.Unwatch(dataId, group)
+	}
+	return nil
+}
+
+func (s *FallbackSource) SearchGroups(dataId string) ([]string, error) {
+	groups, err := s.primary.SearchGroups(dataId)
+	if err == nil || s.opts.Policy == FallbackPolicy_None {
+		return groups, err
+	}
+
+	set := map[string]struct{}{}
+	if s.opts.Policy == FallbackPolicy_Snapshot && s.opts.SnapshotDir != "" {
+		entries, _ := os.ReadDir(s.opts.SnapshotDir)
+		for _, entry := range entries {
+			if _, e := os.Stat(s.snapshotPath(dataId, entry.Name())); entry.IsDir() && e == nil {
+				set[entry.Name()] = struct{}{}
+			}
+		}
+	}
+	s.lock.Lock()
+	for key := range s.defaults {
+		if g, d, _ := strings.Cut(key, "/"); d == dataId {
+			set[g] = struct{}{}
+		}
+	}
+	s.lock.Unlock()
+
+	if len(set) == 0 {
+		return nil, err
+	}
+	logger.Warn(
+		context.TODO(),
+		"[go-helper] search config groups from source failed, fallback",
+		field.String("dataId", dataId),
+		field.String("err", err.Error()),
+	)
+	for g := range set {
+		groups = append(groups, g)
+	}
+	return groups, nil
+}
+
+func (s *FallbackSource) Publish(param PublishParam) error {
+	return s.primary.Publish(param)
+}
+
+func (s *FallbackSource) PublishCAS(param PublishParam, casMd5 string) error {
+	if p, ok := s.primary.(CASPublisher); ok {
+		return p.PublishCAS(param, casMd5)
+	}
+	return ErrCASUnsupported
+}
+
+func (s *FallbackSource) ListHistory(dataId, group string, pageNo, pageSize int) ([]ConfigHistory, error) {
+	if h, ok := s.primary.(HistorySource); ok {
+		return h.ListHistory(dataId, group, pageNo, pageSize)
+	}
+	return nil, ErrHistoryUnsupported
+}
+
+func (s *FallbackSource) GetHistory(dataId, group, id string) (ConfigHistory, error) {
+	if h, ok := s.primary.(HistorySource); ok {
+		return h.GetHistory(dataId, group, id)
+	}
+	return ConfigHistory{}, ErrHistoryUnsupported
+}
+
+// Snapshot 将通过校验的配置写入本地快照, 只有来自配置源的实时配置才会写入
+func (s *FallbackSource) Snapshot(dataId, group, content string) {
+	if s.opts.SnapshotDir == "" || s.ActiveSource(dataId, group) != ActiveSource_Live {
+		return
+	}
+
+	if err := writeFileAtomic(s.snapshotPath(dataId, group), content); err != nil {
+		logger.Warn(
+			context.TODO(),
+			"[go-helper] write config snapshot failed",
+			field.String("dataId", dataId),
+			field.String("group", group),
+			field.String("err", err.Error()),
+		)
+	}
+}
+
+// Close 停止后台重连
+func (s *FallbackSource) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.stop)
+	})
+	return nil
+}
+
+// fallbackWatch: 单个监听器的状态
+type fallbackWatch struct {
+	source   *FallbackSource
+	dataId   string
+	group    string
+	onChange func(change ConfigChange)
+
+	lock      sync.Mutex
+	live      bool   // 是否已收到实时配置
+	watched   bool   // 是否已在配置源注册监听
+	cancelled bool   // 是否已取消监听
+	content   string // 最近一次回调的内容
+}
+
+func (w *fallbackWatch) cancel() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.cancelled = true
+}
+
+// deliver: 回调实时配置, 与上次内容相同时只切换来源不回调
+func (w *fallbackWatch) deliver(change ConfigChange) {
+	w.lock.Lock()
+	if w.cancelled {
+		w.lock.Unlock()
+		return
+	}
+	same := w.content == change.Content
+	w.live = true
+	w.content = change.Content
+	w.lock.Unlock()
+
+	w.source.setActive(w.dataId, w.group, ActiveSource_Live, change.Content)
+	if same {
+		return
+	}
+	w.onChange(change)
+}
+
+// retry: 定期重连配置源, 直到注册监听成功并获取到实时配置
+func (w *fallbackWatch) retry() {
+	ticker := time.NewTicker(w.source.opts.RetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.source.stop:
+			return
+		case <-ticker.C:
+		}
+
+		w.lock.Lock()
+		watched, live, cancelled := w.watched, w.live, w.cancelled
+		w.lock.Unlock()
+		if cancelled {
+			return
+		}
+
+		if !watched {
+			if err := w.source.primary.Watch(w.dataId, w.group, w.deliver); err == nil {
+				w.lock.Lock()
+				w.watched = true
+				w.lock.Unlock()
+				watched = true
+			}
+		}
+		if !live {
+			if content, err := w.source.primary.Get(w.dataId, w.group); err == nil {
+				w.deliver(ConfigChange{Group: w.group, DataId: w.dataId, Content: content})
+				live = true
+			}
+		}
+		if watched && live {
+			return
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package nacos
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/everfir/go-helpers/env"
+	"github.com/zeebo/assert"
+)
+
+// flakySource: 可模拟不可用的配置源
+type flakySource struct {
+	*MemorySource
+	down atomic.Bool
+}
+
+var errDown = errors.New("source is down")
+
+func (s *flakySource) Get(dataId, group string) (string, error) {
+	if s.down.Load() {
+		return "", errDown
+	}
+	return s.MemorySource.Get(dataId, group)
+}
+
+func (s *flakySource) Watch(dataId, group string, onChange func(change ConfigChange)) error {
+	if s.down.Load() {
+		return errDown
+	}
+	return s.MemorySource.Watch(dataId, group, onChange)
+}
+
+func (s *flakySource) SearchGroups(dataId string) ([]string, error) {
+	if s.down.Load() {
+		return nil, errDown
+	}
+	return s.MemorySource.SearchGroups(dataId)
+}
+
+func newFallbackSource(t *testing.T, primary ConfigSource, policy FallbackPolicy) *FallbackSource {
+	t.Helper()
+	source, err := NewFallbackSource(primary, FallbackOptions{
+		Policy:        policy,
+		SnapshotDir:   t.TempDir(),
+		RetryInterval: 10 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+	t.Cleanup(func() { source.Close() })
+	return source
+}
+
+func TestFallbackSnapshot(t *testing.T) {
+	primary := &flakySource{MemorySource: NewMemorySource()}
+	assert.NoError(t, primary.Publish(PublishParam{DataId: "shutdown.json", Group: env.Env(), Content: `{"a":true}`}))
+
+	source := newFallbackSource(t, primary, FallbackPolicy_Snapshot)
+	_, err := GetConfigAndListenFromSource[map[string]bool](source, "shutdown.json")
+	assert.NoError(t, err)
+	assert.Equal(t, source.ActiveSource("shutdown.json", env.Env()), ActiveSource_Live)
+
+	// 通过校验的配置写入快照
+	b, err := os.ReadFile(filepath.Join(source.opts.SnapshotDir, env.Env(), "shutdown.json"))
+	assert.NoError(t, err)
+	assert.Equal(t, string(b), `{"a":true}`)
+
+	// 配置源不可用时从快照启动, 恢复后切换为实时配置
+	primary.down.Store(true)
+	restarted, err := NewFallbackSource(primary, source.opts)
+	assert.NoError(t, err)
+	defer restarted.Close()
+
+	cfg, err := GetConfigAndListenFromSource[map[string]bool](restarted, "shutdown.json")
+	assert.NoError(t, err)
+	got, _ := cfg.Get()
+	assert.DeepEqual(t, got, map[string]bool{"a": true})
+	assert.Equal(t, restarted.ActiveSource("shutdown.json", env.Env()), ActiveSource_Snapshot)
+
+	assert.NoError(t, primary.MemorySource.Publish(PublishParam{DataId: "shutdown.json", Group: env.Env(), Content: `{"b":true}`}))
+	primary.down.Store(false)
+	waitFor(t, func() bool {
+		got, _ := cfg.Get()
+		return got["b"]
+	})
+	assert.Equal(t, restarted.ActiveSource("shutdown.json", env.Env()), ActiveSource_Live)
+
+	// 恢复后的监听正常生效
+	assert.NoError(t, primary.Publish(PublishParam{DataId: "shutdown.json", Group: env.Env(), Content: `{"c":true}`}))
+	got, _ = cfg.Get()
+	assert.DeepEqual(t, got, map[string]bool{"c": true})
+}
+
+// positiveTimeout: timeout 必须大于 0
+type positiveTimeout struct {
+	Timeout int `json:"timeout"`
+}
+
+func (c *positiveTimeout) Validate() error {
+	if c.Timeout <= 0 {
+		return errors.New("timeout should be positive")
+	}
+	return nil
+}
+
+func TestFallbackOverlaySnapshot(t *testing.T) {
+	primary := &flakySource{MemorySource: NewMemorySource()}
+	assert.NoError(t, primary.Publish(PublishParam{DataId: "app.json", Group: env.Env(), Content: `{"timeout":5}`}))
+
+	source := newFallbackSource(t, primary, FallbackPolicy_Snapshot)
+	cfg, err := GetConfigAndListenFromSource[positiveTimeout](source, "app.json", WithOverlay(), WithDiscoveryInterval(0))
+	assert.NoError(t, err)
+	path := filepath.Join(source.opts.SnapshotDir, env.Env(), "app.json")
+	b, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, string(b), `{"timeout":5}`)
+
+	// 未通过校验的层不写入快照
+	assert.NoError(t, primary.Publish(PublishParam{DataId: "app.json", Group: env.Env(), Content: `{"timeout":-1}`}))
+	got, _ := cfg.Get()
+	assert.Equal(t, got.Timeout, 5)
+	b, err = os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, string(b), `{"timeout":5}`)
+
+	assert.NoError(t, primary.Publish(PublishParam{DataId: "app.json", Group: env.Env(), Content: `{"timeout":7}`}))
+	b, err = os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, string(b), `{"timeout":7}`)
+}
+
+func TestFallbackDefault(t *testing.T) {
+	primary := &flakySource{MemorySource: NewMemorySource()}
+	primary.down.Store(true)
+
+	source := newFallbackSource(t, primary, FallbackPolicy_Default)
+	cfg, err := GetConfigAndListenFromSource[map[string]bool](source, "shutdown.json", WithDefault(map[string]bool{"d": true}))
+	assert.NoError(t, err)
+	got, _ := cfg.Get()
+	assert.DeepEqual(t, got, map[string]bool{"d": true})
+	assert.Equal(t, source.ActiveSource("shutdown.json", env.Env()), ActiveSource_Default)
+
+	gray, err := GetConfigAndListenWithGrayFromSource[map[string]bool](source, "shutdown.json", WithDefault(map[string]bool{"d": true}))
+	assert.NoError(t, err)
+	got, _ = gray.Get()
+	assert.DeepEqual(t, got, map[string]bool{"d": true})
+
+	// 不降级时保持原有行为
+	none := newFallbackSource(t, primary, FallbackPolicy_None)
+	_, err = GetConfigAndListenFromSource[map[string]bool](none, "shutdown.json", WithDefault(map[string]bool{}))
+	assert.Error(t, err)
+}
@@ -0,0 +1,29 @@
+//go:build go1.23
+
+package nacos
+
+import (
+	"context"
+	"testing"
+
+	"github.com/everfir/go-helpers/env"
+	"github.com/zeebo/assert"
+)
+
+func TestValues(t *testing.T) {
+	source := NewMemorySource()
+	assert.NoError(t, source.Publish(PublishParam{DataId: "shutdown.json", Group: env.Env(), Content: `{"a":false}`}))
+
+	cfg, err := GetConfigAndListenFromSource[map[string]bool](source, "shutdown.json")
+	assert.NoError(t, err)
+
+	var values []map[string]bool
+	for v := range cfg.Values(context.Background()) {
+		values = append(values, v)
+		if len(values) == 2 {
+			break
+		}
+		assert.NoError(t, source.Publish(PublishParam{DataId: "shutdown.json", Group: env.Env(), Content: `{"a":true}`}))
+	}
+	assert.DeepEqual(t, values, []map[string]bool{{"a": false}, {"a": true}})
+}
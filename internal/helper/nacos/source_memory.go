@@ -0,0 +1,155 @@
+package nacos
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// NewMemorySource 创建进程内配置源，适用于测试
+//
+// Publish 会同步回调该配置的所有监听器。
+func NewMemorySource() *MemorySource {
+	return &MemorySource{
+		data:      map[string]map[string]string{},
+		history:   map[string][]ConfigHistory{},
+		listeners: map[string][]func(change ConfigChange){},
+	}
+}
+
+type MemorySource struct {
+	lock      sync.RWMutex
+	data      map[string]map[string]string           // key: group -> dataId
+	listeners map[string][]func(change ConfigChange) // key: group/dataId
+	history   map[string][]ConfigHistory             // key: group/dataId, 按发布顺序
+}
+
+func (s *MemorySource) Get(dataId, group string) (string, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	content, exist := s.data[group][dataId]
+	if !exist {
+		return "", fmt.Errorf("[go-helper] config not found, dataId:%s group:%s", dataId, group)
+	}
+	return content, nil
+}
+
+func (s *MemorySource) Watch(dataId, group string, onChange func(change ConfigChange)) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	key := group + "/" + dataId
+	s.listeners[key] = append(s.listeners[key], onChange)
+	return nil
+}
+
+func (s *MemorySource) Unwatch(dataId, group string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.listeners, group+"/"+dataId)
+	return nil
+}
+
+// Delete 删除配置, 不会回调监听器
+func (s *MemorySource) Delete(dataId, group string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.data[group], dataId)
+}
+
+// ListHistory 按时间倒序列出历史版本, 不包含内容
+func (s *MemorySource) ListHistory(dataId, group string, pageNo, pageSize int) ([]ConfigHistory, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	all := s.history[group+"/"+dataId]
+	var histories []ConfigHistory
+	for i := len(all) - 1 - (pageNo-1)*pageSize; i >= 0 && len(histories) < pageSize; i-- {
+		h := all[i]
+		h.Content = ""
+		histories = append(histories, h)
+	}
+	return histories, nil
+}
+
+func (s *MemorySource) GetHistory(dataId, group, id string) (ConfigHistory, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	for _, h := range s.history[group+"/"+dataId] {
+		if h.Id == id {
+			return h, nil
+		}
+	}
+	return ConfigHistory{}, fmt.Errorf("[go-helper] config history not found, dataId:%s group:%s id:%s", dataId, group, id)
+}
+
+func (s *MemorySource) SearchGroups(dataId string) ([]string, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	var groups []string
+	for group, configs := range s.data {
+		if _, exist := configs[dataId]; exist {
+			groups = append(groups, group)
+		}
+	}
+	sort.Strings(groups)
+	return groups, nil
+}
+
+func (s *MemorySource) Publish(param PublishParam) error {
+	return s.publish(param, nil)
+}
+
+func (s *MemorySource) PublishCAS(param PublishParam, casMd5 string) error {
+	return s.publish(param, &casMd5)
+}
+
+// publish: casMd5 不为空时校验当前内容的 md5
+func (s *MemorySource) publish(param PublishParam, casMd5 *string) error {
+	s.lock.Lock()
+	if casMd5 != nil {
+		current, exist := s.data[param.Group][param.DataId]
+		if (exist && contentMd5(current) != *casMd5) || (!exist && *casMd5 != "") {
+			s.lock.Unlock()
+			return ErrCASConflict
+		}
+	}
+	if _, exist := s.data[param.Group]; !exist {
+		s.data[param.Group] = map[string]string{}
+	}
+	opType := "U"
+	if _, exist := s.data[param.Group][param.DataId]; !exist {
+		opType = "I"
+	}
+	s.data[param.Group][param.DataId] = param.Content
+
+	key := param.Group + "/" + param.DataId
+	s.history[key] = append(s.history[key], ConfigHistory{
+		Id:          strconv.Itoa(len(s.history[key]) + 1),
+		DataId:      param.DataId,
+		Group:       param.Group,
+		Md5:         contentMd5(param.Content),
+		Content:     param.Content,
+		OpType:      opType,
+		CreatedTime: time.Now().Format(time.RFC3339),
+	})
+	listeners := append([]func(change ConfigChange){}, s.listeners[param.Group+"/"+param.DataId]...)
+	s.lock.Unlock()
+
+	change := ConfigChange{
+		Group:   param.Group,
+		DataId:  param.DataId,
+		Content: param.Content,
+	}
+	for _, onChange := range listeners {
+		onChange(change)
+	}
+	return nil
+}
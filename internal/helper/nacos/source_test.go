@@ -0,0 +1,494 @@
+package nacos
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/everfir/go-helpers/consts"
+	nacos_config "github.com/everfir/go-helpers/define/config"
+	"github.com/everfir/go-helpers/env"
+	"github.com/everfir/go-helpers/internal/helper/codec"
+	"github.com/everfir/go-helpers/internal/helper/secret"
+	internal_config "github.com/everfir/go-helpers/internal/structs/config"
+	"github.com/zeebo/assert"
+)
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before deadline")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestMemorySource(t *testing.T) {
+	source := NewMemorySource()
+	assert.NoError(t, source.Publish(PublishParam{DataId: "shutdown.json", Group: env.Env(), Content: `{"a":true}`}))
+	assert.NoError(t, source.Publish(PublishParam{DataId: "shutdown.json", Group: env.Env() + "_b", Content: `{"b":true}`}))
+
+	cfg, err := GetConfigAndListenWithGrayFromSource[map[string]bool](source, "shutdown.json")
+	assert.NoError(t, err)
+
+	data, exist := cfg.Get(consts.TrafficGroup_B)
+	assert.True(t, exist)
+	assert.True(t, data["b"])
+
+	// 发布后同步回调监听器
+	assert.NoError(t, source.Publish(PublishParam{DataId: "shutdown.json", Group: env.Env(), Content: `{"c":true}`}))
+	data, _ = cfg.Get()
+	assert.True(t, data["c"])
+}
+
+func TestDirSource(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, env.Env()), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, env.Env(), "shutdown.json"), []byte(`{"a":true}`), 0o644))
+
+	source, err := NewDirSource(dir)
+	assert.NoError(t, err)
+	defer source.Close()
+
+	groups, err := source.SearchGroups("shutdown.json")
+	assert.NoError(t, err)
+	assert.DeepEqual(t, groups, []string{env.Env()})
+
+	cfg, err := GetConfigAndListenFromSource[map[string]bool](source, "shutdown.json")
+	assert.NoError(t, err)
+	data, _ := cfg.Get()
+	assert.True(t, data["a"])
+
+	assert.NoError(t, source.Publish(PublishParam{DataId: "shutdown.json", Group: env.Env(), Content: `{"b":true}`}))
+	waitFor(t, func() bool {
+		data, _ := cfg.Get()
+		return data["b"]
+	})
+}
+
+func TestEnvSource(t *testing.T) {
+	t.Setenv(EnvSourceKey("shutdown.json", "test_b"), `{"a":true}`)
+	assert.Equal(t, EnvSourceKey("shutdown.json", "test_b"), "EVERFIR_CONFIG_TEST_B_SHUTDOWN_JSON")
+
+	source := NewEnvSource()
+	content, err := source.Get("shutdown.json", "test_b")
+	assert.NoError(t, err)
+	assert.Equal(t, content, `{"a":true}`)
+
+	groups, err := source.SearchGroups("shutdown.json")
+	assert.NoError(t, err)
+	assert.DeepEqual(t, groups, []string{"test_b"})
+
+	assert.Error(t, source.Publish(PublishParam{DataId: "shutdown.json", Group: "test_b"}))
+}
+
+type yamlConfig struct {
+	Name string `json:"name"`
+}
+
+func (c *yamlConfig) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	return nil
+}
+
+func TestFormat(t *testing.T) {
+	source := NewMemorySource()
+	assert.NoError(t, Publish(source, "app.yaml", yamlConfig{Name: "a"}))
+
+	content, err := source.Get("app.yaml", env.Env())
+	assert.NoError(t, err)
+	assert.Equal(t, content, "name: a\n")
+
+	cfg, err := GetConfigAndListenFromSource[yamlConfig](source, "app.yaml")
+	assert.NoError(t, err)
+	got, _ := cfg.Get()
+	assert.Equal(t, got.Name, "a")
+
+	// 校验失败的变更被忽略
+	assert.NoError(t, source.Publish(PublishParam{DataId: "app.yaml", Group: env.Env(), Content: "name: ''\n"}))
+	got, _ = cfg.Get()
+	assert.Equal(t, got.Name, "a")
+
+	assert.NoError(t, source.Publish(PublishParam{DataId: "app.yaml", Group: env.Env(), Content: "name: b\n"}))
+	got, _ = cfg.Get()
+	assert.Equal(t, got.Name, "b")
+
+	// 显式指定格式优先于扩展名
+	assert.NoError(t, source.Publish(PublishParam{DataId: "app", Group: env.Env(), Content: "name = \"c\"\n"}))
+	toml, err := GetConfigAndListenFromSource[yamlConfig](source, "app", WithFormat(codec.Format_TOML))
+	assert.NoError(t, err)
+	got, _ = toml.Get()
+	assert.Equal(t, got.Name, "c")
+}
+
+func TestChangeEvent(t *testing.T) {
+	source := NewMemorySource()
+	assert.NoError(t, source.Publish(PublishParam{DataId: "shutdown.json", Group: env.Env(), Content: `{"a":false}`}))
+
+	cfg, err := GetConfigAndListenFromSource[map[string]bool](source, "shutdown.json")
+	assert.NoError(t, err)
+
+	var event internal_config.ChangeEvent[map[string]bool]
+	cfg.RegisterChangeListener("test", internal_config.ChangeListenerFunc[map[string]bool](func(e internal_config.ChangeEvent[map[string]bool]) {
+		event = e
+	}))
+
+	assert.NoError(t, source.Publish(PublishParam{DataId: "shutdown.json", Group: env.Env(), Content: `{"a":true}`}))
+	cfg.Flush()
+	assert.Equal(t, event.DataId, "shutdown.json")
+	assert.Equal(t, event.Group, env.Env())
+	assert.Equal(t, event.Md5, contentMd5(`{"a":true}`))
+	assert.Equal(t, event.Content, `{"a":true}`)
+	assert.DeepEqual(t, event.Old, map[string]bool{"a": false})
+	assert.DeepEqual(t, event.Diff, []internal_config.FieldDiff{{Path: "/a", Op: internal_config.DiffOp_Replace, Old: false, New: true}})
+}
+
+func TestGroupDiscovery(t *testing.T) {
+	source := NewMemorySource()
+	assert.NoError(t, source.Publish(PublishParam{DataId: "shutdown.json", Group: env.Env(), Content: `{"a":true}`}))
+
+	cfg, err := GetConfigAndListenWithGrayFromSource[map[string]bool](source, "shutdown.json", WithDiscoveryInterval(10*time.Millisecond))
+	assert.NoError(t, err)
+	defer cfg.Close()
+
+	var lock sync.Mutex
+	var events []internal_config.ChangeEvent[map[string]bool]
+	cfg.RegisterChangeListener("test", internal_config.ChangeListenerFunc[map[string]bool](func(e internal_config.ChangeEvent[map[string]bool]) {
+		lock.Lock()
+		defer lock.Unlock()
+		events = append(events, e)
+	}), consts.TrafficGroup_C)
+
+	got, exist := cfg.Get(consts.TrafficGroup_C)
+	assert.False(t, exist)
+	assert.DeepEqual(t, got, map[string]bool{"a": true})
+
+	// 新增分组被发现并通知监听器
+	group := env.Env() + "_c"
+	assert.NoError(t, source.Publish(PublishParam{DataId: "shutdown.json", Group: group, Content: `{"c":true}`}))
+	waitFor(t, func() bool {
+		_, exist := cfg.Get(consts.TrafficGroup_C)
+		return exist
+	})
+	got, _ = cfg.Get(consts.TrafficGroup_C)
+	assert.DeepEqual(t, got, map[string]bool{"c": true})
+
+	cfg.Flush()
+	lock.Lock()
+	assert.Equal(t, len(events), 1)
+	assert.DeepEqual(t, events[0].Old, map[string]bool{"a": true})
+	assert.DeepEqual(t, events[0].New, map[string]bool{"c": true})
+	assert.Equal(t, events[0].Group, group)
+	lock.Unlock()
+
+	// 新分组的变更正常生效
+	assert.NoError(t, source.Publish(PublishParam{DataId: "shutdown.json", Group: group, Content: `{"c":false}`}))
+	got, _ = cfg.Get(consts.TrafficGroup_C)
+	assert.DeepEqual(t, got, map[string]bool{"c": false})
+	cfg.Flush()
+
+	// 删除的分组被移除, 降级为默认分组
+	source.Delete("shutdown.json", group)
+	waitFor(t, func() bool {
+		_, exist := cfg.Get(consts.TrafficGroup_C)
+		return !exist
+	})
+	waitFor(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(events) == 3
+	})
+	lock.Lock()
+	assert.DeepEqual(t, events[2].New, map[string]bool{"a": true})
+	lock.Unlock()
+	assert.DeepEqual(t, cfg.Groups(), []string{env.Env()})
+}
+
+type overlayConfig struct {
+	Timeout int               `json:"timeout"`
+	Limit   int64             `json:"limit"`
+	Hosts   []string          `json:"hosts"`
+	Labels  map[string]string `json:"labels,omitempty"`
+}
+
+func TestOverlay(t *testing.T) {
+	source := NewMemorySource()
+	base, groupB, momo := env.Env(), env.Env()+"_b", env.Env()+".momo"
+	assert.NoError(t, source.Publish(PublishParam{DataId: "app.yaml", Group: base, Content: `
+timeout: 5
+limit: 9007199254740993
+hosts: [a, b]
+labels: {team: core, tier: web}
+`}))
+	assert.NoError(t, source.Publish(PublishParam{DataId: "app.yaml", Group: groupB, Content: "timeout: 10\nlabels: {tier: null}\n"}))
+	assert.NoError(t, source.Publish(PublishParam{DataId: "app.yaml", Group: momo, Content: "hosts: [m]\n"}))
+	// 其他环境的分组不参与合并
+	assert.NoError(t, source.Publish(PublishParam{DataId: "app.yaml", Group: "other", Content: "timeout: 1\n"}))
+
+	cfg, err := GetConfigAndListenFromSource[overlayConfig](source, "app.yaml", WithOverlay(), WithDiscoveryInterval(10*time.Millisecond))
+	assert.NoError(t, err)
+	defer cfg.Close()
+	assert.DeepEqual(t, cfg.Groups(), []string{base, nacos_config.BusinessGroup(base, "momo"), groupB, nacos_config.BusinessGroup(groupB, "momo")})
+
+	got, _ := cfg.Get()
+	assert.DeepEqual(t, got, overlayConfig{Timeout: 5, Limit: 9007199254740993, Hosts: []string{"a", "b"}, Labels: map[string]string{"team": "core", "tier": "web"}})
+	got, exist := cfg.Get(consts.TrafficGroup_B)
+	assert.True(t, exist)
+	assert.DeepEqual(t, got, overlayConfig{Timeout: 10, Limit: 9007199254740993, Hosts: []string{"a", "b"}, Labels: map[string]string{"team": "core"}})
+	got, exist = cfg.GetWithBusiness("momo", consts.TrafficGroup_B)
+	assert.True(t, exist)
+	assert.DeepEqual(t, got.Hosts, []string{"m"})
+	assert.Equal(t, got.Timeout, 10)
+	got, exist = cfg.GetWithBusiness("momo", consts.TrafficGroup_C)
+	assert.True(t, exist)
+	assert.Equal(t, got.Timeout, 5)
+	got, exist = cfg.GetWithBusiness("other", consts.TrafficGroup_B)
+	assert.False(t, exist)
+	assert.Equal(t, got.Timeout, 10)
+
+	var lock sync.Mutex
+	var events []internal_config.ChangeEvent[overlayConfig]
+	cfg.RegisterChangeListener("test", internal_config.ChangeListenerFunc[overlayConfig](func(e internal_config.ChangeEvent[overlayConfig]) {
+		lock.Lock()
+		defer lock.Unlock()
+		events = append(events, e)
+	}), consts.TrafficGroup_B)
+
+	// 业务补丁变化不影响流量分组的结果, 不通知监听器
+	assert.NoError(t, source.Publish(PublishParam{DataId: "app.yaml", Group: momo, Content: "hosts: [n]\n"}))
+	got, _ = cfg.GetWithBusiness("momo", consts.TrafficGroup_B)
+	assert.DeepEqual(t, got.Hosts, []string{"n"})
+
+	// 基础配置变化重新合并所有结果, 补丁按 RFC 7386 在不存在的字段上创建对象
+	assert.NoError(t, source.Publish(PublishParam{DataId: "app.yaml", Group: base, Content: "timeout: 5\nlimit: 1\nhosts: [a]\n"}))
+	got, _ = cfg.Get(consts.TrafficGroup_B)
+	assert.DeepEqual(t, got, overlayConfig{Timeout: 10, Limit: 1, Hosts: []string{"a"}, Labels: map[string]string{}})
+	got, _ = cfg.GetWithBusiness("momo", consts.TrafficGroup_B)
+	assert.Equal(t, got.Limit, int64(1))
+	cfg.Flush()
+	lock.Lock()
+	assert.Equal(t, len(events), 1)
+	assert.DeepEqual(t, events[0].Diff, []internal_config.FieldDiff{
+		{Path: "/hosts/1", Op: internal_config.DiffOp_Remove, Old: "b"},
+		{Path: "/labels", Op: internal_config.DiffOp_Remove, Old: map[string]any{"team": "core"}},
+		{Path: "/limit", Op: internal_config.DiffOp_Replace, Old: json.Number("9007199254740993"), New: json.Number("1")},
+	})
+	lock.Unlock()
+
+	// 新增、删除的业务补丁被发现
+	assert.NoError(t, source.Publish(PublishParam{DataId: "app.yaml", Group: env.Env() + ".yoyo", Content: "timeout: 7\n"}))
+	waitFor(t, func() bool {
+		_, exist := cfg.GetWithBusiness("yoyo")
+		return exist
+	})
+	got, _ = cfg.GetWithBusiness("yoyo", consts.TrafficGroup_B)
+	assert.Equal(t, got.Timeout, 7)
+
+	source.Delete("app.yaml", momo)
+	waitFor(t, func() bool {
+		_, exist := cfg.GetWithBusiness("momo")
+		return !exist
+	})
+	assert.Equal(t, len(cfg.Groups()), 4)
+}
+
+func TestGetCtx(t *testing.T) {
+	source := NewMemorySource()
+	assert.NoError(t, source.Publish(PublishParam{DataId: "app.json", Group: env.Env(), Content: `{"timeout":5}`}))
+	assert.NoError(t, source.Publish(PublishParam{DataId: "app.json", Group: env.Env() + "_b", Content: `{"timeout":10}`}))
+	assert.NoError(t, source.Publish(PublishParam{DataId: "app.json", Group: env.Env() + ".momo", Content: `{"hosts":["m"]}`}))
+
+	cfg, err := GetConfigAndListenFromSource[overlayConfig](source, "app.json", WithOverlay(), WithDiscoveryInterval(0))
+	assert.NoError(t, err)
+
+	// A 组命中当前环境的默认配置
+	ctx := context.Background()
+	got, exist := cfg.GetCtx(ctx)
+	assert.True(t, exist)
+	assert.Equal(t, got.Timeout, 5)
+
+	// 分组配置不存在时降级为默认配置
+	got, exist = cfg.GetCtx(context.WithValue(ctx, consts.ExperimentGroupKey, consts.TrafficGroup_C))
+	assert.False(t, exist)
+	assert.Equal(t, got.Timeout, 5)
+
+	// 默认按上下文中的实验分组选择
+	groupB := context.WithValue(ctx, consts.ExperimentGroupKey, consts.TrafficGroup_B)
+	got, exist = cfg.GetCtx(groupB)
+	assert.True(t, exist)
+	assert.Equal(t, got.Timeout, 10)
+
+	// 带有业务时合并业务补丁
+	got, exist = cfg.GetCtx(context.WithValue(groupB, consts.BusinessKey, "momo"))
+	assert.True(t, exist)
+	assert.Equal(t, got.Timeout, 10)
+	assert.DeepEqual(t, got.Hosts, []string{"m"})
+
+	// 自定义分组选择, 例如按功能实验分桶
+	cfg.SetGroupSelector(func(ctx context.Context) consts.TrafficGroup { return consts.TrafficGroup_B })
+	got, _ = cfg.GetCtx(ctx)
+	assert.Equal(t, got.Timeout, 10)
+	cfg.SetGroupSelector(nil)
+	got, _ = cfg.GetCtx(ctx)
+	assert.Equal(t, got.Timeout, 5)
+}
+
+// receive: 从 channel 读取一个值, 超时失败
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case v, ok := <-ch:
+		assert.True(t, ok)
+		return v
+	case <-time.After(3 * time.Second):
+		t.Fatal("no value before deadline")
+	}
+	panic("unreachable")
+}
+
+func TestWatch(t *testing.T) {
+	source := NewMemorySource()
+	assert.NoError(t, source.Publish(PublishParam{DataId: "shutdown.json", Group: env.Env(), Content: `{"a":false}`}))
+
+	cfg, err := GetConfigAndListenFromSource[map[string]bool](source, "shutdown.json")
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := cfg.Watch(ctx)
+
+	// 先收到当前配置, 之后收到每次变更
+	assert.DeepEqual(t, receive(t, ch), map[string]bool{"a": false})
+	assert.NoError(t, source.Publish(PublishParam{DataId: "shutdown.json", Group: env.Env(), Content: `{"a":true}`}))
+	assert.DeepEqual(t, receive(t, ch), map[string]bool{"a": true})
+
+	// 读取不及时时只收到最新配置
+	assert.NoError(t, source.Publish(PublishParam{DataId: "shutdown.json", Group: env.Env(), Content: `{"b":true}`}))
+	assert.NoError(t, source.Publish(PublishParam{DataId: "shutdown.json", Group: env.Env(), Content: `{"c":true}`}))
+	got := receive(t, ch)
+	if got["b"] {
+		// 读取 b 时 c 尚未生效
+		got = receive(t, ch)
+	}
+	assert.DeepEqual(t, got, map[string]bool{"c": true})
+
+	// ctx 结束后注销监听器并关闭 channel
+	cancel()
+	waitFor(t, func() bool {
+		select {
+		case _, ok := <-ch:
+			return !ok
+		default:
+			return false
+		}
+	})
+}
+
+type defaultsConfig struct {
+	Name    string        `json:"name" default:"account"`
+	Enabled bool          `json:"enabled" default:"true"`
+	Timeout time.Duration `json:"timeout" default:"5s" env:"TEST_NACOS_TIMEOUT"`
+}
+
+func (c *defaultsConfig) Validate() error {
+	return (&yamlConfig{Name: c.Name}).Validate()
+}
+
+func TestDefaults(t *testing.T) {
+	t.Setenv("TEST_NACOS_TIMEOUT", "")
+	source := NewMemorySource()
+	assert.NoError(t, source.Publish(PublishParam{DataId: "app.json", Group: env.Env(), Content: `{}`}))
+
+	// 默认值在 Validator 之前补全
+	cfg, err := GetConfigAndListenFromSource[defaultsConfig](source, "app.json")
+	assert.NoError(t, err)
+	got, _ := cfg.Get()
+	assert.Equal(t, got, defaultsConfig{Name: "account", Enabled: true, Timeout: 5 * time.Second})
+
+	// 环境变量覆盖配置内容
+	t.Setenv("TEST_NACOS_TIMEOUT", "1m")
+	assert.NoError(t, source.Publish(PublishParam{DataId: "app.json", Group: env.Env(), Content: `{"name":"a","timeout":1000}`}))
+	got, _ = cfg.Get()
+	assert.Equal(t, got, defaultsConfig{Name: "a", Enabled: true, Timeout: time.Minute})
+
+	// 环境变量无效时拒绝变更
+	t.Setenv("TEST_NACOS_TIMEOUT", "soon")
+	assert.NoError(t, source.Publish(PublishParam{DataId: "app.json", Group: env.Env(), Content: `{"name":"b"}`}))
+	got, _ = cfg.Get()
+	assert.Equal(t, got.Name, "a")
+
+	// 配置中显式的 false、0 不使用默认值
+	t.Setenv("TEST_NACOS_TIMEOUT", "")
+	assert.NoError(t, source.Publish(PublishParam{DataId: "app.yaml", Group: env.Env(), Content: "name: c\nenabled: false\ntimeout: 0\n"}))
+	yamlCfg, err := GetConfigAndListenFromSource[defaultsConfig](source, "app.yaml")
+	assert.NoError(t, err)
+	got, _ = yamlCfg.Get()
+	assert.Equal(t, got, defaultsConfig{Name: "c"})
+}
+
+type encryptedConfig struct {
+	User     string `json:"user"`
+	Password string `json:"password"`
+	ApiKey   string `json:"api_key" encrypted:"true"`
+}
+
+func (c *encryptedConfig) Validate() error {
+	if secret.IsEncrypted(c.Password) {
+		return fmt.Errorf("password is not decrypted")
+	}
+	return nil
+}
+
+func TestEncrypted(t *testing.T) {
+	keyring, err := secret.NewKeyring("k1", map[string][]byte{"k1": []byte("0123456789abcdef0123456789abcdef")})
+	assert.NoError(t, err)
+	secret.SetKeyring(keyring)
+	defer secret.SetKeyring(nil)
+
+	password, _ := keyring.Encrypt("p@ss")
+	apiKey, _ := keyring.Encrypt("key")
+	content := fmt.Sprintf(`{"user":"root","password":%q,"api_key":%q}`, password, strings.TrimPrefix(apiKey, secret.EncryptedPrefix))
+	source := NewMemorySource()
+	assert.NoError(t, source.Publish(PublishParam{DataId: "db.json", Group: env.Env(), Content: content}))
+
+	// 解密在 Validator 之前执行
+	cfg, err := GetConfigAndListenFromSource[encryptedConfig](source, "db.json")
+	assert.NoError(t, err)
+	got, _ := cfg.Get()
+	assert.Equal(t, got, encryptedConfig{User: "root", Password: "p@ss", ApiKey: "key"})
+
+	// PublishCAS 按加载流程校验解密后的配置, 发布的仍是密文
+	result, err := PublishCAS(source, "db.json", func(data *encryptedConfig) error {
+		assert.Equal(t, data.Password, password)
+		data.User = "admin"
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.True(t, strings.Contains(result.Content, password))
+	cfg.Flush()
+	got, _ = cfg.Get()
+	assert.Equal(t, got, encryptedConfig{User: "admin", Password: "p@ss", ApiKey: "key"})
+
+	// 无法解密时拒绝变更
+	assert.NoError(t, source.Publish(PublishParam{DataId: "db.json", Group: env.Env(), Content: `{"user":"admin","password":"enc:k2:AAAA"}`}))
+	got, _ = cfg.Get()
+	assert.Equal(t, got.User, "admin")
+	_, err = PublishCAS(source, "db.json", func(data *encryptedConfig) error { return nil })
+	assert.Error(t, err)
+
+	// 没有配置密钥时加载失败
+	other, err := secret.NewKeyring("k2", map[string][]byte{"k2": []byte("0123456789abcdef")})
+	assert.NoError(t, err)
+	secret.SetKeyring(other)
+	_, err = GetConfigAndListenFromSource[encryptedConfig](source, "db.json")
+	assert.Error(t, err)
+}
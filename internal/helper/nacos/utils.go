@@ -43,25 +43,9 @@ var namespaceMapping func() map[string]string = sync.OnceValue(func() map[string
 	}
 })
 
-var authMapping func() map[string][]string = sync.OnceValue(func() map[string][]string {
-	switch env.Idc() {
-	case consts.IDC_BJ:
-		return map[string][]string{
-			consts.EnvTest: []string{"nacos", "EverFir@Nacos20240717.."},
-			consts.EnvProd: []string{"nacos", `BNg%d59=`},
-		}
-	case consts.IDC_RF:
-		return map[string][]string{
-			consts.EnvTest: []string{"nacos", "EverFir@Nacos20240717.."},
-			consts.EnvProd: []string{"nacos", "*60gdE8q"},
-		}
-	default:
-		return map[string][]string{}
-	}
-})
-
+// NacosIp: EVERFIR_NACOS_IP 或 IDC 内置地址, 完整的连接配置见 ClientOptionsFromEnv
 func NacosIp() string {
-	ret := os.Getenv("EVERFIR_NACOS_IP")
+	ret := os.Getenv(NacosIpKey)
 	if ret == "" {
 		ret = ipMapping()[env.Env()]
 	}
@@ -69,17 +53,9 @@ func NacosIp() string {
 }
 
 func Namespace() string {
-	ret := os.Getenv("EVERFIR_NACOS_NAMESPACE")
+	ret := os.Getenv(NacosNamespaceKey)
 	if ret == "" {
 		ret = namespaceMapping()[env.Env()]
 	}
 	return ret
 }
-
-func AuthInfo() (username, passward string) {
-	info := authMapping()[env.Env()]
-	if len(info) == 0 {
-		return
-	}
-	return info[0], info[1]
-}
@@ -0,0 +1,118 @@
+package secret
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// DecryptFields 使用默认密钥集合(DefaultKeyring)解密配置中的加密值, v 需要是指针,
+// 在解析配置、补全默认值之后, Validator 之前执行:
+//   - 带有 enc: 前缀的字符串, 包括结构体字段、切片元素、map 值以及 any 中的字符串
+//   - 带有 `encrypted:"true"` 的字段, 可以省略 enc: 前缀, 值不为空且无法解密时返回错误
+//
+// 没有加密值时不需要配置密钥; 存在加密值但没有配置密钥时返回错误
+func DecryptFields(v any) error {
+	return decrypter{keyring: DefaultKeyring}.run(v)
+}
+
+// DecryptFields 使用指定的密钥集合解密配置中的加密值, 规则与包级 DecryptFields 一致
+func (k *Keyring) DecryptFields(v any) error {
+	return decrypter{keyring: func() (*Keyring, error) { return k, nil }}.run(v)
+}
+
+type decrypter struct {
+	keyring func() (*Keyring, error)
+}
+
+func (d decrypter) run(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return nil
+	}
+	return d.walk(rv.Elem(), "", false)
+}
+
+func (d decrypter) walk(v reflect.Value, path string, tagged bool) error {
+	switch v.Kind() {
+	case reflect.String:
+		s := v.String()
+		if s == "" || (!tagged && !IsEncrypted(s)) {
+			return nil
+		}
+		plaintext, err := d.decrypt(s, path)
+		if err != nil {
+			return err
+		}
+		v.SetString(plaintext)
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		return d.walk(v.Elem(), path, tagged)
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		elem := reflect.New(v.Elem().Type()).Elem()
+		elem.Set(v.Elem())
+		if err := d.walk(elem, path, tagged); err != nil {
+			return err
+		}
+		v.Set(elem)
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if !sf.IsExported() {
+				continue
+			}
+			name := strings.TrimPrefix(path+"."+sf.Name, ".")
+			if err := d.walk(v.Field(i), name, sf.Tag.Get(EncryptedTag) == "true"); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := d.walk(v.Index(i), fmt.Sprintf("%s[%d]", path, i), tagged); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		// map 的值不可寻址, 复制后解密再写回
+		for _, key := range v.MapKeys() {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(v.MapIndex(key))
+			if err := d.walk(elem, fmt.Sprintf("%s[%v]", path, key), tagged); err != nil {
+				return err
+			}
+			v.SetMapIndex(key, elem)
+		}
+	}
+	return nil
+}
+
+// decrypt: 解密单个值, 错误信息中不包含值的内容
+func (d decrypter) decrypt(s, path string) (string, error) {
+	k, err := d.keyring()
+	if err != nil {
+		return "", err
+	}
+	if k == nil {
+		return "", fmt.Errorf("%s is encrypted but no key configured, set %s or %s", path, KeyFileKey, KeysKey)
+	}
+	if !IsEncrypted(s) {
+		// 只有带 tag 的字段可以省略前缀, 明文可能无法按格式解析, 不返回 Decrypt 的错误避免泄露内容
+		plaintext, err := k.Decrypt(EncryptedPrefix + s)
+		if err != nil {
+			return "", fmt.Errorf("%s is not a valid encrypted value", path)
+		}
+		return plaintext, nil
+	}
+
+	plaintext, err := k.Decrypt(s)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	return plaintext, nil
+}
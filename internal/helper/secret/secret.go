@@ -0,0 +1,239 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 加密配置值的格式: enc:<keyId>:<base64(nonce + 密文)>, keyId 同时作为 AES-GCM 的附加数据
+const (
+	EncryptedPrefix = "enc:"
+	EncryptedTag    = "encrypted" // 字段带有 `encrypted:"true"` 时必须是加密值, 明文视为错误
+)
+
+// 密钥相关的环境变量
+const (
+	// KeyFileKey: 密钥文件路径, 每行一个 "keyId:base64 密钥", # 开头为注释
+	KeyFileKey = "EVERFIR_CONFIG_KEY_FILE"
+	// KeysKey: 未指定密钥文件时从环境变量读取密钥, 格式为 "keyId:base64 密钥,keyId:base64 密钥"
+	KeysKey = "EVERFIR_CONFIG_KEYS"
+	// KeyIdKey: 加密使用的密钥 ID, 默认为第一个密钥; 解密按加密值中的 keyId 选择密钥
+	KeyIdKey = "EVERFIR_CONFIG_KEY_ID"
+)
+
+// Keyring: AES-GCM 密钥集合, 使用 primary 加密, 按加密值中的 keyId 解密
+//
+// 轮换密钥时先在所有服务中加入新密钥, 再将 primary 切换为新密钥并重新加密配置, 最后移除旧密钥
+type Keyring struct {
+	primary string
+	aeads   map[string]cipher.AEAD
+}
+
+// NewKeyring 创建密钥集合, 密钥长度需要为 16、24 或 32 字节(AES-128/192/256)
+func NewKeyring(primary string, keys map[string][]byte) (*Keyring, error) {
+	if _, exist := keys[primary]; !exist {
+		return nil, fmt.Errorf("[go-helper] primary key %s not found", primary)
+	}
+
+	k := &Keyring{primary: primary, aeads: make(map[string]cipher.AEAD, len(keys))}
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("[go-helper] invalid key id %q", id)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("[go-helper] invalid key %s: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("[go-helper] invalid key %s: %w", id, err)
+		}
+		k.aeads[id] = aead
+	}
+	return k, nil
+}
+
+// Primary 加密使用的密钥 ID
+func (k *Keyring) Primary() string {
+	return k.primary
+}
+
+// WithPrimary 返回使用指定密钥加密的密钥集合, 用于轮换时使用新密钥重新加密
+func (k *Keyring) WithPrimary(primary string) (*Keyring, error) {
+	if _, exist := k.aeads[primary]; !exist {
+		return nil, fmt.Errorf("[go-helper] primary key %s not found", primary)
+	}
+	return &Keyring{primary: primary, aeads: k.aeads}, nil
+}
+
+// KeyIds 所有密钥 ID
+func (k *Keyring) KeyIds() []string {
+	ids := make([]string, 0, len(k.aeads))
+	for id := range k.aeads {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Encrypt 使用 primary 密钥加密, 返回带 enc: 前缀的加密值
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	aead := k.aeads[k.primary]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("[go-helper] generate nonce failed: %w", err)
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(k.primary))
+	return EncryptedPrefix + k.primary + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密加密值, enc: 前缀可以省略
+func (k *Keyring) Decrypt(value string) (string, error) {
+	id, data, found := strings.Cut(strings.TrimPrefix(value, EncryptedPrefix), ":")
+	if !found {
+		return "", fmt.Errorf("[go-helper] invalid encrypted value, expect %s<keyId>:<base64>", EncryptedPrefix)
+	}
+	aead, exist := k.aeads[id]
+	if !exist {
+		return "", fmt.Errorf("[go-helper] decrypt failed: key %s not found", id)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return "", fmt.Errorf("[go-helper] decrypt failed: %w", err)
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("[go-helper] decrypt failed: ciphertext too short")
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(id))
+	if err != nil {
+		return "", fmt.Errorf("[go-helper] decrypt failed with key %s: %w", id, err)
+	}
+	return string(plaintext), nil
+}
+
+// IsEncrypted 值是否带有 enc: 前缀
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, EncryptedPrefix)
+}
+
+// GenerateKey 生成 AES-256 密钥, 返回 base64 编码
+func GenerateKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("[go-helper] generate key failed: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// KeyringFromEnv 按环境变量 EVERFIR_CONFIG_KEY_FILE、EVERFIR_CONFIG_KEYS、EVERFIR_CONFIG_KEY_ID 创建密钥集合,
+// 没有配置密钥时返回 nil
+func KeyringFromEnv() (*Keyring, error) {
+	var (
+		content string
+		sep     = ","
+	)
+	if path := os.Getenv(KeyFileKey); path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("[go-helper] read key file failed: %w", err)
+		}
+		content, sep = string(b), "\n"
+	} else {
+		content = os.Getenv(KeysKey)
+	}
+	if strings.TrimSpace(content) == "" {
+		return nil, nil
+	}
+
+	ids, keys, err := parseKeys(content, sep)
+	if err != nil {
+		return nil, err
+	}
+	primary := os.Getenv(KeyIdKey)
+	if primary == "" {
+		primary = ids[0]
+	}
+	return NewKeyring(primary, keys)
+}
+
+// parseKeys: 解析 "keyId:base64 密钥" 列表, 返回按出现顺序的 keyId
+func parseKeys(content, sep string) ([]string, map[string][]byte, error) {
+	var (
+		ids  []string
+		keys = map[string][]byte{}
+	)
+	for _, line := range strings.Split(content, sep) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		id, encoded, found := strings.Cut(line, ":")
+		if !found {
+			return nil, nil, fmt.Errorf("[go-helper] invalid key, expect <keyId>:<base64>")
+		}
+		id = strings.TrimSpace(id)
+		if _, exist := keys[id]; exist {
+			return nil, nil, fmt.Errorf("[go-helper] duplicate key id %s", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, nil, fmt.Errorf("[go-helper] invalid key %s: %w", id, err)
+		}
+		ids, keys[id] = append(ids, id), key
+	}
+	if len(ids) == 0 {
+		return nil, nil, fmt.Errorf("[go-helper] no key found")
+	}
+	return ids, keys, nil
+}
+
+var keyring = struct {
+	lock    sync.Mutex
+	keyring *Keyring
+	loaded  bool
+}{}
+
+// SetKeyring 设置解密配置使用的默认密钥集合, 传入 nil 时重新按环境变量创建
+func SetKeyring(k *Keyring) {
+	keyring.lock.Lock()
+	defer keyring.lock.Unlock()
+
+	keyring.keyring, keyring.loaded = k, k != nil
+}
+
+// DefaultKeyring 获取默认密钥集合, 未通过 SetKeyring 设置时按 KeyringFromEnv 创建, 没有配置密钥时返回 nil
+func DefaultKeyring() (*Keyring, error) {
+	keyring.lock.Lock()
+	defer keyring.lock.Unlock()
+
+	if keyring.loaded {
+		return keyring.keyring, nil
+	}
+	k, err := KeyringFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	keyring.keyring, keyring.loaded = k, true
+	return k, nil
+}
+
+// Encrypt 使用默认密钥集合加密, 用于发布加密的配置值; 没有配置密钥时返回错误
+func Encrypt(plaintext string) (string, error) {
+	k, err := DefaultKeyring()
+	if err != nil {
+		return "", err
+	}
+	if k == nil {
+		return "", fmt.Errorf("[go-helper] no key configured, set %s or %s", KeyFileKey, KeysKey)
+	}
+	return k.Encrypt(plaintext)
+}
@@ -0,0 +1,169 @@
+package secret
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zeebo/assert"
+)
+
+func newKeyring(t *testing.T, primary string, ids ...string) *Keyring {
+	keys := map[string][]byte{}
+	for i, id := range ids {
+		keys[id] = bytes.Repeat([]byte{byte(i + 1)}, 32)
+	}
+	k, err := NewKeyring(primary, keys)
+	assert.NoError(t, err)
+	return k
+}
+
+func TestKeyring(t *testing.T) {
+	k1 := newKeyring(t, "k1", "k1")
+	encrypted, err := k1.Encrypt("p@ss")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(encrypted, "enc:k1:"))
+	assert.True(t, IsEncrypted(encrypted))
+
+	plaintext, err := k1.Decrypt(encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, plaintext, "p@ss")
+
+	// 每次加密使用不同的 nonce
+	again, _ := k1.Encrypt("p@ss")
+	assert.True(t, again != encrypted)
+
+	// 轮换: 新密钥加密, 旧密钥加密的值仍可解密
+	rotated := newKeyring(t, "k2", "k1", "k2")
+	assert.DeepEqual(t, rotated.KeyIds(), []string{"k1", "k2"})
+	plaintext, err = rotated.Decrypt(encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, plaintext, "p@ss")
+	encrypted2, _ := rotated.Encrypt("p@ss")
+	assert.True(t, strings.HasPrefix(encrypted2, "enc:k2:"))
+	_, err = k1.Decrypt(encrypted2)
+	assert.Error(t, err)
+
+	old, err := rotated.WithPrimary("k1")
+	assert.NoError(t, err)
+	encrypted3, _ := old.Encrypt("p@ss")
+	assert.True(t, strings.HasPrefix(encrypted3, "enc:k1:"))
+	_, err = rotated.WithPrimary("k3")
+	assert.Error(t, err)
+
+	// 篡改 keyId 或密文
+	_, err = rotated.Decrypt(strings.Replace(encrypted2, "enc:k2:", "enc:k1:", 1))
+	assert.Error(t, err)
+	_, err = rotated.Decrypt(encrypted2[:len(encrypted2)-4] + "AAA=")
+	assert.Error(t, err)
+	_, err = rotated.Decrypt("enc:k2")
+	assert.Error(t, err)
+
+	_, err = NewKeyring("k1", map[string][]byte{"k1": []byte("short")})
+	assert.Error(t, err)
+	_, err = NewKeyring("k2", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)})
+	assert.Error(t, err)
+}
+
+func TestKeyringFromEnv(t *testing.T) {
+	key1, _ := GenerateKey()
+	key2, _ := GenerateKey()
+
+	t.Setenv(KeyFileKey, "")
+	t.Setenv(KeysKey, "")
+	k, err := KeyringFromEnv()
+	assert.NoError(t, err)
+	assert.Nil(t, k)
+
+	t.Setenv(KeysKey, "k1:"+key1+", k2:"+key2)
+	k, err = KeyringFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, k.Primary(), "k1")
+	assert.DeepEqual(t, k.KeyIds(), []string{"k1", "k2"})
+
+	t.Setenv(KeyIdKey, "k2")
+	k, err = KeyringFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, k.Primary(), "k2")
+
+	// 密钥文件优先
+	path := filepath.Join(t.TempDir(), "keys")
+	assert.NoError(t, os.WriteFile(path, []byte("# config keys\nk2:"+key2+"\nk3:"+key1+"\n"), 0o600))
+	t.Setenv(KeyFileKey, path)
+	t.Setenv(KeyIdKey, "")
+	k, err = KeyringFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, k.Primary(), "k2")
+	assert.DeepEqual(t, k.KeyIds(), []string{"k2", "k3"})
+
+	assert.NoError(t, os.WriteFile(path, []byte("k1:"+key1+"\nk1:"+key2), 0o600))
+	_, err = KeyringFromEnv()
+	assert.Error(t, err)
+	assert.NoError(t, os.WriteFile(path, []byte("k1:not-base64"), 0o600))
+	_, err = KeyringFromEnv()
+	assert.Error(t, err)
+
+	raw, _ := base64.StdEncoding.DecodeString(key1)
+	assert.Equal(t, len(raw), 32)
+}
+
+type dbConfig struct {
+	User     string
+	Password string `encrypted:"true"`
+}
+
+type appConfig struct {
+	Name    string
+	ApiKey  string
+	Db      *dbConfig
+	Tokens  []string
+	Headers map[string]string
+	Extra   map[string]any
+	secret  string
+}
+
+func TestDecryptFields(t *testing.T) {
+	k := newKeyring(t, "k1", "k1")
+	enc := func(s string) string {
+		v, err := k.Encrypt(s)
+		assert.NoError(t, err)
+		return v
+	}
+	withoutPrefix := strings.TrimPrefix(enc("db-pass"), EncryptedPrefix)
+
+	cfg := &appConfig{
+		Name:    "app",
+		ApiKey:  enc("api-key"),
+		Db:      &dbConfig{User: "root", Password: withoutPrefix},
+		Tokens:  []string{"plain", enc("token")},
+		Headers: map[string]string{"X-Key": enc("header")},
+		Extra:   map[string]any{"nested": map[string]any{"secret": enc("nested")}, "n": 1.0},
+		secret:  enc("ignored"),
+	}
+	unexported := cfg.secret
+	assert.NoError(t, k.DecryptFields(cfg))
+	assert.Equal(t, cfg.Name, "app")
+	assert.Equal(t, cfg.ApiKey, "api-key")
+	assert.Equal(t, cfg.Db.User, "root")
+	assert.Equal(t, cfg.Db.Password, "db-pass")
+	assert.DeepEqual(t, cfg.Tokens, []string{"plain", "token"})
+	assert.Equal(t, cfg.Headers["X-Key"], "header")
+	assert.DeepEqual(t, cfg.Extra, map[string]any{"nested": map[string]any{"secret": "nested"}, "n": 1.0})
+	assert.Equal(t, cfg.secret, unexported)
+
+	// tag 标记的字段不能是明文, 错误中不包含明文
+	err := k.DecryptFields(&appConfig{Db: &dbConfig{Password: "plain:pass"}})
+	assert.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "Db.Password"))
+	assert.False(t, strings.Contains(err.Error(), "plain"))
+
+	// 没有配置密钥
+	var none *Keyring
+	assert.NoError(t, none.DecryptFields(&appConfig{Name: "app", Db: &dbConfig{}}))
+	err = none.DecryptFields(&appConfig{ApiKey: enc("api-key")})
+	assert.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "ApiKey"))
+}
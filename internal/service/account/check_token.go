@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
-	"sync"
+	"os"
+	"sync/atomic"
 	"time"
 
 	"github.com/everfir/go-helpers/consts"
@@ -18,28 +20,63 @@ import (
 	util_http "github.com/everfir/go-helpers/internal/util/http"
 )
 
-var getAccountConfig func() *config.NacosConfig[define.AccountConfig] = sync.OnceValue(func() *config.NacosConfig[define.AccountConfig] {
-	config, err := nacos.GetConfigAndListen[define.AccountConfig](nacos.GetNacosClient(), "account_config.json")
-	if err != nil {
-		panic(err.Error())
-	}
+var accountConfig = nacos.NewLazyConfig[define.AccountConfig]("account_config.json")
 
-	return config
-})
+// FallbackUrlKey: account_config.json 不可用且降级方式为 FailMode_Open 时使用的 check_token 地址
+const FallbackUrlKey = "EVERFIR_ACCOUNT_FALLBACK_URL"
 
-//const (
-//	checkTokenUrl     string = "http://user-account:8080/account/check_token"
-//	testCheckTokenUrl string = "http://101.126.81.38:10003/account/check_token"
-//)
+var failMode atomic.Int32
 
-func getTokenUrl() string {
-	cfg, _ := getAccountConfig().Get(consts.TrafficGroup_B)
+// SetFailMode 设置 account_config.json 不可用(配置源创建失败或加载失败)时 CheckToken 的降级方式:
+//   - FailMode_Closed(默认): 返回错误, 按 token 校验失败处理
+//   - FailMode_Open: 使用环境变量 EVERFIR_ACCOUNT_FALLBACK_URL 指定的地址, 未指定时同样返回错误
+func SetFailMode(mode nacos.FailMode) {
+	failMode.Store(int32(mode))
+}
 
-	if env.Prod() {
-		return cfg.UrlEnv[consts.EnvProd]
+var groupSelector atomic.Pointer[config.GroupSelector]
+
+// SetGroupSelector 设置按请求选择 account_config.json 分组的方式, 例如 gray.FeatureSelector("new_token_url"),
+// 使账号服务的灰度与功能实验使用相同的分桶; 传入 nil 时恢复默认:
+// 生产环境使用 B 组的配置, 其他环境使用当前环境的默认配置
+func SetGroupSelector(selector config.GroupSelector) {
+	if selector == nil {
+		groupSelector.Store(nil)
+		return
+	}
+	groupSelector.Store(&selector)
+}
+
+// getTokenUrl: 获取账号服务的 check_token 地址
+func getTokenUrl(ctx context.Context) (string, error) {
+	conf, err := accountConfig.Get()
+	if err != nil {
+		if nacos.FailMode(failMode.Load()).Resolve(nacos.FailMode_Closed) == nacos.FailMode_Closed {
+			return "", err
+		}
+		if url := os.Getenv(FallbackUrlKey); url != "" {
+			return url, nil
+		}
+		return "", fmt.Errorf("%w, %s is not set for fail open", err, FallbackUrlKey)
+	}
+	return tokenUrl(ctx, conf, env.Prod()), nil
+}
+
+// tokenUrl: 设置了分组选择方式时按请求选择分组, 否则生产环境使用 B 组的配置
+func tokenUrl(ctx context.Context, conf *config.NacosConfig[define.AccountConfig], prod bool) string {
+	var cfg define.AccountConfig
+	switch selector := groupSelector.Load(); {
+	case selector != nil:
+		cfg, _ = conf.GetWithBusiness(env.Business(ctx), (*selector)(ctx))
+	case prod:
+		cfg, _ = conf.Get(consts.TrafficGroup_B)
+	default:
+		cfg, _ = conf.Get()
 	}
 
-	cfg, _ = getAccountConfig().Get()
+	if prod {
+		return cfg.UrlEnv[consts.EnvProd]
+	}
 	return cfg.UrlEnv[consts.EnvTest]
 }
 
@@ -71,7 +108,12 @@ func CheckToken(ctx context.Context, token string) (*CheckTokenResp, error) {
 		return nil, err
 	}
 
-	request, err := http.NewRequestWithContext(ctx, http.MethodPost, getTokenUrl(), bytes.NewReader(reqBytes))
+	url, err := getTokenUrl(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(reqBytes))
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,4 @@
+package account
+
+// TokenUrl: 按指定环境选择 check_token 地址, 用于测试生产环境的分组选择
+var TokenUrl = tokenUrl
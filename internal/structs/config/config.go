@@ -6,7 +6,7 @@ func NewConfig[T any]() *Config[T] {
 	return &Config[T]{
 		lock:      sync.RWMutex{},
 		Data:      new(T),
-		listeners: map[string]IListener[T]{},
+		listeners: map[string]*listenerQueue[T]{},
 	}
 }
 
@@ -14,10 +14,17 @@ type IListener[T any] interface {
 	OnChange(data T)
 }
 
+// Config: 单个分组的配置
+//
+// 监听器异步执行: 每个监听器有独立的投递队列与 goroutine, 只保留最新的一个待投递事件,
+// 慢或 panic 的监听器不会阻塞配置更新, 也不影响其他监听器
 type Config[T any] struct {
 	lock      sync.RWMutex
 	Data      *T
-	listeners map[string]IListener[T]
+	Metadata  Metadata
+	Content   string // 当前配置的原始内容
+	listeners map[string]*listenerQueue[T]
+	closed    bool
 }
 
 func (config *Config[T]) Get() T {
@@ -28,24 +35,136 @@ func (config *Config[T]) Get() T {
 }
 
 func (config *Config[T]) Set(data *T) {
+	meta := config.metadata()
+	meta.Md5 = ""
+	config.Update(data, meta, "")
+}
+
+// Update: 更新配置并通知监听器, meta 与 content 为本次变更的来源信息与原始内容
+func (config *Config[T]) Update(data *T, meta Metadata, content string) {
 	config.lock.Lock()
+	old := config.Data
 	config.Data = data
+	config.Metadata = meta
+	config.Content = content
 	config.lock.Unlock()
 
-	for _, listener := range config.listeners {
-		listener.OnChange(*data)
+	queues := config.queues()
+	if len(queues) == 0 {
+		return
+	}
+	event := NewChangeEvent(meta, *old, *data, content)
+	for _, q := range queues {
+		q.push(event)
+	}
+}
+
+// Dispatch: 按优先级将事件放入各监听器的投递队列, 不等待监听器执行, 监听器之间的执行顺序不确定
+func (config *Config[T]) Dispatch(event ChangeEvent[T]) {
+	queues := config.queues()
+	for _, q := range queues {
+		q.push(event)
 	}
 }
 
+// Snapshot: 原子地获取当前配置、来源信息与原始内容
+func (config *Config[T]) Snapshot() (T, Metadata, string) {
+	config.lock.RLock()
+	defer config.lock.RUnlock()
+
+	return *config.Data, config.Metadata, config.Content
+}
+
+// Listeners: 获取已注册的监听器名称, 按入队顺序(优先级从高到低, 相同时按名称)排列
+func (config *Config[T]) Listeners() []string {
+	queues := config.queues()
+	names := make([]string, 0, len(queues))
+	for _, q := range queues {
+		names = append(names, q.name)
+	}
+	return names
+}
+
+func (config *Config[T]) queues() []*listenerQueue[T] {
+	config.lock.RLock()
+	queues := make([]*listenerQueue[T], 0, len(config.listeners))
+	for _, q := range config.listeners {
+		queues = append(queues, q)
+	}
+	config.lock.RUnlock()
+
+	sortQueues(queues)
+	return queues
+}
+
+func (config *Config[T]) metadata() Metadata {
+	config.lock.RLock()
+	defer config.lock.RUnlock()
+	return config.Metadata
+}
+
 func (config *Config[T]) RegisterListener(name string, listener IListener[T]) {
+	config.RegisterChangeListener(name, AdaptListener(listener))
+}
+
+// RegisterChangeListener: 注册接收完整变更事件(新旧值、原始内容、字段级差异)的监听器
+func (config *Config[T]) RegisterChangeListener(name string, listener IChangeListener[T]) {
+	config.RegisterApplyListener(name, changeAdapter[T]{listener: listener}, ListenerOptions{})
+}
+
+// RegisterApplyListener: 注册可能应用失败的监听器, 通过 opts 指定优先级与失败后的重试
+//
+// 同名监听器会被替换, 旧监听器尚未投递的事件被丢弃; Close 之后注册的监听器不会收到事件
+func (config *Config[T]) RegisterApplyListener(name string, listener IApplyListener[T], opts ListenerOptions) {
 	config.lock.Lock()
 	defer config.lock.Unlock()
-	config.listeners[name] = listener
+
+	if config.closed {
+		return
+	}
+	if prev, exist := config.listeners[name]; exist {
+		prev.close(false)
+	}
+	config.listeners[name] = newListenerQueue(name, listener, opts)
 }
 
+// UnregisterListener: 注销监听器, 尚未投递的事件被丢弃, 正在执行的监听器不受影响
 func (config *Config[T]) UnregisterListener(name string) {
 	config.lock.Lock()
 	defer config.lock.Unlock()
 
-	delete(config.listeners, name)
+	if q, exist := config.listeners[name]; exist {
+		q.close(false)
+		delete(config.listeners, name)
+	}
+}
+
+// Flush: 等待已放入队列的事件(包括重试)处理完成
+func (config *Config[T]) Flush() {
+	for _, q := range config.queues() {
+		q.flush()
+	}
+}
+
+// Close: 停止接收新的事件, 投递完各监听器尚未投递的事件(失败不再重试)后返回
+//
+// 不能在监听器中调用; 之后 Update 只更新配置, 不再通知监听器
+func (config *Config[T]) Close() {
+	config.lock.Lock()
+	config.closed = true
+	queues := make([]*listenerQueue[T], 0, len(config.listeners))
+	for _, q := range config.listeners {
+		queues = append(queues, q)
+	}
+	config.listeners = map[string]*listenerQueue[T]{}
+	config.lock.Unlock()
+
+	sortQueues(queues)
+	dones := make([]<-chan struct{}, 0, len(queues))
+	for _, q := range queues {
+		dones = append(dones, q.close(true))
+	}
+	for _, done := range dones {
+		<-done
+	}
 }
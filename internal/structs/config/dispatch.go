@@ -0,0 +1,259 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/everfir/logger-go"
+	"github.com/everfir/logger-go/structs/field"
+)
+
+// DefaultRetryBackoff: 监听器重试的初始间隔, 每次失败后翻倍, 最长 maxRetryBackoff
+const (
+	DefaultRetryBackoff = time.Second
+	maxRetryBackoff     = 30 * time.Second
+)
+
+// IApplyListener: 应用配置可能失败的监听器, 返回错误(或 panic)时按 ListenerOptions.Retries 重试
+type IApplyListener[T any] interface {
+	ApplyConfigChange(event ChangeEvent[T]) error
+}
+
+// ApplyListenerFunc: 函数形式的 IApplyListener
+type ApplyListenerFunc[T any] func(event ChangeEvent[T]) error
+
+func (f ApplyListenerFunc[T]) ApplyConfigChange(event ChangeEvent[T]) error {
+	return f(event)
+}
+
+// changeAdapter: 将不返回错误的 IChangeListener 适配为 IApplyListener
+type changeAdapter[T any] struct {
+	listener IChangeListener[T]
+}
+
+func (adapter changeAdapter[T]) ApplyConfigChange(event ChangeEvent[T]) error {
+	adapter.listener.OnConfigChange(event)
+	return nil
+}
+
+// ListenerOptions: 监听器的投递选项
+type ListenerOptions struct {
+	// Priority 优先级, 配置变更时按优先级从高到低(相同时按名称)将事件放入各监听器的队列;
+	// 只决定入队顺序, 各监听器在自己的 goroutine 中执行, 不保证执行与完成的先后
+	Priority int
+	// Retries 应用失败后的重试次数, 默认 0 不重试; 重试期间有新的变更时放弃重试, 直接投递最新的配置
+	Retries int
+	// Backoff 首次重试的间隔, 之后每次翻倍, 默认 1s
+	Backoff time.Duration
+	// OnError 应用失败(包括 panic)时回调, retrying 表示是否还会重试
+	OnError func(name string, err error, retrying bool)
+}
+
+// listenerQueue: 单个监听器的投递队列, 只保留最新的一个待投递事件
+//
+// 每个监听器在独立的 goroutine 中执行, 慢或 panic 的监听器不影响配置更新与其他监听器
+type listenerQueue[T any] struct {
+	name     string
+	listener IApplyListener[T]
+	opts     ListenerOptions
+
+	lock    sync.Mutex
+	cond    *sync.Cond
+	pending *ChangeEvent[T]
+	busy    bool
+	closed  bool
+	wake    chan struct{} // 重试等待期间有新的事件
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+func newListenerQueue[T any](name string, listener IApplyListener[T], opts ListenerOptions) *listenerQueue[T] {
+	if opts.Backoff <= 0 {
+		opts.Backoff = DefaultRetryBackoff
+	}
+	q := &listenerQueue[T]{
+		name:     name,
+		listener: listener,
+		opts:     opts,
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	q.cond = sync.NewCond(&q.lock)
+	go q.run()
+	return q
+}
+
+// push: 放入事件, 与尚未投递的事件合并: Old 为监听器上一次收到的配置, New 为最新配置
+func (q *listenerQueue[T]) push(event ChangeEvent[T]) {
+	q.lock.Lock()
+	if q.closed {
+		q.lock.Unlock()
+		return
+	}
+	if q.pending != nil {
+		event = NewChangeEvent(event.Metadata, q.pending.Old, event.New, event.Content)
+	}
+	q.pending = &event
+	q.cond.Broadcast()
+	q.lock.Unlock()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// close: 停止队列, drain 为 true 时投递完待投递的事件(不再重试)后退出; 返回的 channel 在队列退出后关闭
+func (q *listenerQueue[T]) close(drain bool) <-chan struct{} {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if !q.closed {
+		q.closed = true
+		if !drain {
+			q.pending = nil
+		}
+		close(q.stop)
+		q.cond.Broadcast()
+	}
+	return q.done
+}
+
+// flush: 等待待投递的事件(包括重试)处理完成
+func (q *listenerQueue[T]) flush() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	for q.pending != nil || q.busy {
+		q.cond.Wait()
+	}
+}
+
+func (q *listenerQueue[T]) run() {
+	defer close(q.done)
+
+	for {
+		q.lock.Lock()
+		for q.pending == nil && !q.closed {
+			q.cond.Wait()
+		}
+		event := q.pending
+		if event == nil {
+			q.lock.Unlock()
+			return
+		}
+		q.pending, q.busy = nil, true
+		closing := q.closed
+		q.lock.Unlock()
+
+		q.deliver(*event, closing)
+
+		q.lock.Lock()
+		q.busy = false
+		q.cond.Broadcast()
+		q.lock.Unlock()
+	}
+}
+
+// deliver: 投递事件, 失败时按选项重试, 有新的事件或队列关闭时放弃重试
+func (q *listenerQueue[T]) deliver(event ChangeEvent[T], closing bool) {
+	backoff := q.opts.Backoff
+	for attempt := 0; ; attempt++ {
+		err := q.apply(event)
+		if err == nil {
+			return
+		}
+
+		retrying := !closing && attempt < q.opts.Retries
+		logger.Warn(
+			context.TODO(),
+			"[go-helper] config listener failed",
+			field.String("listener", q.name),
+			field.String("dataId", event.DataId),
+			field.String("group", event.Group),
+			field.String("md5", event.Md5),
+			field.Bool("retrying", retrying),
+			field.String("err", err.Error()),
+		)
+		if q.opts.OnError != nil {
+			q.opts.OnError(q.name, err, retrying)
+		}
+		if !retrying || !q.wait(backoff) {
+			q.supersede(event)
+			return
+		}
+		if backoff *= 2; backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+	}
+}
+
+// wait: 等待重试间隔, 期间有新的事件或队列关闭时返回 false
+func (q *listenerQueue[T]) wait(backoff time.Duration) bool {
+	select {
+	case <-q.wake:
+	default:
+	}
+	q.lock.Lock()
+	interrupted := q.pending != nil || q.closed
+	q.lock.Unlock()
+	if interrupted {
+		return false
+	}
+
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-q.wake:
+	case <-q.stop:
+	}
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return q.pending == nil && !q.closed
+}
+
+// supersede: 事件应用失败, 待投递事件的 Old 改为监听器实际生效的配置, 即失败事件的 Old
+func (q *listenerQueue[T]) supersede(failed ChangeEvent[T]) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.pending != nil {
+		merged := NewChangeEvent(q.pending.Metadata, failed.Old, q.pending.New, q.pending.Content)
+		q.pending = &merged
+	}
+}
+
+// apply: 执行监听器, panic 视为失败
+func (q *listenerQueue[T]) apply(event ChangeEvent[T]) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+			logger.Error(
+				context.TODO(),
+				"[go-helper] config listener panic",
+				field.String("listener", q.name),
+				field.String("dataId", event.DataId),
+				field.String("panic", fmt.Sprint(r)),
+				field.String("stack", string(debug.Stack())),
+			)
+		}
+	}()
+	return q.listener.ApplyConfigChange(event)
+}
+
+// sortQueues: 按优先级从高到低、名称升序排序, 即入队顺序
+func sortQueues[T any](queues []*listenerQueue[T]) {
+	sort.Slice(queues, func(i, j int) bool {
+		if queues[i].opts.Priority != queues[j].opts.Priority {
+			return queues[i].opts.Priority > queues[j].opts.Priority
+		}
+		return queues[i].name < queues[j].name
+	})
+}
@@ -0,0 +1,176 @@
+package config
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zeebo/assert"
+)
+
+func TestDispatch(t *testing.T) {
+	config := NewConfig[int]()
+
+	// 慢监听器不阻塞配置更新, 连续的变更合并为最新的一次
+	var lock sync.Mutex
+	var slow []ChangeEvent[int]
+	block := make(chan struct{})
+	config.RegisterChangeListener("slow", ChangeListenerFunc[int](func(event ChangeEvent[int]) {
+		<-block
+		lock.Lock()
+		defer lock.Unlock()
+		slow = append(slow, event)
+	}))
+
+	// panic 的监听器不影响其他监听器
+	var panics []error
+	config.RegisterApplyListener("panic", ApplyListenerFunc[int](func(event ChangeEvent[int]) error {
+		panic("boom")
+	}), ListenerOptions{OnError: func(name string, err error, retrying bool) {
+		lock.Lock()
+		defer lock.Unlock()
+		panics = append(panics, err)
+	}})
+
+	var fast []int
+	config.RegisterListener("fast", recorderFunc[int](func(data int) {
+		lock.Lock()
+		defer lock.Unlock()
+		fast = append(fast, data)
+	}))
+
+	for i := 1; i <= 3; i++ {
+		v := i
+		config.Update(&v, Metadata{Md5: string(rune('0' + i))}, "")
+		config.lock.RLock()
+		config.listeners["fast"].flush()
+		config.lock.RUnlock()
+	}
+	assert.Equal(t, config.Get(), 3)
+
+	close(block)
+	config.Flush()
+	lock.Lock()
+	assert.DeepEqual(t, fast, []int{1, 2, 3})
+	assert.True(t, len(panics) >= 1)
+	// 阻塞期间的变更合并, Old 为上一次收到的配置
+	assert.True(t, len(slow) <= 2)
+	last := slow[len(slow)-1]
+	assert.Equal(t, last.New, 3)
+	assert.Equal(t, last.Md5, "3")
+	if len(slow) == 2 {
+		assert.Equal(t, last.Old, slow[0].New)
+	}
+	lock.Unlock()
+}
+
+type recorderFunc[T any] func(data T)
+
+func (f recorderFunc[T]) OnChange(data T) { f(data) }
+
+func TestDispatchPriority(t *testing.T) {
+	config := NewConfig[int]()
+	noop := ChangeListenerFunc[int](func(ChangeEvent[int]) {})
+	config.RegisterApplyListener("b", changeAdapter[int]{noop}, ListenerOptions{Priority: 1})
+	config.RegisterApplyListener("a", changeAdapter[int]{noop}, ListenerOptions{Priority: 1})
+	config.RegisterApplyListener("z", changeAdapter[int]{noop}, ListenerOptions{Priority: 10})
+	config.RegisterChangeListener("c", noop)
+	assert.DeepEqual(t, config.Listeners(), []string{"z", "a", "b", "c"})
+
+	config.UnregisterListener("a")
+	assert.DeepEqual(t, config.Listeners(), []string{"z", "b", "c"})
+}
+
+func TestDispatchRetry(t *testing.T) {
+	config := NewConfig[int]()
+
+	var lock sync.Mutex
+	var applied []int
+	var errs []bool
+	failures := 2
+	config.RegisterApplyListener("retry", ApplyListenerFunc[int](func(event ChangeEvent[int]) error {
+		lock.Lock()
+		defer lock.Unlock()
+		if failures > 0 {
+			failures--
+			return errors.New("apply failed")
+		}
+		applied = append(applied, event.New)
+		return nil
+	}), ListenerOptions{
+		Retries: 3,
+		Backoff: time.Millisecond,
+		OnError: func(name string, err error, retrying bool) {
+			lock.Lock()
+			defer lock.Unlock()
+			errs = append(errs, retrying)
+		},
+	})
+
+	v := 1
+	config.Update(&v, Metadata{}, "")
+	config.Flush()
+	lock.Lock()
+	assert.DeepEqual(t, applied, []int{1})
+	assert.DeepEqual(t, errs, []bool{true, true})
+	lock.Unlock()
+
+	// 重试次数用尽后放弃
+	lock.Lock()
+	failures, errs = 10, nil
+	lock.Unlock()
+	v2 := 2
+	config.Update(&v2, Metadata{}, "")
+	config.Flush()
+	lock.Lock()
+	assert.DeepEqual(t, applied, []int{1})
+	assert.DeepEqual(t, errs, []bool{true, true, true, false})
+	lock.Unlock()
+}
+
+func TestDispatchClose(t *testing.T) {
+	config := NewConfig[int]()
+
+	var lock sync.Mutex
+	var applied []int
+	block := make(chan struct{})
+	config.RegisterListener("slow", recorderFunc[int](func(data int) {
+		<-block
+		lock.Lock()
+		defer lock.Unlock()
+		applied = append(applied, data)
+	}))
+
+	for i := 1; i <= 3; i++ {
+		v := i
+		config.Update(&v, Metadata{}, "")
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		config.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+		t.Fatal("close returned before listeners drained")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(block)
+	<-closed
+
+	// 关闭前的最新配置已投递, 关闭后不再通知
+	lock.Lock()
+	assert.Equal(t, applied[len(applied)-1], 3)
+	n := len(applied)
+	lock.Unlock()
+
+	v := 4
+	config.Update(&v, Metadata{}, "")
+	assert.Equal(t, config.Get(), 4)
+	assert.Equal(t, len(config.Listeners()), 0)
+	lock.Lock()
+	assert.Equal(t, len(applied), n)
+	lock.Unlock()
+}
@@ -0,0 +1,189 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/everfir/logger-go"
+	"github.com/everfir/logger-go/structs/field"
+)
+
+// Metadata: 配置的来源信息
+type Metadata struct {
+	Namespace string `json:"namespace"`
+	Group     string `json:"group"`
+	DataId    string `json:"data_id"`
+	Md5       string `json:"md5"`
+}
+
+// DiffOp: 字段变更类型
+type DiffOp string
+
+const (
+	DiffOp_Add     DiffOp = "add"
+	DiffOp_Remove  DiffOp = "remove"
+	DiffOp_Replace DiffOp = "replace"
+)
+
+// FieldDiff: 字段级变更
+//   - Path: 变更字段的 JSON Pointer(RFC 6901), 如 "/momo"、"/features/login/rule/0"
+//   - Old/New: 变更前后的 JSON 值, 新增时 Old 为 nil, 删除时 New 为 nil
+type FieldDiff struct {
+	Path string `json:"path"`
+	Op   DiffOp `json:"op"`
+	Old  any    `json:"old,omitempty"`
+	New  any    `json:"new,omitempty"`
+}
+
+// ChangeEvent: 配置变更事件
+type ChangeEvent[T any] struct {
+	Metadata
+	Old     T           // 变更前的配置
+	New     T           // 变更后的配置
+	Content string      // 变更后的原始配置内容
+	Diff    []FieldDiff // 变更前后配置按 JSON 序列化后的字段级差异
+}
+
+// NewChangeEvent: 创建变更事件并计算字段级差异
+func NewChangeEvent[T any](meta Metadata, old, new T, content string) ChangeEvent[T] {
+	event := ChangeEvent[T]{
+		Metadata: meta,
+		Old:      old,
+		New:      new,
+		Content:  content,
+	}
+
+	diff, err := Diff(old, new)
+	if err != nil {
+		logger.Warn(
+			context.TODO(),
+			"[go-helper] diff config failed",
+			field.String("dataId", meta.DataId),
+			field.String("err", err.Error()),
+		)
+	}
+	event.Diff = diff
+	return event
+}
+
+// Changed: 指定路径(含其子字段)是否发生变更
+func (event ChangeEvent[T]) Changed(path string) bool {
+	for _, diff := range event.Diff {
+		if diff.Path == path || strings.HasPrefix(diff.Path, path+"/") || strings.HasPrefix(path, diff.Path+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// IChangeListener: 接收完整变更事件的监听器
+type IChangeListener[T any] interface {
+	OnConfigChange(event ChangeEvent[T])
+}
+
+// ChangeListenerFunc: 函数形式的 IChangeListener
+type ChangeListenerFunc[T any] func(event ChangeEvent[T])
+
+func (f ChangeListenerFunc[T]) OnConfigChange(event ChangeEvent[T]) {
+	f(event)
+}
+
+// listenerAdapter: 将只接收新值的 IListener 适配为 IChangeListener
+type listenerAdapter[T any] struct {
+	listener IListener[T]
+}
+
+func (adapter listenerAdapter[T]) OnConfigChange(event ChangeEvent[T]) {
+	adapter.listener.OnChange(event.New)
+}
+
+// AdaptListener: 将 IListener 适配为 IChangeListener
+func AdaptListener[T any](listener IListener[T]) IChangeListener[T] {
+	return listenerAdapter[T]{listener: listener}
+}
+
+// Diff: 计算两个值按 JSON 序列化后的字段级差异, 结果按路径排序
+func Diff(old, new any) ([]FieldDiff, error) {
+	o, err := toJSONValue(old)
+	if err != nil {
+		return nil, err
+	}
+	n, err := toJSONValue(new)
+	if err != nil {
+		return nil, err
+	}
+
+	var diffs []FieldDiff
+	diffValue("", o, n, &diffs)
+	sort.SliceStable(diffs, func(i, j int) bool {
+		return diffs[i].Path < diffs[j].Path
+	})
+	return diffs, nil
+}
+
+func toJSONValue(v any) (any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	var ret any
+	err = decoder.Decode(&ret)
+	return ret, err
+}
+
+func diffValue(path string, old, new any, diffs *[]FieldDiff) {
+	switch o := old.(type) {
+	case map[string]any:
+		n, ok := new.(map[string]any)
+		if !ok {
+			break
+		}
+		for k, ov := range o {
+			p := path + "/" + escapePointer(k)
+			if nv, exist := n[k]; exist {
+				diffValue(p, ov, nv, diffs)
+			} else {
+				*diffs = append(*diffs, FieldDiff{Path: p, Op: DiffOp_Remove, Old: ov})
+			}
+		}
+		for k, nv := range n {
+			if _, exist := o[k]; !exist {
+				*diffs = append(*diffs, FieldDiff{Path: path + "/" + escapePointer(k), Op: DiffOp_Add, New: nv})
+			}
+		}
+		return
+	case []any:
+		n, ok := new.([]any)
+		if !ok {
+			break
+		}
+		for i := 0; i < len(o) || i < len(n); i++ {
+			p := path + "/" + strconv.Itoa(i)
+			switch {
+			case i >= len(n):
+				*diffs = append(*diffs, FieldDiff{Path: p, Op: DiffOp_Remove, Old: o[i]})
+			case i >= len(o):
+				*diffs = append(*diffs, FieldDiff{Path: p, Op: DiffOp_Add, New: n[i]})
+			default:
+				diffValue(p, o[i], n[i], diffs)
+			}
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(old, new) {
+		*diffs = append(*diffs, FieldDiff{Path: path, Op: DiffOp_Replace, Old: old, New: new})
+	}
+}
+
+func escapePointer(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/zeebo/assert"
+)
+
+type recorder[T any] struct {
+	values []T
+}
+
+func (r *recorder[T]) OnChange(data T) {
+	r.values = append(r.values, data)
+}
+
+func TestDiff(t *testing.T) {
+	diffs, err := Diff(
+		map[string]any{"a": true, "b": []int{1, 2}, "c": map[string]int{"x": 1}, "d/e": 1},
+		map[string]any{"a": false, "b": []int{1}, "c": map[string]int{"x": 1, "y": 2}},
+	)
+	assert.NoError(t, err)
+	assert.DeepEqual(t, diffs, []FieldDiff{
+		{Path: "/a", Op: DiffOp_Replace, Old: true, New: false},
+		{Path: "/b/1", Op: DiffOp_Remove, Old: json.Number("2")},
+		{Path: "/c/y", Op: DiffOp_Add, New: json.Number("2")},
+		{Path: "/d~1e", Op: DiffOp_Remove, Old: json.Number("1")},
+	})
+
+	diffs, err = Diff(1, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, len(diffs), 0)
+}
+
+func TestChangeListener(t *testing.T) {
+	config := NewConfig[map[string]bool]()
+	config.Data = &map[string]bool{"momo": false}
+
+	legacy := &recorder[map[string]bool]{}
+	config.RegisterListener("legacy", legacy)
+
+	var events []ChangeEvent[map[string]bool]
+	config.RegisterChangeListener("event", ChangeListenerFunc[map[string]bool](func(event ChangeEvent[map[string]bool]) {
+		events = append(events, event)
+	}))
+
+	meta := Metadata{Namespace: "ns", Group: "test", DataId: "shutdown.json", Md5: "md5"}
+	config.Update(&map[string]bool{"momo": true}, meta, `{"momo":true}`)
+	config.Flush()
+
+	assert.DeepEqual(t, legacy.values, []map[string]bool{{"momo": true}})
+	assert.Equal(t, len(events), 1)
+	assert.Equal(t, events[0].Metadata, meta)
+	assert.DeepEqual(t, events[0].Old, map[string]bool{"momo": false})
+	assert.DeepEqual(t, events[0].New, map[string]bool{"momo": true})
+	assert.Equal(t, events[0].Content, `{"momo":true}`)
+	assert.True(t, events[0].Changed("/momo"))
+	assert.False(t, events[0].Changed("/other"))
+	assert.Equal(t, config.Metadata, meta)
+}
@@ -0,0 +1,237 @@
+package structs
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 配置默认值相关的 struct tag
+const (
+	DefaultTag = "default" // 字段缺失时使用的默认值, 例如 `default:"5s"`
+	EnvTag     = "env"     // 环境变量存在且不为空时覆盖字段, 例如 `env:"ACCOUNT_TIMEOUT"`
+)
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// ApplyDefaults: 按 struct tag 补全配置, v 需要是结构体指针, 在解析配置之后、Validator 之前执行
+//
+// 优先级: 环境变量(env) > 配置内容 > 默认值(default)
+//
+// 支持的字段类型:
+//   - 字符串、布尔、整数、浮点数, time.Duration 使用 time.ParseDuration 格式, 例如 "5s"
+//   - 实现了 encoding.TextUnmarshaler 的类型
+//   - 切片: 逗号分隔, 例如 `default:"a,b"`; 以 "[" 开头时按 JSON 解析
+//   - 结构体、map: 按 JSON 解析
+//
+// 嵌套结构体(包括非空指针、切片元素)递归处理。
+// 没有配置内容可以判断字段是否缺失, 零值字段视为缺失, 显式配置的 false、0、"" 也会被默认值覆盖,
+// 解析配置时使用 ApplyMissingDefaults
+func ApplyDefaults(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return nil
+	}
+	return applyDefaults(rv.Elem(), "", nil, false)
+}
+
+// ApplyMissingDefaults: 与 ApplyDefaults 相同, 但按解析出的通用结构(map[string]any、[]any)判断字段是否缺失:
+// 配置中存在的字段即使为 false、0、"" 也不使用默认值, 值为 null 的字段视为缺失
+func ApplyMissingDefaults(v any, tree any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return nil
+	}
+	return applyDefaults(rv.Elem(), "", tree, true)
+}
+
+// applyDefaults: known 为 true 时 tree 是 v 对应的配置内容, 否则按零值判断字段是否缺失
+func applyDefaults(v reflect.Value, path string, tree any, known bool) error {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		return applyDefaults(v.Elem(), path, tree, known)
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() != reflect.Struct && v.Type().Elem().Kind() != reflect.Pointer {
+			return nil
+		}
+		items, ok := tree.([]any)
+		for i := 0; i < v.Len(); i++ {
+			var item any
+			if ok && i < len(items) {
+				item = items[i]
+			}
+			if err := applyDefaults(v.Index(i), fmt.Sprintf("%s[%d]", path, i), item, known && ok && i < len(items)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Struct:
+	default:
+		return nil
+	}
+
+	obj, isObject := tree.(map[string]any)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		fv := v.Field(i)
+		name := strings.TrimPrefix(path+"."+sf.Name, ".")
+
+		// 未命名的嵌入结构体的字段与外层在同一层
+		if sf.Anonymous && jsonName(sf) == "" && indirect(sf.Type).Kind() == reflect.Struct {
+			if err := applyDefaults(fv, name, tree, known); err != nil {
+				return err
+			}
+			continue
+		}
+
+		child, present := lookupField(obj, sf)
+		fieldKnown := known && isObject && jsonName(sf) != "-"
+		missing := fv.IsZero()
+		if fieldKnown {
+			missing = !present || child == nil
+		}
+
+		if def, ok := sf.Tag.Lookup(DefaultTag); ok && missing {
+			if err := setValue(fv, def); err != nil {
+				return fmt.Errorf("invalid default of %s: %w", name, err)
+			}
+		}
+		if key := sf.Tag.Get(EnvTag); key != "" {
+			if val := os.Getenv(key); val != "" {
+				if err := setValue(fv, val); err != nil {
+					return fmt.Errorf("invalid %s for %s: %w", key, name, err)
+				}
+			}
+		}
+
+		if err := applyDefaults(fv, name, child, fieldKnown && present); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Pointer {
+		return t.Elem()
+	}
+	return t
+}
+
+// jsonName: 字段在 json 标签中的名称, 忽略的字段返回 "-"
+func jsonName(sf reflect.StructField) string {
+	tag := sf.Tag.Get("json")
+	if tag == "-" {
+		return "-"
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	return name
+}
+
+// lookupField: 按 encoding/json 的规则查找字段对应的值, 精确匹配优先, 其次不区分大小写
+func lookupField(obj map[string]any, sf reflect.StructField) (any, bool) {
+	name := jsonName(sf)
+	if name == "" {
+		name = sf.Name
+	}
+	if v, ok := obj[name]; ok {
+		return v, true
+	}
+	for key, v := range obj {
+		if strings.EqualFold(key, name) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// setValue: 将字符串解析为字段的类型并赋值
+func setValue(v reflect.Value, s string) error {
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Pointer:
+		elem := reflect.New(v.Type().Elem())
+		if err := setValue(elem.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(elem)
+	case reflect.Slice:
+		if strings.HasPrefix(strings.TrimSpace(s), "[") {
+			return unmarshalJSON(v, s)
+		}
+		parts := strings.Split(s, ",")
+		slice := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err := setValue(slice.Index(i), strings.TrimSpace(part)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	case reflect.Struct, reflect.Map, reflect.Array:
+		return unmarshalJSON(v, s)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+func unmarshalJSON(v reflect.Value, s string) error {
+	ptr := reflect.New(v.Type())
+	if err := json.Unmarshal([]byte(s), ptr.Interface()); err != nil {
+		return err
+	}
+	v.Set(ptr.Elem())
+	return nil
+}
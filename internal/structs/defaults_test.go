@@ -0,0 +1,88 @@
+package structs
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/zeebo/assert"
+)
+
+type defaultsConfig struct {
+	Timeout time.Duration     `json:"timeout" default:"5s" env:"TEST_DEFAULTS_TIMEOUT"`
+	Name    string            `json:"name" default:"account"`
+	Retries int               `json:"retries" default:"3"`
+	Ratio   float64           `json:"ratio" default:"0.5"`
+	Enable  *bool             `json:"enable" default:"true"`
+	Active  bool              `json:"active" default:"true"`
+	Hosts   []string          `json:"hosts" default:"a, b"`
+	Ports   []int             `json:"ports" default:"[80,443]"`
+	Labels  map[string]string `json:"labels" default:"{\"team\":\"core\"}"`
+	Db      struct {
+		Dsn     string        `json:"dsn" env:"TEST_DEFAULTS_DSN"`
+		MaxIdle time.Duration `json:"max_idle" default:"1m"`
+	} `json:"db"`
+	Backends []backend `json:"backends"`
+	Limit    *backend  `json:"limit"`
+	skipped  int       `default:"1"`
+}
+
+type backend struct {
+	Weight int `json:"weight" default:"1"`
+}
+
+func TestApplyDefaults(t *testing.T) {
+	t.Setenv("TEST_DEFAULTS_TIMEOUT", "")
+	t.Setenv("TEST_DEFAULTS_DSN", "")
+
+	var cfg defaultsConfig
+	cfg.Retries = 1
+	cfg.Backends = []backend{{}, {Weight: 5}}
+	assert.NoError(t, ApplyDefaults(&cfg))
+	assert.Equal(t, cfg.Timeout, 5*time.Second)
+	assert.Equal(t, cfg.Name, "account")
+	assert.Equal(t, cfg.Retries, 1) // 配置中的值优先于默认值
+	assert.Equal(t, cfg.Ratio, 0.5)
+	assert.True(t, *cfg.Enable)
+	assert.True(t, cfg.Active)
+	assert.DeepEqual(t, cfg.Hosts, []string{"a", "b"})
+	assert.DeepEqual(t, cfg.Ports, []int{80, 443})
+	assert.DeepEqual(t, cfg.Labels, map[string]string{"team": "core"})
+	assert.Equal(t, cfg.Db.MaxIdle, time.Minute)
+	assert.DeepEqual(t, cfg.Backends, []backend{{Weight: 1}, {Weight: 5}})
+	assert.Nil(t, cfg.Limit)
+	assert.Equal(t, cfg.skipped, 0)
+
+	// 环境变量优先于配置内容
+	t.Setenv("TEST_DEFAULTS_TIMEOUT", "10s")
+	t.Setenv("TEST_DEFAULTS_DSN", "root@tcp(localhost)/test")
+	cfg = defaultsConfig{Timeout: time.Second}
+	assert.NoError(t, ApplyDefaults(&cfg))
+	assert.Equal(t, cfg.Timeout, 10*time.Second)
+	assert.Equal(t, cfg.Db.Dsn, "root@tcp(localhost)/test")
+
+	t.Setenv("TEST_DEFAULTS_TIMEOUT", "soon")
+	assert.Error(t, ApplyDefaults(&cfg))
+
+	// 按配置内容判断字段是否缺失, 显式配置的 false、0、"" 保留, null 视为缺失
+	t.Setenv("TEST_DEFAULTS_TIMEOUT", "")
+	content := `{"active":false,"retries":0,"name":"","ratio":null,"backends":[{"weight":0},{}],"limit":{},"db":{"max_idle":0}}`
+	var tree any
+	cfg = defaultsConfig{}
+	assert.NoError(t, json.Unmarshal([]byte(content), &cfg))
+	assert.NoError(t, json.Unmarshal([]byte(content), &tree))
+	assert.NoError(t, ApplyMissingDefaults(&cfg, tree))
+	assert.False(t, cfg.Active)
+	assert.Equal(t, cfg.Retries, 0)
+	assert.Equal(t, cfg.Name, "")
+	assert.Equal(t, cfg.Ratio, 0.5)
+	assert.Equal(t, cfg.Timeout, 5*time.Second)
+	assert.Equal(t, cfg.Db.MaxIdle, time.Duration(0))
+	assert.DeepEqual(t, cfg.Backends, []backend{{Weight: 0}, {Weight: 1}})
+	assert.Equal(t, *cfg.Limit, backend{Weight: 1})
+
+	// 非结构体忽略
+	m := map[string]bool{}
+	assert.NoError(t, ApplyDefaults(&m))
+	assert.NoError(t, ApplyDefaults(nil))
+}
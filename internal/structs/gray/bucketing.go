@@ -0,0 +1,139 @@
+package gray
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/everfir/go-helpers/consts"
+	"github.com/everfir/go-helpers/env"
+	"github.com/everfir/go-helpers/internal/helper/encode"
+	"github.com/everfir/logger-go"
+	"github.com/everfir/logger-go/structs/field"
+)
+
+// bucketing: 规则使用的分桶方案与盐值, 零值表示默认方案
+type bucketing struct {
+	scheme encode.BucketScheme
+	salt   string
+}
+
+func newBucketing(name, salt string) (bucketing, error) {
+	scheme, exist := encode.GetScheme(name)
+	if !exist {
+		return bucketing{}, fmt.Errorf("unknown hash scheme[%s], registered: %v", name, encode.Schemes())
+	}
+	return bucketing{scheme: scheme, salt: salt}, nil
+}
+
+func (b bucketing) match(accountId string, rate float64) bool {
+	scheme := b.scheme
+	if scheme == nil {
+		scheme, _ = encode.GetScheme(encode.DefaultScheme)
+	}
+	return encode.InRate(scheme, b.salt, accountId, rate)
+}
+
+// maxMigrationAccounts: 迁移模式下最多对比的账号数, 超出后不再对比新账号, 统计视为抽样
+const maxMigrationAccounts = 100000
+
+// HashMigrationStats: 分桶方案迁移统计, 按用户计数, 每个用户只对比一次
+type HashMigrationStats struct {
+	From      string `json:"from"`      // 当前方案
+	To        string `json:"to"`        // 目标方案
+	Evaluated uint64 `json:"evaluated"` // 参与对比的用户数
+	Moved     uint64 `json:"moved"`     // 切换方案后分组会发生变化的用户数
+}
+
+type hashMigration struct {
+	lock      sync.Mutex
+	accounts  map[uint64]struct{} // 已对比的账号
+	evaluated atomic.Uint64
+	moved     atomic.Uint64
+}
+
+// sample: 每个账号只对比一次, 未登录用户与超出 maxMigrationAccounts 的账号不参与对比
+func (m *hashMigration) sample(accountId uint64) bool {
+	if accountId == 0 {
+		return false
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if _, exist := m.accounts[accountId]; exist || len(m.accounts) >= maxMigrationAccounts {
+		return false
+	}
+	if m.accounts == nil {
+		m.accounts = make(map[uint64]struct{})
+	}
+	m.accounts[accountId] = struct{}{}
+	return true
+}
+
+// reportMigration: 迁移模式下对比目标方案的分组, 记录会发生变化的用户
+//
+// 对比不计入表达式的连续失败次数, 每个用户只对比一次。
+func (e *FeatureConfig) reportMigration(ctx context.Context, feature string, group consts.TrafficGroup) {
+	if e.MigrateHash == "" {
+		return
+	}
+
+	target, err := newBucketing(e.MigrateHash, e.salt(feature))
+	if err != nil {
+		return
+	}
+
+	accountId := env.AccountInfo(ctx).AccountId
+	if !e.migration.sample(accountId) {
+		return
+	}
+
+	e.migration.evaluated.Add(1)
+	moved := e.ruleGroup(ctx, &target)
+	if moved == group {
+		return
+	}
+
+	e.migration.moved.Add(1)
+	logger.Debug(
+		ctx,
+		"[go-helper] gray hash migration would move user",
+		field.String("business", env.Business(ctx)),
+		field.String("feature", feature),
+		field.Any("account_id", accountId),
+		field.String("from_scheme", e.scheme()),
+		field.String("to_scheme", e.MigrateHash),
+		field.String("from_group", group.Group()),
+		field.String("to_group", moved.Group()),
+	)
+}
+
+// MigrationStats: 获取分桶方案迁移统计, 未配置 migrate_hash 时返回 false
+func (e *FeatureConfig) MigrationStats() (HashMigrationStats, bool) {
+	if e.MigrateHash == "" {
+		return HashMigrationStats{}, false
+	}
+
+	return HashMigrationStats{
+		From:      e.scheme(),
+		To:        e.MigrateHash,
+		Evaluated: e.migration.evaluated.Load(),
+		Moved:     e.migration.moved.Load(),
+	}, true
+}
+
+func (e *FeatureConfig) scheme() string {
+	if e.Hash == "" {
+		return encode.DefaultScheme
+	}
+	return e.Hash
+}
+
+// salt: 盐值, 未配置时使用实验名称
+func (e *FeatureConfig) salt(feature string) string {
+	if e.HashSalt == "" {
+		return feature
+	}
+	return e.HashSalt
+}
@@ -2,14 +2,63 @@ package gray
 
 import (
 	"context"
+	"fmt"
+	"sort"
 
 	"github.com/everfir/go-helpers/consts"
+	"github.com/everfir/go-helpers/env"
+	"github.com/everfir/logger-go"
+	"github.com/everfir/logger-go/structs/field"
+)
+
+// FeatureStatus: 实验生命周期状态
+type FeatureStatus string
+
+const (
+	FeatureStatus_Draft     FeatureStatus = "draft"     // 草稿: 仅白名单用户进入实验组
+	FeatureStatus_Running   FeatureStatus = "running"   // 运行中: 按分流规则分组
+	FeatureStatus_Paused    FeatureStatus = "paused"    // 暂停: 配置了粘性存储时冻结新分配
+	FeatureStatus_Concluded FeatureStatus = "concluded" // 已结束: 所有用户进入胜出组(winner)
+	FeatureStatus_Cleaned   FeatureStatus = "cleaned"   // 已清理: 实验代码已下线, 所有用户进入 A 组
+	FeatureStatus_Disabled  FeatureStatus = "disabled"  // 未启用: 仅由旧配置 enable=false 推导得到, 不可直接配置
 )
 
 // FeatureConfig: AB实验配置
 type FeatureConfig struct {
-	Enable bool           `json:"enable"`
-	Rule   []*TrafficRule `json:"rule"` // 分流策略, 影响分组逻辑
+	Enable bool           `json:"enable"`           // 兼容旧配置: status 为空时, true 等价于 running
+	Status FeatureStatus  `json:"status,omitempty"` // 生命周期状态, 非空时优先于 enable
+	Winner string         `json:"winner,omitempty"` // 胜出组, 仅在 concluded 状态下生效
+	Rule   []*TrafficRule `json:"rule"`             // 分流策略, 影响分组逻辑
+
+	Hash        string `json:"hash,omitempty"`         // 分桶方案, 为空时使用 xxh3-v1
+	HashSalt    string `json:"hash_salt,omitempty"`    // 分桶盐值, 仅对加盐方案生效, 为空时使用实验名称
+	MigrateHash string `json:"migrate_hash,omitempty"` // 迁移模式: 额外按该方案计算分组, 记录切换后会移动的用户
+
+	migration hashMigration
+}
+
+// State: 获取实验当前生效的生命周期状态
+//
+// status 为空时根据 enable 推导：enable=true 视为 running，否则视为 disabled。
+func (e *FeatureConfig) State() FeatureStatus {
+	if e.Status != "" {
+		return e.Status
+	}
+
+	if e.Enable {
+		return FeatureStatus_Running
+	}
+	return FeatureStatus_Disabled
+}
+
+// Enabled: 实验是否处于生效状态(running/paused/concluded)
+func (e *FeatureConfig) Enabled() bool {
+	switch e.State() {
+	case FeatureStatus_Running, FeatureStatus_Paused, FeatureStatus_Concluded:
+		return true
+	default:
+		return false
+	}
 }
 
 // Format: 格式化配置
@@ -19,7 +68,38 @@ func (e *FeatureConfig) Format() {
 	}
 }
 
-// Validate: 校验配置
+// setBucketing: 下发实验的分桶方案, 方案已在 Validate 中校验; 未经校验的未知方案记录错误并使用默认方案
+func (e *FeatureConfig) setBucketing(feature string) {
+	b, err := newBucketing(e.Hash, e.salt(feature))
+	if err != nil {
+		logger.Error(
+			context.TODO(),
+			"[go-helper] gray feature uses unknown hash scheme, fallback to default",
+			field.String("feature", feature),
+			field.String("err", err.Error()),
+		)
+		b = bucketing{salt: e.salt(feature)}
+	}
+	for _, rule := range e.Rule {
+		rule.bucketing = b
+	}
+}
+
+// setExtraSchema: 下发业务配置的 Extra 类型提示
+func (e *FeatureConfig) setExtraSchema(schema ExtraSchema) {
+	for _, rule := range e.Rule {
+		rule.extraSchema = schema
+	}
+}
+
+// Validate 校验配置
+//
+// 除逐条校验分流规则外，还会检查生命周期状态的合法组合：
+// 1. status 必须是 draft/running/paused/concluded/cleaned 之一（或为空）。
+// 2. concluded 状态必须指定 winner，且 winner 必须在 [a-z] 范围内。
+// 3. 非 concluded 状态不允许配置 winner。
+// 4. draft 状态至少需要一条启用的规则配置了白名单，否则实验对任何人都不可见。
+// 5. hash 与 migrate_hash 必须是已注册的分桶方案。
 func (e *FeatureConfig) Validate() error {
 	var err error
 	for _, rule := range e.Rule {
@@ -27,19 +107,107 @@ func (e *FeatureConfig) Validate() error {
 			return err
 		}
 	}
+
+	if _, err = newBucketing(e.Hash, ""); err != nil {
+		return fmt.Errorf("invalid feature.Hash: %w", err)
+	}
+	if e.MigrateHash != "" {
+		if _, err = newBucketing(e.MigrateHash, ""); err != nil {
+			return fmt.Errorf("invalid feature.MigrateHash: %w", err)
+		}
+	}
+
+	switch e.Status {
+	case "", FeatureStatus_Running, FeatureStatus_Paused, FeatureStatus_Cleaned:
+	case FeatureStatus_Concluded:
+		if e.Winner == "" {
+			return fmt.Errorf("invalid feature.Winner: status[%s] requires a winner", e.Status)
+		}
+	case FeatureStatus_Draft:
+		if !e.hasWhiteList() {
+			return fmt.Errorf("invalid feature.Status[%s]: requires at least one enabled rule with whitelist", e.Status)
+		}
+	default:
+		return fmt.Errorf("invalid feature.Status[%s] should be one of [draft running paused concluded cleaned]", e.Status)
+	}
+
+	if e.Winner != "" {
+		if e.Status != FeatureStatus_Concluded {
+			return fmt.Errorf("invalid feature.Winner[%s]: only allowed when status is %s", e.Winner, FeatureStatus_Concluded)
+		}
+		if len(e.Winner) != 1 || e.Winner[0] < 'a' || e.Winner[0] > 'z' {
+			return fmt.Errorf("invalid feature.Winner[%s] should be in [a-z]", e.Winner)
+		}
+	}
+
 	return nil
 }
 
-// Group: 根据分流规则确定分组
-func (e *FeatureConfig) Group(ctx context.Context) consts.TrafficGroup {
+// Group 根据生命周期状态与分流规则确定分组
+//
+//   - concluded: 所有用户进入 winner 组。
+//   - draft: 仅命中规则白名单的用户进入对应组，其余用户进入 A 组。
+//   - running: 按分流规则分组；配置了粘性存储时优先使用已有分配，并记录新分配。
+//   - paused: 配置了粘性存储时仅返回已有分配，不再产生新分配；否则按分流规则分组。
+//   - 其他状态: 返回 A 组。
+//
+// feature 为实验名称，用作粘性存储的键。配置了 migrate_hash 时，running/paused 状态会额外对比目标分桶方案的分组。
+func (e *FeatureConfig) Group(ctx context.Context, feature string) consts.TrafficGroup {
+	switch e.State() {
+	case FeatureStatus_Concluded:
+		return consts.NewTrafficGroupFromString(e.Winner)
+	case FeatureStatus_Draft:
+		return e.whiteListGroup(ctx)
+	case FeatureStatus_Running:
+		return e.stickyGroup(ctx, feature, true)
+	case FeatureStatus_Paused:
+		return e.stickyGroup(ctx, feature, false)
+	default:
+		return consts.TrafficGroup_A
+	}
+}
+
+// stickyGroup: 结合粘性存储确定分组, assign 为 false 时不产生新分配
+func (e *FeatureConfig) stickyGroup(ctx context.Context, feature string, assign bool) consts.TrafficGroup {
+	store := getStickyStore()
+	accountId := env.AccountInfo(ctx).AccountId
+	if store == nil || accountId == 0 {
+		group := e.ruleGroup(ctx, nil)
+		e.reportMigration(ctx, feature, group)
+		return group
+	}
+
+	business := env.Business(ctx)
+	if group, exist := store.Get(ctx, business, feature, accountId); exist {
+		return group
+	}
+
+	if !assign {
+		// 暂停状态下冻结新分配
+		return consts.TrafficGroup_A
+	}
+
+	group := e.ruleGroup(ctx, nil)
+	e.reportMigration(ctx, feature, group)
+	store.Set(ctx, business, feature, accountId, group)
+	return group
+}
+
+// ruleGroup: 根据分流规则确定分组, override 不为空时使用指定的分桶方案(迁移对比), 此时表达式失败不计入连续失败次数
+func (e *FeatureConfig) ruleGroup(ctx context.Context, override *bucketing) consts.TrafficGroup {
 	for _, rule := range e.Rule {
 		// 该分流规则已经关闭，跳过
 		if !rule.Enable {
 			continue
 		}
 
+		b, probe := rule.bucketing, override != nil
+		if probe {
+			b = *override
+		}
+
 		// 根据分流规则确定分组
-		if rule.Group(ctx) {
+		if rule.group(ctx, b, probe) {
 			return consts.NewTrafficGroupFromString(rule.TargetGroup)
 		}
 	}
@@ -47,3 +215,37 @@ func (e *FeatureConfig) Group(ctx context.Context) consts.TrafficGroup {
 	// 没有匹配到任何分流规则，返回默认分组
 	return consts.TrafficGroup_A
 }
+
+// whiteListGroup: 仅根据规则白名单确定分组
+func (e *FeatureConfig) whiteListGroup(ctx context.Context) consts.TrafficGroup {
+	accountId := fmt.Sprintf("%d", env.AccountInfo(ctx).AccountId)
+	for _, rule := range e.Rule {
+		if rule.Enable && rule.InWhiteList(accountId) {
+			return consts.NewTrafficGroupFromString(rule.TargetGroup)
+		}
+	}
+	return consts.TrafficGroup_A
+}
+
+func (e *FeatureConfig) hasWhiteList() bool {
+	for _, rule := range e.Rule {
+		if rule.Enable && len(rule.WhiteList) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// featuresByStatus: 按生命周期状态对实验名称分组, 名称有序
+func featuresByStatus(features map[string]*FeatureConfig) map[FeatureStatus][]string {
+	ret := make(map[FeatureStatus][]string)
+	for name, config := range features {
+		status := config.State()
+		ret[status] = append(ret[status], name)
+	}
+
+	for _, names := range ret {
+		sort.Strings(names)
+	}
+	return ret
+}
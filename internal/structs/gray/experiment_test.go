@@ -0,0 +1,150 @@
+package gray
+
+import (
+	"context"
+	"testing"
+
+	"github.com/everfir/go-helpers/consts"
+	"github.com/everfir/go-helpers/define"
+	"github.com/zeebo/assert"
+)
+
+func TestFeatureStatusValidate(t *testing.T) {
+	cases := []struct {
+		status    FeatureStatus
+		winner    string
+		whiteList []string
+		valid     bool
+	}{
+		{status: FeatureStatus_Running, whiteList: []string{"7"}, valid: true},
+		{status: "unknown", whiteList: []string{"7"}, valid: false},
+		{status: FeatureStatus_Concluded, whiteList: []string{"7"}, valid: false},
+		{status: FeatureStatus_Concluded, winner: "bb", whiteList: []string{"7"}, valid: false},
+		{status: FeatureStatus_Concluded, winner: "c", whiteList: []string{"7"}, valid: true},
+		{status: FeatureStatus_Running, winner: "c", whiteList: []string{"7"}, valid: false},
+		{status: FeatureStatus_Draft, whiteList: []string{"7"}, valid: true},
+		{status: FeatureStatus_Draft, valid: false},
+	}
+
+	for _, c := range cases {
+		feature := &FeatureConfig{
+			Status: c.status,
+			Winner: c.winner,
+			Rule:   []*TrafficRule{{Enable: true, TargetGroup: "b", Rate: 1, TrafficRate: 1, WhiteList: c.whiteList}},
+		}
+		feature.Format()
+		assert.Equal(t, feature.Validate() == nil, c.valid)
+	}
+}
+
+func TestFeatureStatusGroup(t *testing.T) {
+	cases := []struct {
+		status    FeatureStatus
+		enable    bool
+		winner    string
+		accountId uint64
+		state     FeatureStatus
+		want      consts.TrafficGroup
+	}{
+		// 兼容旧配置
+		{status: "", enable: false, accountId: 1, state: FeatureStatus_Disabled, want: consts.TrafficGroup_A},
+		{status: "", enable: true, accountId: 1, state: FeatureStatus_Running, want: consts.TrafficGroup_B},
+		{status: FeatureStatus_Concluded, winner: "c", accountId: 1, state: FeatureStatus_Concluded, want: consts.TrafficGroup_C},
+		{status: FeatureStatus_Draft, accountId: 7, state: FeatureStatus_Draft, want: consts.TrafficGroup_B},
+		{status: FeatureStatus_Draft, accountId: 1, state: FeatureStatus_Draft, want: consts.TrafficGroup_A},
+		{status: FeatureStatus_Cleaned, accountId: 1, state: FeatureStatus_Cleaned, want: consts.TrafficGroup_A},
+	}
+
+	for _, c := range cases {
+		feature := &FeatureConfig{
+			Status: c.status,
+			Enable: c.enable,
+			Winner: c.winner,
+			Rule:   []*TrafficRule{{Enable: true, TargetGroup: "b", Rate: 1, TrafficRate: 1, WhiteList: []string{"7"}}},
+		}
+		feature.Format()
+
+		ctx := context.WithValue(context.Background(), consts.BusinessKey, "business1")
+		ctx = context.WithValue(ctx, consts.AccountInfoKey, &define.AccountInfo{AccountId: c.accountId})
+		assert.Equal(t, feature.State(), c.state)
+		assert.Equal(t, feature.Group(ctx, "f"), c.want)
+	}
+}
+
+func TestFeatureStatusPaused(t *testing.T) {
+	defer SetStickyStore(nil)
+
+	rules := []*TrafficRule{{Enable: true, TargetGroup: "b", Rate: 1, TrafficRate: 1}}
+	paused := &FeatureConfig{Status: FeatureStatus_Paused, Rule: rules}
+	running := &FeatureConfig{Status: FeatureStatus_Running, Rule: rules}
+	paused.Format()
+
+	// 按顺序执行, 后面的用例依赖前面的分配
+	cases := []struct {
+		feature   *FeatureConfig
+		sticky    bool
+		accountId uint64
+		want      consts.TrafficGroup
+	}{
+		// 未配置粘性存储时, 按分流规则分组
+		{feature: paused, sticky: false, accountId: 1, want: consts.TrafficGroup_B},
+		// 运行中的实验记录分配
+		{feature: running, sticky: true, accountId: 1, want: consts.TrafficGroup_B},
+		// 已分配的用户保持分组, 新用户冻结在 A 组
+		{feature: paused, sticky: true, accountId: 1, want: consts.TrafficGroup_B},
+		{feature: paused, sticky: true, accountId: 2, want: consts.TrafficGroup_A},
+	}
+
+	for _, c := range cases {
+		if c.sticky && getStickyStore() == nil {
+			SetStickyStore(NewMemoryStickyStore())
+		}
+		ctx := context.WithValue(context.Background(), consts.BusinessKey, "business1")
+		ctx = context.WithValue(ctx, consts.AccountInfoKey, &define.AccountInfo{AccountId: c.accountId})
+		assert.Equal(t, c.feature.Group(ctx, "f"), c.want)
+	}
+}
+
+func TestFeaturesByStatus(t *testing.T) {
+	g := Gray{Feature: map[string]*FeatureConfig{
+		"b": {Status: FeatureStatus_Running},
+		"a": {Status: FeatureStatus_Running},
+		"c": {Status: FeatureStatus_Cleaned},
+	}}
+
+	ret := g.FeaturesByStatus()
+	assert.DeepEqual(t, ret[FeatureStatus_Running], []string{"a", "b"})
+	assert.DeepEqual(t, ret[FeatureStatus_Cleaned], []string{"c"})
+}
+
+func TestFeatureHashMigration(t *testing.T) {
+	feature := &FeatureConfig{
+		Status:      FeatureStatus_Running,
+		MigrateHash: "xxh3-salted-v2",
+		Rule:        []*TrafficRule{{Enable: true, TargetGroup: "b", Rate: 0.5, TrafficRate: 1}},
+	}
+	feature.Format()
+	assert.NoError(t, feature.Validate())
+	feature.setBucketing("f")
+
+	// 同一用户的多次请求只对比一次
+	for round := 0; round < 2; round++ {
+		for i := uint64(1); i <= 1000; i++ {
+			ctx := context.WithValue(context.Background(), consts.BusinessKey, "business1")
+			ctx = context.WithValue(ctx, consts.AccountInfoKey, &define.AccountInfo{AccountId: i})
+			feature.Group(ctx, "f")
+		}
+	}
+
+	stats, ok := feature.MigrationStats()
+	assert.True(t, ok)
+	assert.Equal(t, stats.From, "xxh3-v1")
+	assert.Equal(t, stats.Evaluated, uint64(1000))
+	// 两种方案相互独立, 约一半用户会移动分组
+	assert.True(t, stats.Moved > 300 && stats.Moved < 700)
+
+	feature.Hash = "unknown"
+	assert.Error(t, feature.Validate())
+	feature.Hash, feature.MigrateHash = "", "unknown"
+	assert.Error(t, feature.Validate())
+}
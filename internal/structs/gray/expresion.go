@@ -1,6 +1,243 @@
 package gray
 
-// ctime
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
 
+	"github.com/everfir/logger-go"
+	"github.com/everfir/logger-go/structs/field"
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/parser"
+	"github.com/expr-lang/expr/vm"
+)
+
+// ExprLimits: 规则表达式的沙箱限制
+type ExprLimits struct {
+	MaxNodes     int                 // 表达式 AST 节点数上限, 大的 in 列表等会被拒绝
+	Operators    map[string]struct{} // 允许使用的运算符
+	Functions    map[string]struct{} // 允许使用的内置函数
+	MemoryBudget uint                // 运行时内存预算, 为 0 时使用 DefaultMemoryBudget; 写入全局 vm.MemoryBudget, 影响进程内所有 expr 调用, 见 SetExprLimits
+	MaxFailures  int32               // 连续运行失败(含超出内存预算)达到该次数后自动禁用表达式
+}
+
+// DefaultMemoryBudget: 默认的运行时内存预算, 包初始化时显式设置, 不依赖 expr 的默认值
+const DefaultMemoryBudget uint = 1e6
+
+// DefaultExprLimits: 默认沙箱限制
+func DefaultExprLimits() ExprLimits {
+	return ExprLimits{
+		MaxNodes: 256,
+		Operators: toSet(
+			"==", "!=", "<", "<=", ">", ">=",
+			"&&", "||", "and", "or", "!", "not",
+			"in", "contains", "startsWith", "endsWith",
+			"+", "-", "*", "/", "%", "??",
+		),
+		Functions: toSet(
+			"len", "lower", "upper", "trim", "hasPrefix", "hasSuffix",
+			"int", "float", "string", "abs", "min", "max",
+			"all", "any", "none", "one", "count",
+		),
+		MemoryBudget: DefaultMemoryBudget,
+		MaxFailures:  10,
+	}
+}
+
+var exprLimits atomic.Pointer[ExprLimits]
+
+// memoryBudget: 保护 vm.MemoryBudget, 写入时等待本包正在运行的表达式结束
+var memoryBudget sync.RWMutex
+
+func init() {
+	SetExprLimits(DefaultExprLimits())
+}
+
+// SetExprLimits: 设置规则表达式的沙箱限制, 对之后编译的表达式生效
+//
+// 注意: MemoryBudget 会直接写入 expr 的包级变量 vm.MemoryBudget, 改变进程内所有 expr 使用方
+// (包括其他依赖库) 的内存预算, 而不仅是灰度规则。expr v1.16 的 VM 在每次运行时都从该全局变量读取预算,
+// 不支持按次传入, 因此无法隔离。本包只能与自己运行的表达式同步, 应在服务启动时、
+// 加载灰度配置及其他使用 expr 的代码运行前调用; 预算发生变化时会记录一条 Warn 日志。
+func SetExprLimits(limits ExprLimits) {
+	if limits.MemoryBudget == 0 {
+		limits.MemoryBudget = DefaultMemoryBudget
+	}
+
+	memoryBudget.Lock()
+	previous := vm.MemoryBudget
+	vm.MemoryBudget = limits.MemoryBudget
+	memoryBudget.Unlock()
+	exprLimits.Store(&limits)
+
+	if previous != limits.MemoryBudget {
+		logger.Warn(
+			context.Background(),
+			"[go-helper] expr vm.MemoryBudget changed for the whole process",
+			field.Any("previous", previous),
+			field.Any("current", limits.MemoryBudget),
+		)
+	}
+}
+
+func getExprLimits() *ExprLimits {
+	return exprLimits.Load()
+}
+
+// Expresion: 经过沙箱校验的规则表达式
+//
+// 连续运行失败达到 ExprLimits.MaxFailures 次后自动禁用, 之后视为不匹配, 直到配置重新加载。
+// 失败日志只在每轮连续失败的第一次和禁用时各记录一次, 避免在请求路径上刷日志。
 type Expresion struct {
+	source    string
+	program   *vm.Program
+	usesExtra bool // 表达式是否引用了 extra
+
+	maxFailures int32
+	failures    atomic.Int32
+	disabled    atomic.Bool
+	reportOnce  sync.Once
+}
+
+// CompileExpresion: 校验并编译表达式
+//
+// 编译前会检查 AST 节点数、运算符与内置函数是否在允许范围内, 禁止调用外部函数。
+func CompileExpresion(source string) (*Expresion, error) {
+	limits := getExprLimits()
+
+	tree, err := parser.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+
+	checker := &sandboxVisitor{limits: limits}
+	ast.Walk(&tree.Node, checker)
+	if checker.err != nil {
+		return nil, checker.err
+	}
+
+	program, err := expr.Compile(source, expr.AsBool())
+	if err != nil {
+		return nil, err
+	}
+
+	return &Expresion{
+		source:      source,
+		program:     program,
+		usesExtra:   usesExtra(program.Node()),
+		maxFailures: limits.MaxFailures,
+	}, nil
+}
+
+// Disabled: 表达式是否已被自动禁用
+func (e *Expresion) Disabled() bool {
+	return e.disabled.Load()
+}
+
+// Run: 运行表达式, 已禁用或运行失败时返回 false
+func (e *Expresion) Run(ctx context.Context, param map[string]interface{}) bool {
+	return e.run(ctx, param, false)
+}
+
+// run: probe 为 true 时只运行不记录失败, 用于分桶方案迁移对比等不影响分组结果的场景
+func (e *Expresion) run(ctx context.Context, param map[string]interface{}, probe bool) bool {
+	if e.disabled.Load() {
+		return false
+	}
+
+	memoryBudget.RLock()
+	match, err := expr.Run(e.program, param)
+	memoryBudget.RUnlock()
+	if probe {
+		ret, _ := match.(bool)
+		return err == nil && ret
+	}
+	if err != nil {
+		e.fail(ctx, err)
+		return false
+	}
+
+	e.failures.Store(0)
+	ret, _ := match.(bool)
+	return ret
+}
+
+func (e *Expresion) fail(ctx context.Context, err error) {
+	failures := e.failures.Add(1)
+	// 只在连续失败的第一次记录警告, 运行成功后计数清零, 下一轮失败会再次记录
+	if failures == 1 {
+		logger.Warn(
+			ctx,
+			"expr.Run failed, further consecutive failures are not logged",
+			field.String("error", err.Error()),
+			field.String("rule.Expresion", e.source),
+		)
+	}
+
+	if e.maxFailures <= 0 || failures < e.maxFailures {
+		return
+	}
+
+	e.disabled.Store(true)
+	e.reportOnce.Do(func() {
+		logger.Error(
+			ctx,
+			"[go-helper] gray expression disabled after repeated failures",
+			field.String("rule.Expresion", e.source),
+			field.Any("failures", e.maxFailures),
+			field.Bool("memory_budget_exceeded", strings.Contains(err.Error(), "memory budget exceeded")),
+			field.String("last_error", err.Error()),
+		)
+	})
+}
+
+// sandboxVisitor: 检查表达式 AST 是否满足沙箱限制
+type sandboxVisitor struct {
+	limits *ExprLimits
+	nodes  int
+	err    error
+}
+
+func (v *sandboxVisitor) Visit(node *ast.Node) {
+	if v.err != nil {
+		return
+	}
+
+	v.nodes++
+	if v.limits.MaxNodes > 0 && v.nodes > v.limits.MaxNodes {
+		v.err = fmt.Errorf("expression too large, exceeds %d nodes", v.limits.MaxNodes)
+		return
+	}
+
+	switch n := (*node).(type) {
+	case *ast.BinaryNode:
+		v.checkOperator(n.Operator)
+	case *ast.UnaryNode:
+		v.checkOperator(n.Operator)
+	case *ast.BuiltinNode:
+		if _, ok := v.limits.Functions[n.Name]; !ok {
+			v.err = fmt.Errorf("function %q is not allowed", n.Name)
+		}
+	case *ast.CallNode:
+		v.err = fmt.Errorf("function call %q is not allowed", n.Callee.String())
+	case *ast.VariableDeclaratorNode:
+		v.err = fmt.Errorf("variable declaration %q is not allowed", n.Name)
+	}
+}
+
+func (v *sandboxVisitor) checkOperator(operator string) {
+	if _, ok := v.limits.Operators[operator]; !ok {
+		v.err = fmt.Errorf("operator %q is not allowed", operator)
+	}
+}
+
+func toSet(items ...string) map[string]struct{} {
+	ret := make(map[string]struct{}, len(items))
+	for _, item := range items {
+		ret[item] = struct{}{}
+	}
+	return ret
 }
@@ -0,0 +1,97 @@
+package gray
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/expr-lang/expr/vm"
+	"github.com/zeebo/assert"
+)
+
+func TestCompileExpresionSandbox(t *testing.T) {
+	_, err := CompileExpresion(`user.ctime >= 1741017900 && app.platform in ["ios", "android"]`)
+	assert.NoError(t, err)
+	_, err = CompileExpresion(`lower(user.channel) startsWith "we" and not (user.role in [1, 2])`)
+	assert.NoError(t, err)
+
+	// 超过节点数上限
+	items := make([]string, 0, 300)
+	for i := 0; i < 300; i++ {
+		items = append(items, fmt.Sprintf("%d", i))
+	}
+	_, err = CompileExpresion(fmt.Sprintf("user.account_id in [%s]", strings.Join(items, ",")))
+	assert.Error(t, err)
+
+	// 不在白名单中的运算符与函数
+	_, err = CompileExpresion(`user.nickname matches "^a.*"`)
+	assert.Error(t, err)
+	_, err = CompileExpresion(`len(repeat(user.nickname, 1000000)) > 0`)
+	assert.Error(t, err)
+	_, err = CompileExpresion(`let x = 1; x == 1`)
+	assert.Error(t, err)
+}
+
+func TestExpresionAutoDisable(t *testing.T) {
+	e, err := CompileExpresion(`user.account_id / user.role > 1`)
+	assert.NoError(t, err)
+
+	param := map[string]interface{}{"user": map[string]interface{}{"account_id": "x", "role": 0}}
+
+	// 迁移对比等探测运行不计入连续失败次数
+	for i := int32(0); i < e.maxFailures; i++ {
+		assert.False(t, e.run(context.Background(), param, true))
+	}
+	assert.False(t, e.Disabled())
+
+	// 运行成功后连续失败计数清零, 下一轮失败重新计数(并重新记录一次警告)
+	valid := map[string]interface{}{"user": map[string]interface{}{"account_id": 10, "role": 1}}
+	assert.False(t, e.Run(context.Background(), param))
+	assert.Equal(t, e.failures.Load(), int32(1))
+	assert.True(t, e.Run(context.Background(), valid))
+	assert.Equal(t, e.failures.Load(), int32(0))
+
+	for i := int32(0); i < e.maxFailures; i++ {
+		assert.False(t, e.Disabled())
+		assert.False(t, e.Run(context.Background(), param))
+	}
+	assert.True(t, e.Disabled())
+
+	// 禁用后即使参数合法也视为不匹配
+	assert.False(t, e.Run(context.Background(), valid))
+}
+
+func TestSetExprLimits(t *testing.T) {
+	defer SetExprLimits(DefaultExprLimits())
+
+	// 默认设置非零的内存预算
+	assert.Equal(t, getExprLimits().MemoryBudget, DefaultMemoryBudget)
+	assert.Equal(t, vm.MemoryBudget, DefaultMemoryBudget)
+
+	e, err := CompileExpresion(`user.role > 1`)
+	assert.NoError(t, err)
+	param := map[string]interface{}{"user": map[string]interface{}{"role": 2}}
+
+	// 与正在运行的表达式并发设置
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				assert.True(t, e.Run(context.Background(), param))
+			}
+		}()
+	}
+	limits := DefaultExprLimits()
+	limits.MemoryBudget = 0
+	SetExprLimits(limits)
+	wg.Wait()
+	assert.Equal(t, getExprLimits().MemoryBudget, DefaultMemoryBudget)
+
+	limits.MemoryBudget = 10
+	SetExprLimits(limits)
+	assert.Equal(t, vm.MemoryBudget, uint(10))
+}
@@ -0,0 +1,237 @@
+package gray
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/everfir/logger-go"
+	"github.com/everfir/logger-go/structs/field"
+	"github.com/expr-lang/expr/ast"
+)
+
+// ExtraTargetPrefix: Targets 中以该前缀开头的键匹配用户 Extra 中的字段, 例如 "extra.tier"
+const ExtraTargetPrefix = "extra."
+
+// ExtraFieldType: Extra 字段的类型提示
+type ExtraFieldType string
+
+const (
+	ExtraFieldType_String  ExtraFieldType = "string"
+	ExtraFieldType_Int     ExtraFieldType = "int"
+	ExtraFieldType_Float   ExtraFieldType = "float"
+	ExtraFieldType_Bool    ExtraFieldType = "bool"
+	ExtraFieldType_Strings ExtraFieldType = "strings"
+)
+
+// ExtraSchema: Extra 字段的类型提示, key 为字段路径(支持 "a.b" 形式的嵌套路径)
+//
+// 配置了类型提示的字段会在表达式与 Targets 匹配前转换为对应类型,
+// 无法转换的字段视为不存在; 未配置的字段保持 JSON 解析后的原始类型。
+type ExtraSchema map[string]ExtraFieldType
+
+// Validate: 校验类型提示
+func (schema ExtraSchema) Validate() error {
+	for path, typ := range schema {
+		switch typ {
+		case ExtraFieldType_String, ExtraFieldType_Int, ExtraFieldType_Float, ExtraFieldType_Bool, ExtraFieldType_Strings:
+		default:
+			return fmt.Errorf("invalid extra_schema[%s]: unknown type[%s]", path, typ)
+		}
+	}
+	return nil
+}
+
+// Typed: 按类型提示转换 Extra, 不会修改传入的 data
+func (schema ExtraSchema) Typed(data map[string]any) map[string]any {
+	if len(schema) == 0 {
+		return data
+	}
+
+	ret := cloneMap(data)
+	for path, typ := range schema {
+		keys := strings.Split(path, ".")
+		val, exist := lookupPath(ret, keys)
+		if !exist {
+			continue
+		}
+
+		typed, ok := convertExtra(val, typ)
+		if !ok {
+			typed = nil
+		}
+		setPath(ret, keys, typed)
+	}
+	return ret
+}
+
+// Lookup: 获取指定路径的字段, 并按类型提示转换
+func (schema ExtraSchema) Lookup(data map[string]any, path string) (any, bool) {
+	val, exist := lookupPath(data, strings.Split(path, "."))
+	if !exist {
+		return nil, false
+	}
+
+	if typ, ok := schema[path]; ok {
+		return convertExtra(val, typ)
+	}
+	return val, true
+}
+
+func lookupPath(data map[string]any, keys []string) (any, bool) {
+	var cur any = data
+	for _, key := range keys {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// setPath: 沿路径复制嵌套对象后写入, 避免修改请求内共享的解析结果
+func setPath(data map[string]any, keys []string, val any) {
+	cur := data
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := cur[key].(map[string]any)
+		if !ok {
+			return
+		}
+		next = cloneMap(next)
+		cur[key] = next
+		cur = next
+	}
+	cur[keys[len(keys)-1]] = val
+}
+
+func cloneMap(data map[string]any) map[string]any {
+	ret := make(map[string]any, len(data))
+	for k, v := range data {
+		ret[k] = v
+	}
+	return ret
+}
+
+func convertExtra(val any, typ ExtraFieldType) (any, bool) {
+	switch typ {
+	case ExtraFieldType_String:
+		return scalarString(val)
+	case ExtraFieldType_Int:
+		switch v := val.(type) {
+		case float64:
+			if v != float64(int64(v)) {
+				return nil, false
+			}
+			return int64(v), true
+		case string:
+			i, err := strconv.ParseInt(v, 10, 64)
+			return i, err == nil
+		}
+	case ExtraFieldType_Float:
+		switch v := val.(type) {
+		case float64:
+			return v, true
+		case string:
+			f, err := strconv.ParseFloat(v, 64)
+			return f, err == nil
+		}
+	case ExtraFieldType_Bool:
+		switch v := val.(type) {
+		case bool:
+			return v, true
+		case string:
+			b, err := strconv.ParseBool(v)
+			return b, err == nil
+		}
+	case ExtraFieldType_Strings:
+		ret := extraStrings(val)
+		return ret, ret != nil
+	}
+	return nil, false
+}
+
+func scalarString(val any) (string, bool) {
+	switch v := val.(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case bool:
+		return strconv.FormatBool(v), true
+	default:
+		return "", false
+	}
+}
+
+// extraStrings: 将字段转换为字符串列表, 用于 Targets 匹配
+func extraStrings(val any) []string {
+	switch v := val.(type) {
+	case []string:
+		return v
+	case []any:
+		ret := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := scalarString(item); ok {
+				ret = append(ret, s)
+			}
+		}
+		return ret
+	default:
+		if s, ok := scalarString(v); ok {
+			return []string{s}
+		}
+		return nil
+	}
+}
+
+// usesExtra: 判断表达式是否引用了顶层变量 extra
+func usesExtra(node ast.Node) bool {
+	visitor := &extraVisitor{}
+	ast.Walk(&node, visitor)
+	return visitor.found
+}
+
+type extraVisitor struct {
+	found bool
+}
+
+func (v *extraVisitor) Visit(node *ast.Node) {
+	if ident, ok := (*node).(*ast.IdentifierNode); ok && ident.Value == "extra" {
+		v.found = true
+	}
+}
+
+// 解析失败日志每个账户只记录一次, 超过上限后清空重新计数
+const maxExtraWarnedAccounts = 10000
+
+var extraWarned = struct {
+	lock     sync.Mutex
+	accounts map[uint64]struct{}
+}{accounts: map[uint64]struct{}{}}
+
+func warnExtraOnce(ctx context.Context, accountId uint64, err error) {
+	extraWarned.lock.Lock()
+	if _, exist := extraWarned.accounts[accountId]; exist {
+		extraWarned.lock.Unlock()
+		return
+	}
+	if len(extraWarned.accounts) >= maxExtraWarnedAccounts {
+		extraWarned.accounts = map[uint64]struct{}{}
+	}
+	extraWarned.accounts[accountId] = struct{}{}
+	extraWarned.lock.Unlock()
+
+	logger.Warn(
+		ctx,
+		"[go-helper] parse account extra failed, extra rules will not match",
+		field.Any("account_id", accountId),
+		field.String("err", err.Error()),
+	)
+}
@@ -0,0 +1,71 @@
+package gray
+
+import (
+	"context"
+	"testing"
+
+	"github.com/everfir/go-helpers/consts"
+	"github.com/everfir/go-helpers/define"
+	"github.com/everfir/go-helpers/env"
+	"github.com/zeebo/assert"
+)
+
+func TestExtraRule(t *testing.T) {
+	targets := map[string][]string{
+		"extra.tier":            {"gold", "silver"},
+		"extra.campaign.source": {"wechat"},
+	}
+	levelCity := `extra.level >= 3 && extra.city == "beijing"`
+
+	cases := []struct {
+		targets   map[string][]string
+		expresion string
+		extra     string
+		usesExtra bool
+		want      bool
+	}{
+		// 按 Extra 字段匹配目标
+		{targets: targets, extra: `{"tier":"gold","campaign":{"source":"wechat"}}`, want: true},
+		{targets: targets, extra: `{"tier":"bronze","campaign":{"source":"wechat"}}`, want: false},
+		{targets: targets, extra: `{"tier":"gold"}`, want: false},
+		// 解析失败视为不匹配
+		{targets: targets, extra: `not json`, want: false},
+		// level 按类型提示从字符串转换为整数
+		{expresion: levelCity, extra: `{"level":"5","city":"beijing"}`, usesExtra: true, want: true},
+		{expresion: levelCity, extra: `{"level":1,"city":"beijing"}`, usesExtra: true, want: false},
+		{expresion: levelCity, extra: `{"level":`, usesExtra: true, want: false},
+		// 未引用 extra 的规则不受解析失败影响
+		{expresion: `user.account_id == 1`, extra: `{"level":`, usesExtra: false, want: true},
+	}
+
+	for _, c := range cases {
+		g := Gray{
+			ExtraSchema: ExtraSchema{"level": ExtraFieldType_Int},
+			Feature: map[string]*FeatureConfig{
+				"f": {Rule: []*TrafficRule{{
+					Enable:      true,
+					Rate:        1,
+					TrafficRate: 1,
+					TargetGroup: "b",
+					Targets:     c.targets,
+					Expresion:   c.expresion,
+				}}},
+			},
+		}
+		assert.NoError(t, g.Validate())
+		g.Format()
+		rule := g.Feature["f"].Rule[0]
+
+		ctx := context.WithValue(context.Background(), consts.AccountInfoKey, &define.AccountInfo{AccountId: 1, Extra: c.extra})
+		ctx = env.WithAccountExtra(ctx)
+		if c.expresion != "" {
+			assert.Equal(t, rule.expresion.usesExtra, c.usesExtra)
+		}
+		assert.Equal(t, rule.Group(ctx), c.want)
+	}
+}
+
+func TestExtraSchemaValidate(t *testing.T) {
+	assert.NoError(t, ExtraSchema{"a": ExtraFieldType_Strings}.Validate())
+	assert.Error(t, ExtraSchema{"a": "map"}.Validate())
+}
@@ -2,6 +2,7 @@ package gray
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/everfir/go-helpers/consts"
 	"github.com/everfir/logger-go"
@@ -26,21 +27,27 @@ func (gc *GrayConfig) Validate() error {
 }
 
 type Gray struct {
-	Feature map[string]*FeatureConfig `json:"feature"`
+	Feature     map[string]*FeatureConfig `json:"feature"`
+	ExtraSchema ExtraSchema               `json:"extra_schema,omitempty"` // 用户 Extra 字段的类型提示
 }
 
 // Format: 格式化配置
 func (g *Gray) Format() {
-	for _, config := range g.Feature {
+	for name, config := range g.Feature {
 		config.Format()
+		config.setBucketing(name)
+		config.setExtraSchema(g.ExtraSchema)
 	}
 }
 
 // Validate: 校验配置
 func (g *Gray) Validate() error {
-	for _, config := range g.Feature {
+	if err := g.ExtraSchema.Validate(); err != nil {
+		return err
+	}
+	for name, config := range g.Feature {
 		if err := config.Validate(); err != nil {
-			return err
+			return fmt.Errorf("feature[%s]: %w", name, err)
 		}
 	}
 	return nil
@@ -51,15 +58,32 @@ func (g *Gray) Group(ctx context.Context, feature string) consts.TrafficGroup {
 		return consts.TrafficGroup_A
 	}
 
-	return g.Feature[feature].Group(ctx)
+	return g.Feature[feature].Group(ctx, feature)
+}
+
+// FeaturesByStatus: 按生命周期状态列出该业务下的实验名称, 用于发现长期未清理的实验
+func (g *Gray) FeaturesByStatus() map[FeatureStatus][]string {
+	return featuresByStatus(g.Feature)
+}
+
+// MigrationStats: 获取该业务下处于分桶方案迁移模式的实验统计
+func (g *Gray) MigrationStats() map[string]HashMigrationStats {
+	ret := make(map[string]HashMigrationStats)
+	for name, config := range g.Feature {
+		if stats, ok := config.MigrationStats(); ok {
+			ret[name] = stats
+		}
+	}
+	return ret
 }
 
 // Experimental 判断某个功能是否属于某个灰度组
 //
 // 该方法的逻辑如下：
 // 1. 如果功能未配置（Feature 未在 Gray 结构体中定义），认为该功能默认启用（即稳定分支），返回 TrafficGroup_A。
-// 2. 如果功能已配置但未启用（Enable 字段为 false），返回 TrafficGroup_A。
-// 3. 根据功能的实验分组进行判断：
+// 2. 根据功能的生命周期状态与实验分组进行判断（详见 FeatureConfig.Group）：
+//   - concluded 状态返回 winner 组；draft 状态仅白名单用户进入实验组。
+//   - 未启用（disabled）或已清理（cleaned）的功能返回 TrafficGroup_A。
 //   - 如果分组未知（TrafficGroup_Unknow），返回 TrafficGroup_A，并记录警告日志。
 //   - 如果分组为 B（TrafficGroup_B），返回 TrafficGroup_B（表示该功能对该分组开放）。
 //   - 其他情况返回 TrafficGroup_A（表示该功能对该分组未开放）。
@@ -94,13 +118,9 @@ func (g Gray) Experimental(ctx context.Context, feature string) consts.TrafficGr
 		// 功能未配置，返回稳定分支
 		return consts.TrafficGroup_A
 	}
-	if !config.Enable {
-		// 功能已配置但未启用，返回稳定分支
-		return consts.TrafficGroup_A
-	}
 
-	// 根据用户确定分组
-	group := config.Group(ctx)
+	// 根据生命周期状态与用户确定分组
+	group := config.Group(ctx, feature)
 	if group == consts.TrafficGroup_Unknow {
 		// 记录未知分组的警告日志
 		logger.Warn(
@@ -0,0 +1,70 @@
+package gray
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/everfir/go-helpers/consts"
+)
+
+// StickyStore: 实验分组的粘性存储
+//
+// 配置后，running 状态的实验会记录每个用户的首次分组并在之后复用，
+// paused 状态的实验只返回已有分组，不再产生新的分配。
+type StickyStore interface {
+	Get(ctx context.Context, business, feature string, accountId uint64) (consts.TrafficGroup, bool)
+	Set(ctx context.Context, business, feature string, accountId uint64, group consts.TrafficGroup)
+}
+
+var stickyStore atomic.Pointer[StickyStore]
+
+// SetStickyStore: 设置全局粘性存储, 传入 nil 表示关闭
+func SetStickyStore(store StickyStore) {
+	if store == nil {
+		stickyStore.Store(nil)
+		return
+	}
+	stickyStore.Store(&store)
+}
+
+func getStickyStore() StickyStore {
+	store := stickyStore.Load()
+	if store == nil {
+		return nil
+	}
+	return *store
+}
+
+// NewMemoryStickyStore: 创建进程内的粘性存储, 适用于单实例服务与测试
+func NewMemoryStickyStore() *MemoryStickyStore {
+	return &MemoryStickyStore{
+		lock: sync.RWMutex{},
+		data: map[string]consts.TrafficGroup{},
+	}
+}
+
+type MemoryStickyStore struct {
+	lock sync.RWMutex
+	data map[string]consts.TrafficGroup
+}
+
+func (s *MemoryStickyStore) Get(ctx context.Context, business, feature string, accountId uint64) (consts.TrafficGroup, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	group, exist := s.data[stickyKey(business, feature, accountId)]
+	return group, exist
+}
+
+func (s *MemoryStickyStore) Set(ctx context.Context, business, feature string, accountId uint64, group consts.TrafficGroup) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.data[stickyKey(business, feature, accountId)] = group
+}
+
+func stickyKey(business, feature string, accountId uint64) string {
+	return fmt.Sprintf("%s:%s:%d", business, feature, accountId)
+}
@@ -4,16 +4,14 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/everfir/go-helpers/consts"
 	"github.com/everfir/go-helpers/define"
 	"github.com/everfir/go-helpers/env"
-	"github.com/everfir/go-helpers/internal/helper/encode"
 	"github.com/everfir/go-helpers/internal/helper/slice"
 	"github.com/everfir/logger-go"
 	"github.com/everfir/logger-go/structs/field"
-	"github.com/expr-lang/expr"
-	"github.com/expr-lang/expr/vm"
 )
 
 // TrafficRule: 分流策略
@@ -22,14 +20,16 @@ type TrafficRule struct {
 
 	Rate      float64             `json:"rate"`      // 首次分流比例, 从所有流量中，获取部分流量，用于判断余下的条件
 	Expresion string              `json:"expresion"` // 表达式，在Rule模式下生效
-	Targets   map[string][]string `json:"targets"`   // 匹配目标
+	Targets   map[string][]string `json:"targets"`   // 匹配目标, 支持 "extra.xxx" 匹配用户 Extra 字段
 	WhiteList []string            `json:"whitelist"` // 白名单
 	BlackList []string            `json:"blacklist"` // 黑名单
 
 	TrafficRate float64 `json:"traffic_rate"` // 分流比例, 满足条件后，分流到指定组的流量比例
 	TargetGroup string  `json:"target_group"` // 所属分流组
 
-	expresionProgram *vm.Program
+	expresion   *Expresion  // 经过沙箱校验的表达式, 由 Validate 编译
+	extraSchema ExtraSchema // 业务配置的 Extra 类型提示, 由 Gray.Format 下发
+	bucketing   bucketing   // 实验的分桶方案, 由 Gray.Format 下发
 }
 
 // Format: 格式化配置
@@ -49,7 +49,7 @@ func (rule *TrafficRule) Format() {
 // 2. 检查 TargetGroup 是否在有效范围内（应为 b-z）。
 // 3. 检查 Rate 是否在有效范围内（0 到 1 之间）。
 // 4. 检查 TrafficRate 是否在有效范围内（0 到 1 之间）。
-// 5. 如果 Expresion 不为空，尝试编译表达式并检查是否有效。
+// 5. 如果 Expresion 不为空，按沙箱限制（节点数、运算符、函数白名单）编译表达式并检查是否有效。
 //
 // 返回值：
 //   - 如果所有检查都通过，返回 nil；
@@ -78,7 +78,7 @@ func (rule *TrafficRule) Validate() error {
 	// 预编译表达式
 	if rule.Expresion != "" {
 		var err error
-		rule.expresionProgram, err = expr.Compile(rule.Expresion, expr.AsBool())
+		rule.expresion, err = CompileExpresion(rule.Expresion)
 		if err != nil {
 			return fmt.Errorf("compile rule.Expresion[%s] failed: %w", rule.Expresion, err)
 		}
@@ -97,7 +97,8 @@ func (rule *TrafficRule) Validate() error {
 //   - 检查设备信息，如果设备不在目标设备列表中，则返回 false，表示不匹配。
 //   - 检查平台信息，如果平台不在目标平台列表中，则返回 false，表示不匹配。
 //   - 检查应用类型，如果应用类型不在目标应用类型列表中，则返回 false，表示不匹配。
-//   - 如果定义了表达式，则运行预编译的表达式并根据结果返回匹配状态。
+//   - 检查 "extra.xxx" 目标，如果用户 Extra 字段不在目标列表中，则返回 false，表示不匹配。
+//   - 如果定义了表达式，则运行预编译的表达式并根据结果返回匹配状态；连续失败的表达式会被自动禁用并视为不匹配。
 //   - 最后进行二次分流，如果用户的哈希值不符合设定的流量比例，则返回 false，表示不匹配。
 //   - 如果所有检查都通过，则返回 true，表示匹配。
 //
@@ -117,6 +118,11 @@ func (rule *TrafficRule) Validate() error {
 //	match := rule.Group(context.Background())
 //	fmt.Println(match) // true 或 false
 func (rule *TrafficRule) Group(ctx context.Context) (match bool) {
+	return rule.group(ctx, rule.bucketing, false)
+}
+
+// group: 使用指定的分桶方案进行分组, probe 为 true 时表达式失败不计入连续失败次数
+func (rule *TrafficRule) group(ctx context.Context, b bucketing, probe bool) (match bool) {
 	// 需要参与判断的数据
 	var device consts.TDevice = env.Device(ctx)               // 获取设备信息
 	var appType consts.TAppType = env.AppType(ctx)            // 获取应用类型
@@ -135,7 +141,7 @@ func (rule *TrafficRule) Group(ctx context.Context) (match bool) {
 
 	// 检查白名单
 	accountId := fmt.Sprintf("%d", accountInfo.AccountId) // 将账户 ID 转换为字符串
-	if _, exist := slice.Find(rule.WhiteList, accountId); exist {
+	if rule.InWhiteList(accountId) {
 		// 如果账户在白名单中，返回 true，表示匹配
 		return true
 	}
@@ -147,7 +153,7 @@ func (rule *TrafficRule) Group(ctx context.Context) (match bool) {
 	}
 
 	// 先进行首次分流
-	if !rateMatch(accountId, rule.Rate) {
+	if !b.match(accountId, rule.Rate) {
 		// 如果用户的哈希值不符合设定的比例，返回 false，表示不匹配
 		return false
 	}
@@ -176,29 +182,30 @@ func (rule *TrafficRule) Group(ctx context.Context) (match bool) {
 		}
 	}
 
-	// 检查表达式
-	if rule.Expresion != "" && rule.expresionProgram != nil {
-		param := makeParam(ctx, &accountInfo)                // 创建表达式参数
-		match, err := expr.Run(rule.expresionProgram, param) // 运行表达式
-		if err != nil {
-			// 如果表达式运行失败，记录警告日志并返回 false，表示不匹配
-			logger.Warn(
-				ctx,
-				"expr.Run failed",
-				field.String("error", err.Error()),
-				field.String("rule.Expresion", rule.Expresion),
-				field.Any("param", param),
-			)
+	// 检查 Extra 目标与引用了 extra 的表达式, Extra 解析失败视为不匹配
+	var extra map[string]any
+	if rule.hasExtraTargets() || (rule.expresion != nil && rule.expresion.usesExtra) {
+		var err error
+		if extra, err = env.AccountExtra(ctx); err != nil {
+			warnExtraOnce(ctx, accountInfo.AccountId, err)
 			return false
 		}
-		if !match.(bool) {
-			// 如果表达式结果为 false，返回 false，表示不匹配
+		if !rule.extraMatch(extra) {
+			return false
+		}
+	}
+
+	// 检查表达式
+	if rule.Expresion != "" && rule.expresion != nil {
+		param := makeParam(ctx, &accountInfo, rule.extraSchema.Typed(extra)) // 创建表达式参数
+		if !rule.expresion.run(ctx, param, probe) {
+			// 如果表达式结果为 false 或运行失败，返回 false，表示不匹配
 			return false
 		}
 	}
 
 	// 二次分流
-	if !rateMatch(accountId, rule.TrafficRate) {
+	if !b.match(accountId, rule.TrafficRate) {
 		// 如果用户的哈希值不符合设定的流量比例，返回 false，表示不匹配
 		return false
 	}
@@ -207,14 +214,47 @@ func (rule *TrafficRule) Group(ctx context.Context) (match bool) {
 	return true
 }
 
-func rateMatch(accountId string, rate float64) bool {
-	hash := encode.HashString(accountId)
-	bucket := hash % 1000
-	threshold := uint64(rate * float64(1000))
-	return bucket < threshold
+// InWhiteList: 判断账户是否在白名单中, 依赖 Format 对白名单排序
+func (rule *TrafficRule) InWhiteList(accountId string) bool {
+	_, exist := slice.Find(rule.WhiteList, accountId)
+	return exist
+}
+
+func (rule *TrafficRule) hasExtraTargets() bool {
+	for key, targets := range rule.Targets {
+		if strings.HasPrefix(key, ExtraTargetPrefix) && len(targets) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// extraMatch: 检查用户 Extra 是否满足所有 "extra.xxx" 目标, 字段为列表时任一元素命中即可
+func (rule *TrafficRule) extraMatch(extra map[string]any) bool {
+	for key, targets := range rule.Targets {
+		if !strings.HasPrefix(key, ExtraTargetPrefix) || len(targets) == 0 {
+			continue
+		}
+
+		val, exist := rule.extraSchema.Lookup(extra, strings.TrimPrefix(key, ExtraTargetPrefix))
+		if !exist {
+			return false
+		}
+
+		var found bool
+		for _, v := range extraStrings(val) {
+			if _, found = slice.Find(targets, v); found {
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
 }
 
-func makeParam(ctx context.Context, accountInfo *define.AccountInfo) (ret map[string]interface{}) {
+func makeParam(ctx context.Context, accountInfo *define.AccountInfo, extra map[string]any) (ret map[string]interface{}) {
 	templateIds := make([]interface{}, 0, len(accountInfo.TemplateIDs))
 	for _, id := range accountInfo.TemplateIDs {
 		templateIds = append(templateIds, id)
@@ -248,5 +288,6 @@ func makeParam(ctx context.Context, accountInfo *define.AccountInfo) (ret map[st
 
 	ret["user"] = m
 	ret["app"] = d
+	ret["extra"] = extra
 	return ret
 }
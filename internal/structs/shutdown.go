@@ -0,0 +1,351 @@
+package structs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ShutdownAllBusiness: 对所有业务生效的停服规则的 key
+const ShutdownAllBusiness = "*"
+
+// ShutdownConfig: 停服配置, key 为业务, ShutdownAllBusiness 对所有业务生效
+//
+// 每个业务的值可以是:
+//   - bool: 兼容旧格式, true 表示整个业务停服
+//   - 规则对象或规则数组: 按顺序匹配, 命中任一规则即停服
+type ShutdownConfig map[string]ShutdownRules
+
+// ShutdownRules: 一个业务的停服规则
+type ShutdownRules []ShutdownRule
+
+// ShutdownRule: 停服规则, 条件为空表示不限制, 所有条件都满足时命中
+type ShutdownRule struct {
+	Routes     []string `json:"routes,omitempty"`      // 请求路径, 以 * 结尾时按前缀匹配, 包含其他通配符时按 path.Match 匹配
+	Methods    []string `json:"methods,omitempty"`     // HTTP 方法, 忽略大小写
+	Platforms  []string `json:"platforms,omitempty"`   // 平台, 例如 ios、android
+	AppTypes   []string `json:"app_types,omitempty"`   // 应用类型, 例如 app
+	Versions   []string `json:"versions,omitempty"`    // 客户端版本范围, 任一范围满足即可, 例如 ">=1.2.0 <2.0.0"; 请求没有版本时不满足
+	Message    string   `json:"message,omitempty"`     // 返回给客户端的提示
+	RetryAfter int      `json:"retry_after,omitempty"` // 秒, 大于 0 时设置 Retry-After header
+	Allowlist  []string `json:"allowlist,omitempty"`   // 不受停服影响的账号 ID
+
+	compiled *shutdownMatcher // 由 Validate 编译
+}
+
+// ShutdownRequest: 参与停服规则匹配的请求信息
+type ShutdownRequest struct {
+	Path      string
+	Method    string
+	Platform  string
+	AppType   string
+	Version   string
+	AccountId string
+}
+
+// UnmarshalJSON: 兼容 bool 格式, 同时支持单个规则对象与规则数组
+func (rules *ShutdownRules) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	switch {
+	case bytes.Equal(data, []byte("null")), bytes.Equal(data, []byte("false")):
+		*rules = nil
+	case bytes.Equal(data, []byte("true")):
+		*rules = ShutdownRules{{}}
+	case bytes.HasPrefix(data, []byte("{")):
+		var rule ShutdownRule
+		if err := json.Unmarshal(data, &rule); err != nil {
+			return err
+		}
+		*rules = ShutdownRules{rule}
+	default:
+		var list []ShutdownRule
+		if err := json.Unmarshal(data, &list); err != nil {
+			return err
+		}
+		*rules = list
+	}
+	return nil
+}
+
+// MarshalJSON: 没有规则时输出 false, 只有一个不带条件与提示的规则时输出 true, 与旧格式保持一致
+func (rules ShutdownRules) MarshalJSON() ([]byte, error) {
+	switch {
+	case len(rules) == 0:
+		return []byte("false"), nil
+	case len(rules) == 1 && rules[0].isZero():
+		return []byte("true"), nil
+	default:
+		return json.Marshal([]ShutdownRule(rules))
+	}
+}
+
+func (rule *ShutdownRule) isZero() bool {
+	return len(rule.Routes) == 0 && len(rule.Methods) == 0 && len(rule.Platforms) == 0 && len(rule.AppTypes) == 0 &&
+		len(rule.Versions) == 0 && rule.Message == "" && rule.RetryAfter == 0 && len(rule.Allowlist) == 0
+}
+
+// Validate: 校验并编译所有规则
+func (c *ShutdownConfig) Validate() error {
+	for business, rules := range *c {
+		for i := range rules {
+			if err := rules[i].Validate(); err != nil {
+				return fmt.Errorf("invalid shutdown rule %s[%d]: %w", business, i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Validate: 校验并编译规则
+func (rule *ShutdownRule) Validate() error {
+	m, err := rule.compile()
+	if err != nil {
+		return err
+	}
+	rule.compiled = m
+	return nil
+}
+
+func (rule *ShutdownRule) compile() (*shutdownMatcher, error) {
+	if rule.RetryAfter < 0 {
+		return nil, fmt.Errorf("retry_after[%d] should not be negative", rule.RetryAfter)
+	}
+
+	m := &shutdownMatcher{
+		routes:    map[string]struct{}{},
+		methods:   toLowerSet(rule.Methods),
+		platforms: toLowerSet(rule.Platforms),
+		appTypes:  toLowerSet(rule.AppTypes),
+		allowlist: map[string]struct{}{},
+	}
+	for _, route := range rule.Routes {
+		switch {
+		case strings.HasSuffix(route, "*") && !strings.ContainsAny(strings.TrimSuffix(route, "*"), "*?["):
+			m.prefixes = append(m.prefixes, strings.TrimSuffix(route, "*"))
+		case strings.ContainsAny(route, "*?["):
+			if _, err := path.Match(route, ""); err != nil {
+				return nil, fmt.Errorf("invalid route[%s]: %w", route, err)
+			}
+			m.patterns = append(m.patterns, route)
+		default:
+			m.routes[route] = struct{}{}
+		}
+	}
+	m.anyRoute = len(rule.Routes) == 0
+	for _, versions := range rule.Versions {
+		r, err := parseVersionRange(versions)
+		if err != nil {
+			return nil, fmt.Errorf("invalid versions[%s]: %w", versions, err)
+		}
+		m.versions = append(m.versions, r)
+	}
+	for _, id := range rule.Allowlist {
+		m.allowlist[id] = struct{}{}
+	}
+	return m, nil
+}
+
+// Match: 按顺序匹配业务的规则, 然后匹配对所有业务生效的规则, 返回命中的规则
+func (c ShutdownConfig) Match(business string, req ShutdownRequest) (*ShutdownRule, bool) {
+	for _, key := range [...]string{business, ShutdownAllBusiness} {
+		rules := c[key]
+		for i := range rules {
+			if rules[i].Match(req) {
+				return &rules[i], true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Match: 请求是否命中规则, 白名单中的账号不命中
+func (rule *ShutdownRule) Match(req ShutdownRequest) bool {
+	m := rule.compiled
+	if m == nil {
+		// 未经过 Validate 的规则临时编译, 编译失败时不命中
+		var err error
+		if m, err = rule.compile(); err != nil {
+			return false
+		}
+	}
+
+	if _, exist := m.allowlist[req.AccountId]; exist && req.AccountId != "" {
+		return false
+	}
+	if !inSet(m.methods, req.Method) || !inSet(m.platforms, req.Platform) || !inSet(m.appTypes, req.AppType) {
+		return false
+	}
+	if !m.matchRoute(req.Path) {
+		return false
+	}
+	return m.matchVersion(req.Version)
+}
+
+// shutdownMatcher: 编译后的规则, 集合为空表示不限制
+type shutdownMatcher struct {
+	anyRoute  bool
+	routes    map[string]struct{}
+	prefixes  []string
+	patterns  []string
+	methods   map[string]struct{}
+	platforms map[string]struct{}
+	appTypes  map[string]struct{}
+	versions  []versionRange
+	allowlist map[string]struct{}
+}
+
+func (m *shutdownMatcher) matchRoute(p string) bool {
+	if m.anyRoute {
+		return true
+	}
+	if _, exist := m.routes[p]; exist {
+		return true
+	}
+	for _, prefix := range m.prefixes {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+	for _, pattern := range m.patterns {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *shutdownMatcher) matchVersion(version string) bool {
+	if len(m.versions) == 0 {
+		return true
+	}
+	v, ok := parseVersion(version)
+	if !ok {
+		return false
+	}
+	for _, r := range m.versions {
+		if r.contains(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func toLowerSet(items []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(items))
+	for _, item := range items {
+		set[strings.ToLower(item)] = struct{}{}
+	}
+	return set
+}
+
+// inSet: 集合为空表示不限制
+func inSet(set map[string]struct{}, item string) bool {
+	if len(set) == 0 {
+		return true
+	}
+	_, exist := set[strings.ToLower(item)]
+	return exist
+}
+
+// versionRange: 版本范围, 所有约束都满足时包含
+type versionRange []versionConstraint
+
+type versionConstraint struct {
+	op      string
+	version []int
+}
+
+// parseVersionRange: 解析空格分隔的版本约束, 例如 ">=1.2.0 <2.0.0", 不带运算符时表示等于
+func parseVersionRange(s string) (versionRange, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty version range")
+	}
+
+	r := make(versionRange, 0, len(fields))
+	for _, f := range fields {
+		op := "="
+		for _, candidate := range []string{">=", "<=", "!=", ">", "<", "="} {
+			if strings.HasPrefix(f, candidate) {
+				op, f = candidate, strings.TrimPrefix(f, candidate)
+				break
+			}
+		}
+		v, ok := parseVersion(f)
+		if !ok {
+			return nil, fmt.Errorf("invalid version[%s]", f)
+		}
+		r = append(r, versionConstraint{op: op, version: v})
+	}
+	return r, nil
+}
+
+func (r versionRange) contains(v []int) bool {
+	for _, c := range r {
+		n := compareVersion(v, c.version)
+		var ok bool
+		switch c.op {
+		case ">=":
+			ok = n >= 0
+		case "<=":
+			ok = n <= 0
+		case ">":
+			ok = n > 0
+		case "<":
+			ok = n < 0
+		case "!=":
+			ok = n != 0
+		default:
+			ok = n == 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// parseVersion: 解析点分隔的数字版本, 忽略前缀 v 以及 - 或 + 之后的内容, 例如 v1.2.3-beta
+func parseVersion(s string) ([]int, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		s = s[:i]
+	}
+	if s == "" {
+		return nil, false
+	}
+
+	parts := strings.Split(s, ".")
+	v := make([]int, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		v[i] = n
+	}
+	return v, true
+}
+
+// compareVersion: 逐段比较, 缺少的段视为 0
+func compareVersion(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,91 @@
+package structs
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/zeebo/assert"
+)
+
+func TestShutdownConfigFormat(t *testing.T) {
+	var cfg ShutdownConfig
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"a": true,
+		"b": false,
+		"c": {"routes": ["/pay"], "message": "维护中"},
+		"d": [{"methods": ["post"]}, {"app_types": ["app"]}]
+	}`), &cfg))
+	assert.NoError(t, cfg.Validate())
+
+	assert.Equal(t, len(cfg["a"]), 1)
+	assert.Equal(t, len(cfg["b"]), 0)
+	assert.Equal(t, cfg["c"][0].Message, "维护中")
+	assert.Equal(t, len(cfg["d"]), 2)
+
+	// 旧格式原样输出
+	b, err := json.Marshal(ShutdownConfig{"a": {{}}, "b": nil})
+	assert.NoError(t, err)
+	assert.Equal(t, string(b), `{"a":true,"b":false}`)
+	b, err = json.Marshal(cfg["c"])
+	assert.NoError(t, err)
+	assert.Equal(t, string(b), `[{"routes":["/pay"],"message":"维护中"}]`)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"a":"yes"}`), &cfg))
+}
+
+func TestShutdownConfigValidate(t *testing.T) {
+	for _, rule := range []ShutdownRule{
+		{RetryAfter: -1},
+		{Routes: []string{"/a/[b"}},
+		{Versions: []string{">=1.x"}},
+		{Versions: []string{" "}},
+	} {
+		cfg := ShutdownConfig{"a": {rule}}
+		assert.Error(t, cfg.Validate())
+	}
+}
+
+func TestShutdownMatch(t *testing.T) {
+	cfg := ShutdownConfig{
+		"momo": {
+			{Routes: []string{"/pay", "/order/*", "/user/*/profile"}, Methods: []string{"POST"}, Allowlist: []string{"9"}},
+			{Platforms: []string{"ios"}, AppTypes: []string{"app"}, Versions: []string{">=1.0.0 <1.5", "2.1.0"}},
+		},
+		ShutdownAllBusiness: {{Routes: []string{"/admin/*"}}},
+	}
+	assert.NoError(t, cfg.Validate())
+
+	match := func(business string, req ShutdownRequest) bool {
+		_, matched := cfg.Match(business, req)
+		return matched
+	}
+
+	// 路由: 精确、前缀、通配
+	assert.True(t, match("momo", ShutdownRequest{Path: "/pay", Method: "post"}))
+	assert.True(t, match("momo", ShutdownRequest{Path: "/order/1", Method: "POST"}))
+	assert.True(t, match("momo", ShutdownRequest{Path: "/user/1/profile", Method: "POST"}))
+	assert.False(t, match("momo", ShutdownRequest{Path: "/pay/1", Method: "POST"}))
+	assert.False(t, match("momo", ShutdownRequest{Path: "/pay", Method: "GET"}))
+	assert.False(t, match("momo", ShutdownRequest{Path: "/pay", Method: "POST", AccountId: "9"}))
+
+	// 平台、应用类型、版本范围
+	app := ShutdownRequest{Path: "/feed", Method: "GET", Platform: "iOS", AppType: "app"}
+	for version, want := range map[string]bool{
+		"1.0.0": true, "v1.4.9": true, "1.5": false, "1.5.0-beta": false, "2.1": true, "2.1.0.0": true, "2.1.1": false, "": false, "x": false,
+	} {
+		app.Version = version
+		assert.Equal(t, match("momo", app), want)
+	}
+	app.Version, app.AppType = "1.0.0", "h5"
+	assert.False(t, match("momo", app))
+
+	// 对所有业务生效的规则
+	rule, matched := cfg.Match("other", ShutdownRequest{Path: "/admin/users"})
+	assert.True(t, matched)
+	assert.Equal(t, rule, &cfg[ShutdownAllBusiness][0])
+	assert.True(t, match("momo", ShutdownRequest{Path: "/admin/users"}))
+	assert.False(t, match("other", ShutdownRequest{Path: "/pay", Method: "POST"}))
+
+	// 未经过 Validate 的规则同样生效
+	assert.True(t, ShutdownConfig{"a": {{}}}["a"][0].Match(ShutdownRequest{}))
+}
@@ -4,26 +4,30 @@ import (
 	"context"
 	"net/http"
 	"strings"
-	"sync"
+	"sync/atomic"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/everfir/go-helpers/consts"
-	"github.com/everfir/go-helpers/define/config"
 	"github.com/everfir/go-helpers/internal/helper/nacos"
 	. "github.com/everfir/go-helpers/internal/structs"
 	"github.com/everfir/logger-go"
 	"github.com/everfir/logger-go/structs/field"
 )
 
-var getBusinessConfig func() *config.NacosConfig[BusinessConfig] = sync.OnceValue(func() *config.NacosConfig[BusinessConfig] {
-	config, err := nacos.GetConfigAndListen[BusinessConfig](nacos.GetNacosClient(), "business.json")
-	if err != nil {
-		panic(err.Error())
-	}
-	return config
-})
+var businessConfig = nacos.NewLazyConfig[BusinessConfig]("business.json")
 
+var businessFailMode atomic.Int32
+
+// SetBusinessFailMode 设置 business.json 不可用(配置源创建失败或加载失败)时 BusinessMiddleware 的降级方式:
+//   - FailMode_Closed(默认): 无法校验业务, 返回 503
+//   - FailMode_Open: 不校验业务是否存在, header 中的业务不为空即放行
+func SetBusinessFailMode(mode nacos.FailMode) {
+	businessFailMode.Store(int32(mode))
+}
+
+// BusinessMiddleware 按 business.json 校验 header 中的业务, 并将业务、平台等信息存储在 Context 中,
+// 配置不可用时的行为见 SetBusinessFailMode
 func BusinessMiddleware(c *gin.Context) {
 	// 根据header中的字段来确定业务信息
 	business := strings.ToLower(c.GetHeader(consts.BusinessKey.String()))
@@ -38,21 +42,7 @@ func BusinessMiddleware(c *gin.Context) {
 	logger.Debug(c.Request.Context(), "device", field.String("device", device))
 	logger.Debug(c.Request.Context(), "appType", field.String("appType", appType))
 
-	cfg, exist := getBusinessConfig().Get()
-	if !exist {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
-			"err_code": http.StatusBadRequest,
-			"err_msg":  "business field in header is not expected, this business does not exist",
-		})
-		return
-	}
-
-	valid := cfg.Valid(business)
-	if !valid {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
-			"err_code": http.StatusBadRequest,
-			"err_msg":  "business field in header is not expected, this business does not exist",
-		})
+	if !validBusiness(c, business) {
 		return
 	}
 
@@ -66,3 +56,35 @@ func BusinessMiddleware(c *gin.Context) {
 
 	c.Next()
 }
+
+// validBusiness: 校验业务, 不通过时中止请求
+func validBusiness(c *gin.Context, business string) bool {
+	config, err := businessConfig.Get()
+	if err != nil {
+		if nacos.FailMode(businessFailMode.Load()).Resolve(nacos.FailMode_Closed) == nacos.FailMode_Closed {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"err_code": http.StatusServiceUnavailable,
+				"err_msg":  "business config is unavailable",
+			})
+			return false
+		}
+		if business == "" {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"err_code": http.StatusBadRequest,
+				"err_msg":  "unexcept business field in header",
+			})
+			return false
+		}
+		return true
+	}
+
+	cfg, exist := config.Get()
+	if !exist || !cfg.Valid(business) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"err_code": http.StatusBadRequest,
+			"err_msg":  "business field in header is not expected, this business does not exist",
+		})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/everfir/go-helpers/internal/helper/nacos"
+	"github.com/gin-gonic/gin"
+)
+
+// ConfigDebugHandler 以 JSON 输出进程内所有配置的状态，配置内容中的敏感字段已脱敏
+//
+// 示例：
+//
+//	router.GET("/debug/configs", middleware.ConfigDebugHandler)
+func ConfigDebugHandler(c *gin.Context) {
+	resp := gin.H{
+		"healthy": true,
+		"configs": nacos.ConfigStatuses(),
+	}
+	if err := nacos.CheckConfigHealth(); err != nil {
+		resp["healthy"] = false
+		resp["err_msg"] = err.Error()
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// ConfigHealthHandler 配置健康检查，配置加载失败或 RequireConfigs 声明的配置未加载时返回 503
+//
+// 示例：
+//
+//	router.GET("/health/configs", middleware.ConfigHealthHandler)
+func ConfigHealthHandler(c *gin.Context) {
+	if err := nacos.CheckConfigHealth(); err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"err_code": http.StatusServiceUnavailable,
+			"err_msg":  err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
@@ -0,0 +1,6 @@
+package middleware
+
+// ResetShutdownConfig: 重置 shutdown.json 的延迟加载配置, 使测试之间互不影响
+func ResetShutdownConfig() {
+	shutdownConfig.Reset()
+}
@@ -1,26 +1,224 @@
 package middleware_test
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/everfir/go-helpers/consts"
+	"github.com/everfir/go-helpers/define"
 	"github.com/everfir/go-helpers/env"
+	"github.com/everfir/go-helpers/helper/nacos"
+	"github.com/everfir/go-helpers/helper/nacos/nacostest"
 	"github.com/everfir/go-helpers/middleware"
 	"github.com/gin-gonic/gin"
+	"github.com/zeebo/assert"
 )
 
 func TestBusinessMiddleware(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
+	client := nacostest.NewConfigClient()
+	defer client.Close()
+	client.Seed(env.Env(), map[string]string{
+		"business.json": `{"business":[{"id":"1","name":"momo","status":1},{"id":"2","name":"offline","status":0}]}`,
+	})
+	nacos.SetConfigSource(nacos.NewNacosSource(client))
+	// Get 不会等待加载, 启动时加载中间件使用的配置
+	assert.NoError(t, nacos.InitConfigs(context.Background(), nacos.InitOptions{Configs: []string{"business.json"}}))
+
 	router := gin.New()
 	router.Use(middleware.BusinessMiddleware)
 
 	router.GET("/test", func(c *gin.Context) {
-		t.Logf("business: %s", env.Business(c.Request.Context()))
-		t.Logf("platform: %s", env.Platform(c.Request.Context()))
-		t.Logf("version: %s", env.Version(c.Request.Context()))
-		t.Logf("device: %s", env.Device(c.Request.Context()))
-		t.Logf("appType: %s", env.AppType(c.Request.Context()))
+		assert.Equal(t, env.Business(c.Request.Context()), "momo")
+		assert.Equal(t, env.Platform(c.Request.Context()), consts.DP_IOS)
+		assert.Equal(t, env.Version(c.Request.Context()), "1.0.0")
+		assert.Equal(t, env.Device(c.Request.Context()), consts.Dev_Phone)
+		assert.Equal(t, env.AppType(c.Request.Context()), consts.AppType_App)
+		c.Status(http.StatusOK)
 	})
 
-	router.Run(":8080")
+	request := func(business string) int {
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set(consts.BusinessKey.String(), business)
+		req.Header.Set(consts.PlatformKey.String(), "ios")
+		req.Header.Set(consts.VersionKey.String(), "1.0.0")
+		req.Header.Set(consts.DeviceKey.String(), "phone")
+		req.Header.Set(consts.AppTypeKey.String(), "app")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, request("momo"), http.StatusOK)
+	assert.Equal(t, request("offline"), http.StatusBadRequest)
+	assert.Equal(t, request("unknown"), http.StatusBadRequest)
+}
+
+func TestConfigDebugHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	client := nacostest.NewConfigClient()
+	defer client.Close()
+	client.Seed(env.Env(), map[string]string{
+		"debug.json": `{"name":"a","token":"secret"}`,
+	})
+	nacos.RequireConfigs("debug.json")
+	cfg, err := nacos.GetConfigAndListenFromSource[map[string]string](nacos.NewNacosSource(client), "debug.json")
+	assert.NoError(t, err)
+
+	router := gin.New()
+	router.GET("/debug/configs", middleware.ConfigDebugHandler)
+	router.GET("/health/configs", middleware.ConfigHealthHandler)
+
+	request := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	assert.Equal(t, request("/health/configs").Code, http.StatusOK)
+
+	var resp struct {
+		Healthy bool                 `json:"healthy"`
+		Configs []nacos.ConfigStatus `json:"configs"`
+	}
+	w := request("/debug/configs")
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, resp.Healthy)
+
+	var found bool
+	for _, status := range resp.Configs {
+		if status.DataId != "debug.json" {
+			continue
+		}
+		found = true
+		assert.DeepEqual(t, status.Groups[0].Content, map[string]any{"name": "a", "token": "******"})
+	}
+	assert.True(t, found)
+	assert.False(t, strings.Contains(w.Body.String(), "secret"))
+
+	// 依赖的配置关闭后视为缺失
+	cfg.Close()
+	assert.Equal(t, request("/health/configs").Code, http.StatusServiceUnavailable)
+}
+
+func TestShutdownMiddlewareFailMode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// 配置源创建失败, shutdown.json 不可用
+	middleware.ResetShutdownConfig()
+	nacos.SetConfigSource(nil)
+	t.Setenv("EVERFIR_CONFIG_SOURCE", "unknown")
+	defer middleware.SetShutdownFailMode(nacos.FailMode_Default)
+	assert.Error(t, nacos.InitConfigs(context.Background(), nacos.InitOptions{Configs: []string{"shutdown.json"}, Retries: -1}))
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), consts.BusinessKey, "momo"))
+		c.Next()
+	})
+	router.Use(middleware.ShutdownMiddleware)
+	router.GET("/test", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	request := func() int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+		return w.Code
+	}
+
+	// 默认放行
+	assert.Equal(t, request(), http.StatusOK)
+
+	middleware.SetShutdownFailMode(nacos.FailMode_Closed)
+	assert.Equal(t, request(), 599)
+
+	middleware.SetShutdownFailMode(nacos.FailMode_Open)
+	assert.Equal(t, request(), http.StatusOK)
+}
+
+func TestShutdownMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	client := nacostest.NewConfigClient()
+	defer client.Close()
+	client.Seed(env.Env(), map[string]string{
+		"shutdown.json": `{
+			"offline": true,
+			"online": false,
+			"momo": [
+				{"routes": ["/pay/*"], "methods": ["POST"], "message": "支付维护中", "retry_after": 600, "allowlist": ["9"]},
+				{"platforms": ["ios"], "versions": ["<2.0.0"]}
+			]
+		}`,
+	})
+	middleware.ResetShutdownConfig()
+	nacos.SetConfigSource(nacos.NewNacosSource(client))
+	defer nacos.SetConfigSource(nil)
+	assert.NoError(t, nacos.InitConfigs(context.Background(), nacos.InitOptions{Configs: []string{"shutdown.json"}}))
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		ctx := c.Request.Context()
+		ctx = context.WithValue(ctx, consts.BusinessKey, c.GetHeader(consts.BusinessKey.String()))
+		ctx = context.WithValue(ctx, consts.PlatformKey, consts.TDevicePlatform(c.GetHeader(consts.PlatformKey.String())))
+		ctx = context.WithValue(ctx, consts.VersionKey, c.GetHeader(consts.VersionKey.String()))
+		if id := c.GetHeader("X-Account-Id"); id != "" {
+			accountId, _ := strconv.ParseUint(id, 10, 64)
+			ctx = context.WithValue(ctx, consts.AccountInfoKey, &define.AccountInfo{AccountId: accountId})
+		}
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	})
+	router.Use(middleware.ShutdownMiddleware)
+	router.Any("/*path", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	request := func(method, path, business string, headers ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set(consts.BusinessKey.String(), business)
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// 兼容 bool 格式
+	assert.Equal(t, request(http.MethodGet, "/a", "offline").Code, 599)
+	assert.Equal(t, request(http.MethodGet, "/a", "online").Code, http.StatusOK)
+
+	// 按路由与方法匹配, 返回提示与 Retry-After
+	w := request(http.MethodPost, "/pay/order", "momo")
+	assert.Equal(t, w.Code, 599)
+	assert.Equal(t, w.Header().Get("Retry-After"), "600")
+	var body map[string]any
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, body["err_msg"], "支付维护中")
+	assert.Equal(t, request(http.MethodGet, "/pay/order", "momo").Code, http.StatusOK)
+	assert.Equal(t, request(http.MethodPost, "/user", "momo").Code, http.StatusOK)
+
+	// 白名单中的账号不受影响
+	assert.Equal(t, request(http.MethodPost, "/pay/order", "momo", "X-Account-Id", "9").Code, http.StatusOK)
+	assert.Equal(t, request(http.MethodPost, "/pay/order", "momo", "X-Account-Id", "10").Code, 599)
+
+	// 按平台与版本范围匹配
+	ios := func(version string) int {
+		return request(http.MethodGet, "/user", "momo", consts.PlatformKey.String(), "ios", consts.VersionKey.String(), version).Code
+	}
+	assert.Equal(t, ios("1.9.3"), 599)
+	assert.Equal(t, ios("2.0.0"), http.StatusOK)
+	assert.Equal(t, ios(""), http.StatusOK)
+	assert.Equal(t, request(http.MethodGet, "/user", "momo", consts.PlatformKey.String(), "android", consts.VersionKey.String(), "1.0.0").Code, http.StatusOK)
 }
@@ -1,23 +1,37 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
-	"sync"
+	"strconv"
+	"sync/atomic"
 
-	"github.com/everfir/go-helpers/define/config"
 	"github.com/everfir/go-helpers/env"
 	"github.com/everfir/go-helpers/internal/helper/nacos"
+	"github.com/everfir/go-helpers/internal/structs"
 	"github.com/gin-gonic/gin"
 )
 
-var shutdownConfig func() *config.NacosConfig[map[string]bool] = sync.OnceValue(func() *config.NacosConfig[map[string]bool] {
-	config, err := nacos.GetConfigAndListen[map[string]bool](nacos.GetNacosClient(), "shutdown.json")
-	if err != nil {
-		panic(err.Error())
-	}
-	return config
-})
+// shutdownConfig: key 为业务, "*" 对所有业务生效; 值为 bool 时兼容旧格式, 也可以是停服规则, 见 structs.ShutdownConfig
+var shutdownConfig = nacos.NewLazyConfig[structs.ShutdownConfig](
+	"shutdown.json",
+	// Nacos 与快照均不可用时默认不停服
+	nacos.WithDefault(structs.ShutdownConfig{}),
+)
 
+var shutdownFailMode atomic.Int32
+
+// SetShutdownFailMode 设置 shutdown.json 不可用(配置源创建失败或加载失败)时 ShutdownMiddleware 的降级方式:
+//   - FailMode_Open(默认): 按未停服处理, 放行请求
+//   - FailMode_Closed: 按停服处理, 返回 599
+func SetShutdownFailMode(mode nacos.FailMode) {
+	shutdownFailMode.Store(int32(mode))
+}
+
+// ShutdownMiddleware 按 shutdown.json 对命中停服规则的请求返回 599, 配置不可用时的行为见 SetShutdownFailMode
+//
+// 规则带有 message 时返回 {"err_code":599,"err_msg":message}, retry_after 大于 0 时设置 Retry-After header;
+// 账号白名单依赖 AuthMiddleware 写入的用户信息, 需要放在 AuthMiddleware 之后
 func ShutdownMiddleware(c *gin.Context) {
 	// 根据header中的字段来确定业务
 	business := env.Business(c.Request.Context())
@@ -29,15 +43,53 @@ func ShutdownMiddleware(c *gin.Context) {
 		return
 	}
 
-	cfg, exist := shutdownConfig().Get()
+	config, err := shutdownConfig.Get()
+	if err != nil {
+		if nacos.FailMode(shutdownFailMode.Load()).Resolve(nacos.FailMode_Open) == nacos.FailMode_Closed {
+			c.AbortWithStatus(599)
+			return
+		}
+		c.Next()
+		return
+	}
+
+	cfg, exist := config.Get()
 	if !exist {
 		c.AbortWithStatus(599)
 		return
 	}
 
-	if cfg[business] {
+	rule, matched := cfg.Match(business, structs.ShutdownRequest{
+		Path:      c.Request.URL.Path,
+		Method:    c.Request.Method,
+		Platform:  env.Platform(c.Request.Context()).String(),
+		AppType:   env.AppType(c.Request.Context()).String(),
+		Version:   env.Version(c.Request.Context()),
+		AccountId: accountId(c.Request.Context()),
+	})
+	if !matched {
+		c.Next()
+		return
+	}
+
+	if rule.RetryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(rule.RetryAfter))
+	}
+	if rule.Message == "" {
 		c.AbortWithStatus(599)
 		return
 	}
-	c.Next()
+	c.AbortWithStatusJSON(599, gin.H{
+		"err_code": 599,
+		"err_msg":  rule.Message,
+	})
+}
+
+// accountId: 已登录用户的账号 ID, 未登录时为空
+func accountId(ctx context.Context) string {
+	info := env.AccountInfo(ctx)
+	if !info.Validate() {
+		return ""
+	}
+	return strconv.FormatUint(info.AccountId, 10)
 }